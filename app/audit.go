@@ -0,0 +1,75 @@
+package app
+
+import (
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/identity"
+)
+
+// AuditLogPath returns the audit log selected by --audit-log, falling back
+// to audit.file in the config file. An empty path means auditing is off.
+func (c Command) AuditLogPath(cmd *cobra.Command) (string, error) {
+	if path := args.GetAuditLogPath(cmd); path != "" {
+		return path, nil
+	}
+
+	cfg, err := LoadConfig(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Audit.File, nil
+}
+
+// Recorder returns the audit recorder for the command.
+func (c Command) Recorder(cmd *cobra.Command) (audit.Recorder, error) {
+	path, err := c.AuditLogPath(cmd)
+	if err != nil || path == "" {
+		return audit.NoopRecorder{}, err
+	}
+
+	return audit.NewFileLog(path), nil
+}
+
+// Audit records a key operation, attributing it to the AWS caller identity.
+// A failure to record is logged but does not fail the command.
+func (c Command) Audit(cmd *cobra.Command, record audit.Record) {
+	c.audit(cmd, func() string { return c.actor(cmd) }, record)
+}
+
+// audit records a key operation attributed to actor, which is only called
+// when auditing is on.
+func (c Command) audit(cmd *cobra.Command, actor func() string, record audit.Record) {
+	recorder, err := c.Recorder(cmd)
+	if err == nil {
+		if _, disabled := recorder.(audit.NoopRecorder); disabled {
+			return
+		}
+
+		record.Actor = actor()
+		err = recorder.Record(record)
+	}
+
+	if err != nil {
+		klog.Logf("Error writing audit record for %s of '%s': %s",
+			record.Operation, record.KeyName, err).Error()
+	}
+}
+
+// actor returns the ARN of the AWS caller identity, falling back to the
+// local user when it cannot be looked up.
+func (c Command) actor(cmd *cobra.Command) string {
+	if c.Identity == nil {
+		return identity.Local()
+	}
+
+	caller, err := c.Identity.Caller(c.Context(cmd))
+	if err != nil {
+		return identity.Local()
+	}
+
+	return caller.ARN
+}
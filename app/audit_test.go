@@ -0,0 +1,58 @@
+package app_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/identity"
+)
+
+func TestAuditActor(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "names the AWS caller",
+			expected: apptest.CallerARN,
+		},
+		{
+			name:     "falls back to the local user",
+			err:      errors.New("no credentials"),
+			expected: identity.Local(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			env.Identity.Err = test.err
+
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			cmd := &cobra.Command{}
+			args.AttachAuditLogFlag(cmd)
+			require.NoError(t, cmd.ParseFlags([]string{"--audit-log", path}))
+
+			env.Command.Audit(cmd, audit.NewRecord(audit.OperationFetch, "payments", nil))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			var record audit.Record
+			require.NoError(t, json.Unmarshal(data, &record))
+			assert.Equal(t, test.expected, record.Actor)
+		})
+	}
+}
@@ -1,12 +1,77 @@
 package app
 
 import (
-	"github.com/aws/aws-sdk-go/aws/session"
+	"context"
+	"io"
+	"os"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/identity"
+	"github.com/kmesiab/go-key-rotator-cli/process"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/scheduler"
 	"github.com/kmesiab/go-key-rotator-cli/types"
 )
 
+// Command holds what every sub command depends on. Commands reach the
+// store, the key generator, the file system, child processes, the time and
+// their input and output only through these fields, so tests can replace
+// any of them.
 type Command struct {
-	KeyRotator types.KeyRotatorInterface
-	AWSSession *session.Session
+	KeyRotator     types.KeyRotatorInterface
+	KeyGenerator   types.KeyGeneratorInterface
+	ParameterStore types.ParameterStoreInterface
+	Files          filesystem.FileSystem
+	Processes      process.Runner
+	Clock          scheduler.Clock
+
+	// Stdout receives command results and keys written to "-". Stderr
+	// receives results when keys are streamed to stdout. Children run by
+	// exec are connected to Stdin, Stdout and Stderr.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Identity looks up who AWSConfig's credentials belong to. NewCommand
+	// looks them up once per run.
+	Identity  identity.Resolver
+	AWSConfig aws.Config
+}
+
+// NewCommand returns a Command backed by store that generates real keys,
+// reads and writes the disk, runs real processes, uses the system clock,
+// looks up its identity once with STS and uses the process's stdin, stdout
+// and stderr. Its KeyRotator rotates transactionally in store.
+func NewCommand(store types.ParameterStoreInterface, cfg aws.Config) Command {
+	generator := rotation.RSAKeyGenerator{}
+
+	keyRotator := rotation.NewTransactionalRotator(store)
+	keyRotator.Generator = generator
+
+	return Command{
+		KeyRotator:     keyRotator,
+		KeyGenerator:   generator,
+		ParameterStore: store,
+		Files:          filesystem.Disk{},
+		Processes:      process.SystemRunner{},
+		Clock:          scheduler.SystemClock{},
+		Stdin:          os.Stdin,
+		Stdout:         os.Stdout,
+		Stderr:         os.Stderr,
+		Identity:       identity.NewOnce(identity.STS{Config: cfg}),
+		AWSConfig:      cfg,
+	}
+}
+
+// Context returns the context cobra runs cmd with, or the background
+// context when cmd is run directly rather than executed.
+func (c Command) Context(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+
+	return context.Background()
 }
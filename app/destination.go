@@ -0,0 +1,21 @@
+package app
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+)
+
+// Destination returns where the command's key files are written, as chosen
+// by the --out-dir, --private-out, --public-out, --force and --chown flags.
+func (c Command) Destination(cmd *cobra.Command) filesystem.Destination {
+	return filesystem.Destination{
+		Dir:            args.GetString(cmd, args.FlagStringOutDir),
+		PrivateKeyPath: args.GetString(cmd, args.FlagStringPrivateOut),
+		PublicKeyPath:  args.GetString(cmd, args.FlagStringPublicOut),
+		Force:          args.GetBool(cmd, args.FlagStringForce),
+		Owner:          args.GetString(cmd, args.FlagStringChown),
+		Stdout:         c.Stdout,
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Process exit codes. These are documented in the README; scripts rely on
+// them, so existing values must not change.
+const (
+	ExitOK                 = 0
+	ExitFailure            = 1
+	ExitValidation         = 2
+	ExitNotFound           = 3
+	ExitPermissionDenied   = 4
+	ExitConflict           = 5
+	ExitBackendUnavailable = 6
+)
+
+var exitCodes = map[types.ErrorKind]int{
+	types.ErrorKindValidation:         ExitValidation,
+	types.ErrorKindNotFound:           ExitNotFound,
+	types.ErrorKindPermissionDenied:   ExitPermissionDenied,
+	types.ErrorKindConflict:           ExitConflict,
+	types.ErrorKindBackendUnavailable: ExitBackendUnavailable,
+}
+
+// ExitCode maps a command's error to the process exit code. A
+// *types.ExitError exits with its own code, and errors without a kind exit
+// with ExitFailure.
+func ExitCode(err error) int {
+	var exitErr *types.ExitError
+
+	if err == nil {
+		return ExitOK
+	}
+
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	if code, ok := exitCodes[types.KindOf(err)]; ok {
+		return code
+	}
+
+	return ExitFailure
+}
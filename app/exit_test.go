@@ -0,0 +1,69 @@
+package app_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: app.ExitOK},
+		{name: "unclassified", err: errors.New("boom"), expected: app.ExitFailure},
+		{
+			name:     "validation",
+			err:      types.Errorf(types.ErrorKindValidation, "bad name"),
+			expected: app.ExitValidation,
+		},
+		{
+			name:     "wrapped not found sentinel",
+			err:      fmt.Errorf("fetching: %w", types.ErrParameterNotFound),
+			expected: app.ExitNotFound,
+		},
+		{
+			name:     "already exists sentinel",
+			err:      types.ErrParameterAlreadyExists,
+			expected: app.ExitConflict,
+		},
+		{
+			name:     "permission denied",
+			err:      fmt.Errorf("reading: %w", types.NewError(types.ErrorKindPermissionDenied, errors.New("denied"))),
+			expected: app.ExitPermissionDenied,
+		},
+		{
+			name:     "conflict",
+			err:      types.NewError(types.ErrorKindConflict, errors.New("locked")),
+			expected: app.ExitConflict,
+		},
+		{
+			name:     "backend unavailable",
+			err:      types.NewError(types.ErrorKindBackendUnavailable, errors.New("throttled")),
+			expected: app.ExitBackendUnavailable,
+		},
+		{
+			name:     "exit error keeps its code",
+			err:      &types.ExitError{Code: 42},
+			expected: 42,
+		},
+		{
+			name:     "joined errors use the first classified one",
+			err:      errors.Join(errors.New("boom"), types.ErrParameterNotFound),
+			expected: app.ExitNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, app.ExitCode(test.err))
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Name returns the key name given by --name, expanded by --name-template
+// or prefixed by --path-prefix, after checking that Parameter Store in the
+// configured region accepts it.
+func (c Command) Name(cmd *cobra.Command) (string, error) {
+	name, err := args.ResolveName(cmd)
+	if err != nil {
+		return "", types.NewError(types.ErrorKindValidation, err)
+	}
+
+	if err := aws.ValidateKeyName(name, c.AWSConfig.Region); err != nil {
+		return "", types.NewError(types.ErrorKindValidation, err)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// ParameterOptions returns the options both parameters are stored with, as
+// chosen by --kms-key-id, --tag, --tier and --expire-after, and when the
+// parameters expire, measured from now. Keys with a max age get a
+// NoChangeNotification policy, so Parameter Store emits an event when they
+// become due without being rotated. Only --expire-after has the parameters
+// deleted, with an event a due window beforehand. Policies need the
+// Advanced tier: without --tier, Intelligent-Tiering lets Parameter Store
+// pick it, and the Standard tier gets no policies. Keys that do not expire
+// have a nil expiry.
+func (c Command) ParameterOptions(
+	cmd *cobra.Command,
+	keyPolicy policy.Policy,
+	now time.Time,
+) (types.PutOptions, *time.Time, error) {
+	options := types.PutOptions{
+		Type:  rotation.ParameterTypeSecureString,
+		KeyID: args.GetString(cmd, args.FlagStringKMSKeyID),
+	}
+
+	var err error
+
+	if options.Tags, err = aws.ParseTags(args.GetStringArray(cmd, args.FlagStringTag)); err != nil {
+		return options, nil, err
+	}
+
+	if tier := args.GetString(cmd, args.FlagStringTier); tier != "" {
+		if options.Tier, err = aws.ParseTier(tier); err != nil {
+			return options, nil, err
+		}
+	}
+
+	standard := options.Tier == string(ssmtypes.ParameterTierStandard)
+
+	var expireAfter time.Duration
+
+	if value := args.GetString(cmd, args.FlagStringExpireAfter); value != "" {
+		if expireAfter, err = policy.ParseDuration(value); err != nil || expireAfter <= 0 {
+			return options, nil, fmt.Errorf("invalid --%s '%s'", args.FlagStringExpireAfter, value)
+		}
+
+		if standard {
+			return options, nil, fmt.Errorf("--%s needs the %s or %s tier", args.FlagStringExpireAfter,
+				ssmtypes.ParameterTierAdvanced, ssmtypes.ParameterTierIntelligentTiering)
+		}
+	}
+
+	switch {
+	case keyPolicy.Managed() && standard:
+		klog.Logf("Not attaching a no-change notification: parameter policies need the %s tier",
+			ssmtypes.ParameterTierAdvanced).Info()
+	case keyPolicy.Managed():
+		options.Policies = append(options.Policies,
+			aws.NoChangeNotificationPolicy(keyPolicy.MaxAge-keyPolicy.EffectiveDueWindow()))
+	}
+
+	var expiresAt *time.Time
+
+	if expireAfter > 0 {
+		at := now.Add(expireAfter).UTC()
+		expiresAt = &at
+
+		// Without a max age, the due window is measured against the expiry
+		notice := policy.Policy{MaxAge: expireAfter, DueWindow: keyPolicy.DueWindow}
+		if keyPolicy.Managed() {
+			notice = keyPolicy
+		}
+
+		options.Policies = append(options.Policies,
+			aws.ExpirationPolicy(at), aws.ExpirationNotificationPolicy(notice.EffectiveDueWindow()))
+	}
+
+	if len(options.Policies) > 0 && options.Tier == "" {
+		options.Tier = string(ssmtypes.ParameterTierIntelligentTiering)
+	}
+
+	return options, expiresAt, nil
+}
+
+// Rotator returns the key rotator configured to store both halves with
+// options.
+func (c Command) Rotator(options types.PutOptions) (types.KeyRotatorInterface, error) {
+	if options.IsZero() {
+		return c.KeyRotator, nil
+	}
+
+	transactional, ok := c.KeyRotator.(*rotation.TransactionalRotator)
+	if !ok {
+		return nil, rotation.ErrOptionsUnsupported
+	}
+
+	configured := *transactional
+	configured.Options = options
+
+	return &configured, nil
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"io"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/output"
+)
+
+// OutputFormat returns the format selected by --output.
+func (c Command) OutputFormat(cmd *cobra.Command) (output.Format, error) {
+	return output.ParseFormat(args.GetOutput(cmd))
+}
+
+// Print writes a command's result in the selected output format. Results go
+// to Stdout, unless the command is streaming keys there, in which case they
+// go to Stderr.
+func (c Command) Print(cmd *cobra.Command, result any) {
+	if c.Destination(cmd).Streams() {
+		c.PrintTo(cmd, c.Stderr, result)
+
+		return
+	}
+
+	c.PrintTo(cmd, c.Stdout, result)
+}
+
+// PrintTo writes a command's result to w in the selected output format.
+func (c Command) PrintTo(cmd *cobra.Command, w io.Writer, result any) {
+	format, err := c.OutputFormat(cmd)
+	if err == nil {
+		err = output.Write(w, format, result)
+	}
+
+	if err != nil {
+		klog.Logf("Error writing output: %s", err).Error()
+	}
+}
@@ -0,0 +1,350 @@
+package app
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/hooks"
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+	"github.com/kmesiab/go-key-rotator-cli/notify"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// RotationRequest describes a key pair for RotateKeyPair to rotate.
+type RotationRequest struct {
+	// Name is the key name, without the _priv.pem and _pub.pem suffixes.
+	Name    string
+	KeySize int
+
+	// Options are applied to both halves, both when they are written and
+	// when a rollback restores the previous pair. See ParameterOptions.
+	Options types.PutOptions
+
+	// Locker excludes other rotations of the key. The lease lasts LockTTL
+	// and is renewed until the rotation ends; a held lock is waited on for
+	// up to LockWait. Nil does not lock.
+	Locker   lock.Locker
+	LockTTL  time.Duration
+	LockWait time.Duration
+
+	// IfDue skips the rotation unless the key's policy says it is due.
+	IfDue bool
+}
+
+// NewRotationRequest returns a request to rotate name with a keySize bit
+// key pair, locked as chosen by --lock, --lock-dir, --lock-ttl and
+// --lock-wait, and skipped unless due with --if-due. Commands without those
+// flags neither lock nor skip.
+func (c Command) NewRotationRequest(cmd *cobra.Command, name string, keySize int) (RotationRequest, error) {
+	request := RotationRequest{
+		Name:     name,
+		KeySize:  keySize,
+		LockTTL:  args.GetDuration(cmd, args.FlagStringLockTTL),
+		LockWait: args.GetDuration(cmd, args.FlagStringLockWait),
+		IfDue:    args.GetBool(cmd, args.FlagStringIfDue),
+	}
+
+	switch backend := args.GetString(cmd, args.FlagStringLock); backend {
+	case args.LockBackendNone, "":
+		request.Locker = lock.NoopLocker{}
+	case args.LockBackendFile:
+		request.Locker = lock.NewFileLocker(args.GetString(cmd, args.FlagStringLockDir), request.LockTTL)
+	case args.LockBackendSSM:
+		request.Locker = lock.NewParameterStoreLocker(c.ParameterStore, request.LockTTL)
+	default:
+		return request, types.Errorf(types.ErrorKindValidation,
+			"invalid lock configuration: unknown lock backend '%s'", backend)
+	}
+
+	// A lease that expires as it is written excludes no one, and KeepAlive
+	// cannot renew it.
+	if _, noop := request.Locker.(lock.NoopLocker); !noop && request.LockTTL <= 0 {
+		return request, types.Errorf(types.ErrorKindValidation,
+			"invalid lock configuration: --%s must be positive, got %s", args.FlagStringLockTTL, request.LockTTL)
+	}
+
+	return request, nil
+}
+
+// KeyPairRotation is the outcome of RotateKeyPair.
+type KeyPairRotation struct {
+	// Operation is audit.OperationStore when the key did not exist yet,
+	// and audit.OperationRotate otherwise.
+	Operation audit.Operation
+
+	// Skipped is set, along with the key's Status, when IfDue found the
+	// key was not due.
+	Skipped bool
+	Status  policy.Status
+
+	// Payload describes the previous and new key pairs, as hooks saw them.
+	Payload hooks.Payload
+
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+// RotateKeyPair rotates a key pair the way every command does: under the
+// key's lock, between the configured pre_rotate and post_rotate hooks, and
+// rolled back when writing fails or a hook asks for it. Every outcome is
+// audited, failures run the on_failure hooks, and webhooks are told about
+// the new or restored key pair.
+func (c Command) RotateKeyPair(cmd *cobra.Command, cfg *config.Config, request RotationRequest) (KeyPairRotation, error) {
+	ctx := c.Context(cmd)
+	outcome := KeyPairRotation{Operation: audit.OperationRotate}
+
+	// Audit records and webhook events name the same actor. It is only
+	// looked up once one of them needs it.
+	actor := sync.OnceValue(func() string { return c.actor(cmd) })
+
+	privKeyName := aws.MakePrivateKeyName(request.Name)
+	pubKeyName := aws.MakePublicKeyName(request.Name)
+
+	keyRotator, err := c.Rotator(request.Options)
+	if err != nil {
+		return outcome, types.NewError(types.ErrorKindValidation, err)
+	}
+
+	locker := request.Locker
+	if locker == nil {
+		locker = lock.NoopLocker{}
+	}
+
+	held, err := lock.AcquireWait(locker, request.Name, request.LockWait)
+	if errors.Is(err, lock.ErrLocked) {
+		return outcome, types.Errorf(types.ErrorKindConflict, "unable to lock '%s' for rotation: %w", request.Name, err)
+	}
+
+	if err != nil {
+		return outcome, fmt.Errorf("unable to lock '%s' for rotation: %w", request.Name, err)
+	}
+
+	// Hooks may outlast the TTL, so the lease is renewed until released
+	stopRenewing := lock.KeepAlive(held, request.LockTTL)
+
+	defer func() {
+		if err := stopRenewing(); err != nil {
+			klog.Logf("Error renewing lock on '%s': %s\n", request.Name, err).Warn()
+		}
+
+		if err := held.Release(); err != nil {
+			klog.Logf("Error releasing lock on '%s': %s\n", request.Name, err).Warn()
+		}
+	}()
+
+	if request.IfDue {
+		status, err := c.RotationStatus(cfg, request.Name)
+		if err != nil {
+			return outcome, fmt.Errorf("unable to determine whether '%s' is due for rotation: %w",
+				request.Name, err)
+		}
+
+		if !status.NeedsRotation() {
+			outcome.Skipped = true
+			outcome.Status = status
+
+			return outcome, nil
+		}
+
+		klog.Logf("Key '%s' is %s, rotating.", request.Name, status).Info()
+	}
+
+	// Remember the current pair for hooks and a hook-requested rollback
+	snapshot, err := rotation.TakeSnapshot(c.ParameterStore, privKeyName, pubKeyName)
+	if err != nil {
+		return outcome, fmt.Errorf("error reading the current key pair: %w", err)
+	}
+
+	snapshot.Options = request.Options
+
+	if !snapshot.Exists() {
+		outcome.Operation = audit.OperationStore
+	}
+
+	payload := hooks.Payload{
+		KeyName:        request.Name,
+		PrivateKeyName: privKeyName,
+		PublicKeyName:  pubKeyName,
+		PublicKeyPEM:   []byte(snapshot.Values[pubKeyName]),
+	}
+
+	if previous, ok := snapshot.Values[pubKeyName]; ok {
+		if payload.PreviousFingerprint, err = rotation.FingerprintPEM(previous); err != nil {
+			klog.Logf("Unable to fingerprint the current public key: %s\n", err).Warn()
+		}
+	}
+
+	if err := hooks.Run(ctx, hooks.EventPreRotate, cfg.Hooks.PreRotate, payload); err != nil {
+		c.auditRotation(cmd, actor, outcome.Operation, payload, err)
+		runFailureHooks(ctx, cfg, payload, err)
+
+		return outcome, fmt.Errorf("aborting rotation: %w", err)
+	}
+
+	// Generate and rotate the keys
+	privateKey, publicKey, err := keyRotator.Rotate(privKeyName, pubKeyName, request.KeySize)
+
+	if errors.Is(err, rotation.ErrRollbackFailed) {
+		c.auditRotation(cmd, actor, outcome.Operation, payload, err)
+		runFailureHooks(ctx, cfg, payload, err)
+
+		return outcome, fmt.Errorf("error rotating keys and restoring the previous pair. '%s' may "+
+			"hold a mismatched key pair: %w", request.Name, err)
+	}
+
+	if err != nil {
+		c.auditRotation(cmd, actor, outcome.Operation, payload, err)
+		runFailureHooks(ctx, cfg, payload, err)
+
+		if errors.Is(err, rotation.ErrRolledBack) {
+			c.auditRotation(cmd, actor, audit.OperationRollback, payload, nil)
+			sendRollbackEvent(ctx, cfg, actor, payload)
+		}
+
+		return outcome, fmt.Errorf("error rotating keys: %w", err)
+	}
+
+	if err := c.describeRotation(&payload, publicKey); err != nil {
+		klog.Logf("Unable to describe the new key pair for hooks: %s\n", err).Warn()
+	}
+
+	outcome.Payload = payload
+
+	if err := hooks.Run(ctx, hooks.EventPostRotate, cfg.Hooks.PostRotate, payload); err != nil {
+		if !hooks.RollbackRequested(err) {
+			klog.Logf("Keys were rotated but a hook failed: %s\n", err).Warn()
+		} else {
+			c.auditRotation(cmd, actor, outcome.Operation, payload, err)
+
+			restoreErr := snapshot.Restore(c.ParameterStore)
+
+			c.auditRotation(cmd, actor, audit.OperationRollback, payload, restoreErr)
+			runFailureHooks(ctx, cfg, payload, err)
+
+			if restoreErr != nil {
+				return outcome, fmt.Errorf("rolling back rotation: %w. Error restoring the previous key pair, "+
+					"'%s' may hold a mismatched key pair: %w", err, request.Name, restoreErr)
+			}
+
+			sendRollbackEvent(ctx, cfg, actor, payload)
+
+			return outcome, fmt.Errorf("rolled back rotation: %w", err)
+		}
+	}
+
+	c.auditRotation(cmd, actor, outcome.Operation, payload, nil)
+
+	eventType := notify.EventRotated
+	if outcome.Operation == audit.OperationStore {
+		eventType = notify.EventStored
+	}
+
+	event := notify.NewEvent(eventType, payload.KeyName, "")
+	event.OldFingerprint = payload.PreviousFingerprint
+	event.NewFingerprint = payload.Fingerprint
+	event.Version = payload.Version
+
+	sendEvent(ctx, cfg, actor, event)
+
+	outcome.PrivateKey = privateKey
+	outcome.PublicKey = publicKey
+
+	return outcome, nil
+}
+
+// RotationStatus evaluates the named key pair against its configured
+// rotation policy.
+func (c Command) RotationStatus(cfg *config.Config, name string) (policy.Status, error) {
+	keyPolicy := cfg.PolicyFor(name)
+	if !keyPolicy.Managed() {
+		return "", fmt.Errorf("no max age configured; set --%s or max_age in the config file",
+			args.FlagStringMaxAge)
+	}
+
+	lastRotated, err := policy.LastRotated(c.ParameterStore, aws.MakePrivateKeyName(name), aws.MakePublicKeyName(name))
+	if err != nil {
+		return "", err
+	}
+
+	return keyPolicy.Evaluate(lastRotated, c.Clock.Now()), nil
+}
+
+// describeRotation fills in the payload fields describing the new key pair.
+func (c Command) describeRotation(payload *hooks.Payload, publicKey *rsa.PublicKey) error {
+	var err error
+
+	if payload.PublicKeyPEM, err = rotator.EncodePublicKeyToPEM(publicKey); err != nil {
+		return err
+	}
+
+	if payload.Fingerprint, err = rotation.Fingerprint(publicKey); err != nil {
+		return err
+	}
+
+	metadata, err := c.ParameterStore.DescribeParameter(payload.PrivateKeyName)
+	if err != nil {
+		return err
+	}
+
+	payload.Version = metadata.Version
+
+	return nil
+}
+
+// auditRotation records the outcome of a rotation, or of its rollback,
+// attributed to actor.
+func (c Command) auditRotation(cmd *cobra.Command, actor func() string, op audit.Operation, payload hooks.Payload, err error) {
+	record := audit.NewRecord(op, payload.KeyName, err)
+	record.Fingerprint = payload.Fingerprint
+	record.PreviousFingerprint = payload.PreviousFingerprint
+
+	c.audit(cmd, actor, record)
+}
+
+// runFailureHooks runs the on_failure hooks for a failed rotation. Their own
+// failures are only logged.
+func runFailureHooks(ctx context.Context, cfg *config.Config, payload hooks.Payload, cause error) {
+	payload.Err = cause
+
+	if err := hooks.Run(ctx, hooks.EventOnFailure, cfg.Hooks.OnFailure, payload); err != nil {
+		klog.Logf("%s\n", err).Error()
+	}
+}
+
+// sendRollbackEvent notifies webhooks that the rotation described by payload
+// was undone. The event's new fingerprint is that of the restored key pair.
+func sendRollbackEvent(ctx context.Context, cfg *config.Config, actor func() string, payload hooks.Payload) {
+	event := notify.NewEvent(notify.EventRolledBack, payload.KeyName, "")
+	event.OldFingerprint = payload.Fingerprint
+	event.NewFingerprint = payload.PreviousFingerprint
+
+	sendEvent(ctx, cfg, actor, event)
+}
+
+// sendEvent delivers an event from actor to the configured webhooks.
+// Delivery failures are only logged.
+func sendEvent(ctx context.Context, cfg *config.Config, actor func() string, event notify.Event) {
+	if len(cfg.Webhooks) == 0 {
+		return
+	}
+
+	event.Actor = actor()
+
+	if err := notify.NewNotifier(cfg.Webhooks).Notify(ctx, event); err != nil {
+		klog.Logf("Error sending %s event: %s\n", event.Type, err).Warn()
+	}
+}
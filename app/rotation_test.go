@@ -0,0 +1,72 @@
+package app_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/notify"
+)
+
+func TestRotateKeyPairAttributesEventsLikeAuditRecords(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []notify.Event
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	env := apptest.New(t)
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	cmd := &cobra.Command{}
+	args.AttachAuditLogFlag(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--audit-log", path}))
+
+	cfg := &config.Config{Webhooks: []config.WebhookConfig{{URL: server.URL, Secret: "secret"}}}
+
+	request, err := env.Command.NewRotationRequest(cmd, "/prod/signing", 2048)
+	require.NoError(t, err)
+
+	_, err = env.Command.RotateKeyPair(cmd, cfg, request)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var record audit.Record
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, apptest.CallerARN, record.Actor)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, events, 1)
+	assert.Equal(t, notify.EventStored, events[0].Type)
+	assert.Equal(t, record.Actor, events[0].Actor)
+}
@@ -0,0 +1,383 @@
+package app
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	cliaws "github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Environment variables read when the matching flag is not given.
+const (
+	EnvConfig       = "GO_ROTATE_CONFIG"
+	EnvProfile      = "GO_ROTATE_PROFILE"
+	EnvBackend      = "GO_ROTATE_BACKEND"
+	EnvPathPrefix   = "GO_ROTATE_PATH_PREFIX"
+	EnvNameTemplate = "GO_ROTATE_NAME_TEMPLATE"
+	EnvEnvironment  = "GO_ROTATE_ENV"
+	EnvService      = "GO_ROTATE_SERVICE"
+	EnvKeyType      = "GO_ROTATE_KEY_TYPE"
+	EnvKeySize      = "GO_ROTATE_KEY_SIZE"
+	EnvOutput       = "GO_ROTATE_OUTPUT"
+	EnvRegion       = "AWS_REGION"
+	EnvAWSProfile   = "AWS_PROFILE"
+	EnvRoleARN      = "GO_ROTATE_ROLE_ARN"
+	EnvExternalID   = "GO_ROTATE_EXTERNAL_ID"
+	EnvMFASerial    = "GO_ROTATE_MFA_SERIAL"
+	EnvEndpointURL  = "AWS_ENDPOINT_URL"
+	EnvRetryMode    = "AWS_RETRY_MODE"
+	EnvMaxAttempts  = "AWS_MAX_ATTEMPTS"
+	EnvTimeout      = "GO_ROTATE_TIMEOUT"
+)
+
+// Where a setting's value came from, highest precedence first.
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceProfile = "profile"
+	SourceConfig  = "config"
+	SourceDefault = "default"
+)
+
+// Names of the settings. All but profile can be set by a profile.
+const (
+	SettingProfile      = "profile"
+	SettingBackend      = "backend"
+	SettingRegion       = "region"
+	SettingAWSProfile   = "aws_profile"
+	SettingRoleARN      = "role_arn"
+	SettingExternalID   = "external_id"
+	SettingMFASerial    = "mfa_serial"
+	SettingEndpoint     = "endpoint_url"
+	SettingRetryMode    = "retry_mode"
+	SettingAttempts     = "max_attempts"
+	SettingTimeout      = "timeout"
+	SettingPathPrefix   = "path_prefix"
+	SettingNameTemplate = "name_template"
+	SettingEnvironment  = "env"
+	SettingService      = "service"
+	SettingKeyType      = "key_type"
+	SettingKeySize      = "key_size"
+	SettingOutput       = "output"
+)
+
+type Setting struct {
+	Name   string `json:"name" yaml:"name"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+type Settings []Setting
+
+// ConfigOptions returns the AWS config options from the settings.
+func (s Settings) ConfigOptions() cliaws.ConfigOptions {
+	// An invalid value leaves the SDK's default in place. ApplySettings
+	// rejects it before the config is loaded.
+	maxAttempts, _ := strconv.Atoi(s.Get(SettingAttempts))
+
+	return cliaws.ConfigOptions{
+		Region:      s.Get(SettingRegion),
+		Profile:     s.Get(SettingAWSProfile),
+		RoleARN:     s.Get(SettingRoleARN),
+		ExternalID:  s.Get(SettingExternalID),
+		MFASerial:   s.Get(SettingMFASerial),
+		EndpointURL: s.Get(SettingEndpoint),
+		RetryMode:   s.Get(SettingRetryMode),
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// Get returns the value of the named setting.
+func (s Settings) Get(name string) string {
+	return s.lookup(name).Value
+}
+
+func (s Settings) lookup(name string) Setting {
+	for _, setting := range s {
+		if setting.Name == name {
+			return setting
+		}
+	}
+
+	return Setting{Name: name}
+}
+
+// binding ties a setting to the flag, environment variable and profile
+// field that can set it. Settings without a flag fall back to fallback.
+type binding struct {
+	name     string
+	flag     string
+	env      string
+	profile  func(config.Profile) string
+	fallback string
+}
+
+var bindings = []binding{
+	{
+		name:     SettingBackend,
+		env:      EnvBackend,
+		profile:  func(p config.Profile) string { return p.Backend },
+		fallback: config.BackendSSM,
+	},
+	{
+		name:    SettingRegion,
+		flag:    args.FlagStringRegion,
+		env:     EnvRegion,
+		profile: func(p config.Profile) string { return p.Region },
+	},
+	{
+		name:    SettingAWSProfile,
+		flag:    args.FlagStringProfile,
+		env:     EnvAWSProfile,
+		profile: func(p config.Profile) string { return p.AWSProfile },
+	},
+	{
+		name:    SettingRoleARN,
+		flag:    args.FlagStringRoleARN,
+		env:     EnvRoleARN,
+		profile: func(p config.Profile) string { return p.RoleARN },
+	},
+	{
+		name:    SettingExternalID,
+		flag:    args.FlagStringExternalID,
+		env:     EnvExternalID,
+		profile: func(p config.Profile) string { return p.ExternalID },
+	},
+	{
+		name:    SettingMFASerial,
+		flag:    args.FlagStringMFASerial,
+		env:     EnvMFASerial,
+		profile: func(p config.Profile) string { return p.MFASerial },
+	},
+	{
+		name:    SettingEndpoint,
+		flag:    args.FlagStringEndpointURL,
+		env:     EnvEndpointURL,
+		profile: func(p config.Profile) string { return p.EndpointURL },
+	},
+	{
+		name:    SettingRetryMode,
+		flag:    args.FlagStringRetryMode,
+		env:     EnvRetryMode,
+		profile: func(p config.Profile) string { return p.RetryMode },
+	},
+	{
+		name: SettingAttempts,
+		flag: args.FlagStringMaxAttempts,
+		env:  EnvMaxAttempts,
+		profile: func(p config.Profile) string {
+			if p.MaxAttempts == 0 {
+				return ""
+			}
+
+			return strconv.Itoa(p.MaxAttempts)
+		},
+	},
+	{
+		name: SettingTimeout,
+		flag: args.FlagStringTimeout,
+		env:  EnvTimeout,
+		profile: func(p config.Profile) string {
+			if p.Timeout == 0 {
+				return ""
+			}
+
+			return p.Timeout.String()
+		},
+	},
+	{
+		name:    SettingPathPrefix,
+		flag:    args.FlagStringPathPrefix,
+		env:     EnvPathPrefix,
+		profile: func(p config.Profile) string { return p.PathPrefix },
+	},
+	{
+		name:    SettingNameTemplate,
+		flag:    args.FlagStringNameTemplate,
+		env:     EnvNameTemplate,
+		profile: func(p config.Profile) string { return p.NameTemplate },
+	},
+	{
+		name:    SettingEnvironment,
+		flag:    args.FlagStringEnv,
+		env:     EnvEnvironment,
+		profile: func(p config.Profile) string { return p.Env },
+	},
+	{
+		name:    SettingService,
+		flag:    args.FlagStringService,
+		env:     EnvService,
+		profile: func(p config.Profile) string { return p.Service },
+	},
+	{
+		name:     SettingKeyType,
+		env:      EnvKeyType,
+		profile:  func(p config.Profile) string { return p.KeyType },
+		fallback: config.KeyTypeRSA,
+	},
+	{
+		name: SettingKeySize,
+		flag: args.FlagStringSize,
+		env:  EnvKeySize,
+		profile: func(p config.Profile) string {
+			if p.KeySize == 0 {
+				return ""
+			}
+
+			return strconv.Itoa(p.KeySize)
+		},
+		fallback: strconv.Itoa(args.DefaultKeySize),
+	},
+	{
+		name:     SettingOutput,
+		flag:     args.FlagStringOutput,
+		env:      EnvOutput,
+		profile:  func(p config.Profile) string { return p.Output },
+		fallback: args.DefaultOutput,
+	},
+}
+
+// LoadConfig loads the file given by --config or GO_ROTATE_CONFIG. Without
+// either, the default config files are read if they exist.
+func LoadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path := args.GetConfigPath(cmd)
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+
+	if path != "" {
+		return config.Load(path)
+	}
+
+	return config.Discover()
+}
+
+// Config loads the command's config file, see LoadConfig, and expands the
+// names of the keys it lists like --name, so they match the names commands
+// resolve.
+func (c Command) Config(cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := LoadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ExpandKeyNames(func(name string) (string, error) {
+		return args.ExpandName(cmd, name)
+	}); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Settings resolves the command's settings. See ResolveSettings.
+func (c Command) Settings(cmd *cobra.Command, cfg *config.Config) (Settings, error) {
+	return ResolveSettings(cmd, cfg)
+}
+
+// resolveProfile returns the profile selected by --config-profile or
+// GO_ROTATE_PROFILE, falling back to the config file's default profile.
+func resolveProfile(cmd *cobra.Command, cfg *config.Config) Setting {
+	setting := Setting{Name: SettingProfile}
+
+	switch {
+	case args.GetConfigProfile(cmd) != "":
+		setting.Value, setting.Source = args.GetConfigProfile(cmd), SourceFlag
+	case os.Getenv(EnvProfile) != "":
+		setting.Value, setting.Source = os.Getenv(EnvProfile), SourceEnv
+	case cfg.Profile != "":
+		setting.Value, setting.Source = cfg.Profile, SourceConfig
+	default:
+		setting.Source = SourceDefault
+	}
+
+	return setting
+}
+
+// ResolveSettings works out the active profile, then each setting from, in
+// order of precedence, its flag, its environment variable, the profile, and
+// its default.
+func ResolveSettings(cmd *cobra.Command, cfg *config.Config) (Settings, error) {
+	selected := resolveProfile(cmd, cfg)
+
+	profile, err := cfg.ActiveProfile(selected.Value)
+	if err != nil {
+		return nil, types.NewError(types.ErrorKindValidation, err)
+	}
+
+	settings := Settings{selected}
+
+	for _, b := range bindings {
+		settings = append(settings, b.resolve(cmd, profile))
+	}
+
+	// Environment variables are not validated with the profile
+	resolved := config.Profile{
+		Backend:   settings.Get(SettingBackend),
+		KeyType:   settings.Get(SettingKeyType),
+		RetryMode: settings.Get(SettingRetryMode),
+	}
+
+	if err := resolved.Validate(); err != nil {
+		return nil, types.NewError(types.ErrorKindValidation, err)
+	}
+
+	return settings, nil
+}
+
+func (b binding) resolve(cmd *cobra.Command, profile config.Profile) Setting {
+	setting := Setting{Name: b.name}
+
+	flag := cmd.Flag(b.flag)
+
+	switch {
+	case flag != nil && flag.Changed:
+		setting.Value, setting.Source = flag.Value.String(), SourceFlag
+	case os.Getenv(b.env) != "":
+		setting.Value, setting.Source = os.Getenv(b.env), SourceEnv
+	case b.profile(profile) != "":
+		setting.Value, setting.Source = b.profile(profile), SourceProfile
+	case flag != nil:
+		setting.Value, setting.Source = flag.DefValue, SourceDefault
+	default:
+		setting.Value, setting.Source = b.fallback, SourceDefault
+	}
+
+	return setting
+}
+
+// ApplySettings resolves the command's settings and sets every flag that
+// was not given on the command line to the value taken from the
+// environment or the profile, so commands read it like any other flag.
+func ApplySettings(cmd *cobra.Command) (Settings, error) {
+	cfg, err := LoadConfig(cmd)
+	if err != nil {
+		return nil, types.Errorf(types.ErrorKindValidation, "failed to load config: %w", err)
+	}
+
+	settings, err := ResolveSettings(cmd, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range bindings {
+		setting := settings.lookup(b.name)
+
+		flag := cmd.Flag(b.flag)
+		if flag == nil || setting.Source == SourceFlag || setting.Source == SourceDefault {
+			continue
+		}
+
+		if err := flag.Value.Set(setting.Value); err != nil {
+			return nil, types.Errorf(types.ErrorKindValidation, "invalid %s '%s' from %s: %w",
+				b.name, setting.Value, setting.Source, err)
+		}
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,175 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const profileConfig = `
+profile: staging
+profiles:
+  staging:
+    region: us-west-2
+    path_prefix: staging/
+    key_size: 4096
+    output: yaml
+    timeout: 10s
+  prod:
+    region: us-east-1
+    backend: vault
+`
+
+// newStoreCommand returns the store command mounted under a root command
+// with the persistent flags, reading the given config file.
+func newStoreCommand(t *testing.T, configYAML string, flags ...string) *cobra.Command {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(configYAML), 0o600))
+
+	root := &cobra.Command{Use: "root"}
+	args.AttachConfigFlag(root)
+	args.AttachProfileFlags(root)
+	args.AttachNamingFlags(root)
+	args.AttachOutputFlag(root)
+	args.AttachAWSFlags(root)
+
+	cmd, err := args.MountRotateCommand(func(*cobra.Command, []string) error { return nil })
+	require.NoError(t, err)
+	root.AddCommand(cmd)
+
+	require.NoError(t, cmd.ParseFlags(append([]string{"--config", path}, flags...)))
+
+	return cmd
+}
+
+func TestResolveSettingsPrecedence(t *testing.T) {
+	t.Setenv(app.EnvRegion, "")
+	t.Setenv(app.EnvKeySize, "3072")
+
+	cmd := newStoreCommand(t, profileConfig, "--output", "json")
+
+	cfg, err := app.LoadConfig(cmd)
+	require.NoError(t, err)
+
+	settings, err := app.ResolveSettings(cmd, cfg)
+	require.NoError(t, err)
+
+	expected := map[string]app.Setting{
+		app.SettingProfile:    {Value: "staging", Source: app.SourceConfig},
+		app.SettingBackend:    {Value: "ssm", Source: app.SourceDefault},
+		app.SettingRegion:     {Value: "us-west-2", Source: app.SourceProfile},
+		app.SettingPathPrefix: {Value: "staging/", Source: app.SourceProfile},
+		app.SettingKeySize:    {Value: "3072", Source: app.SourceEnv},
+		app.SettingOutput:     {Value: "json", Source: app.SourceFlag},
+	}
+
+	for _, setting := range settings {
+		if want, ok := expected[setting.Name]; ok {
+			assert.Equal(t, want.Value, setting.Value, setting.Name)
+			assert.Equal(t, want.Source, setting.Source, setting.Name)
+		}
+	}
+}
+
+func TestConfigOptions(t *testing.T) {
+	t.Setenv(app.EnvRegion, "ap-south-1")
+	t.Setenv(app.EnvRoleARN, "")
+	t.Setenv(app.EnvEndpointURL, "")
+	t.Setenv(app.EnvRetryMode, "")
+	t.Setenv(app.EnvMaxAttempts, "")
+
+	cmd := newStoreCommand(t, `
+profiles:
+  local:
+    region: us-east-1
+    role_arn: arn:aws:iam::123456789012:role/rotator
+    endpoint_url: http://localhost:4566
+    retry_mode: adaptive
+    max_attempts: 6
+`, "--config-profile", "local", "--role-arn", "arn:aws:iam::123456789012:role/override",
+		"--max-attempts", "2")
+
+	cfg, err := app.LoadConfig(cmd)
+	require.NoError(t, err)
+
+	settings, err := app.ResolveSettings(cmd, cfg)
+	require.NoError(t, err)
+
+	options := settings.ConfigOptions()
+	assert.Equal(t, "ap-south-1", options.Region, "the environment beats the profile")
+	assert.Equal(t, "arn:aws:iam::123456789012:role/override", options.RoleARN, "flags beat the profile")
+	assert.Equal(t, "http://localhost:4566", options.EndpointURL)
+	assert.Equal(t, "adaptive", options.RetryMode)
+	assert.Equal(t, 2, options.MaxAttempts)
+}
+
+func TestApplySettings(t *testing.T) {
+	t.Setenv(app.EnvKeySize, "")
+	t.Setenv(app.EnvOutput, "")
+	t.Setenv(app.EnvPathPrefix, "")
+	t.Setenv(app.EnvTimeout, "")
+
+	cmd := newStoreCommand(t, profileConfig, "--name", "payments")
+
+	_, err := app.ApplySettings(cmd)
+	require.NoError(t, err)
+
+	assert.Equal(t, "4096", args.GetSize(cmd))
+	assert.Equal(t, "yaml", args.GetOutput(cmd))
+	assert.Equal(t, "staging/payments", args.GetName(cmd))
+	assert.Equal(t, 10*time.Second, args.GetDuration(cmd, args.FlagStringTimeout))
+	assert.False(t, cmd.Flag(args.FlagStringSize).Changed, "profile values are not flags given on the command line")
+}
+
+func TestApplySettingsRejectsInvalidValues(t *testing.T) {
+	t.Setenv(app.EnvKeySize, "big")
+
+	_, err := app.ApplySettings(newStoreCommand(t, profileConfig))
+	assert.Equal(t, types.ErrorKindValidation, types.KindOf(err))
+
+	t.Setenv(app.EnvKeySize, "")
+
+	_, err = app.ApplySettings(newStoreCommand(t, profileConfig, "--config-profile", "prod"))
+	assert.ErrorContains(t, err, "unsupported backend")
+
+	_, err = app.ApplySettings(newStoreCommand(t, profileConfig, "--retry-mode", "eventually"))
+	assert.ErrorContains(t, err, "unsupported retry mode")
+
+	t.Setenv(app.EnvProfile, "missing")
+
+	_, err = app.ApplySettings(newStoreCommand(t, profileConfig))
+	assert.ErrorContains(t, err, "profile 'missing' not found")
+}
+
+func TestApplySettingsNameTemplate(t *testing.T) {
+	t.Setenv(app.EnvPathPrefix, "")
+	t.Setenv(app.EnvEnvironment, "")
+	t.Setenv(app.EnvService, "billing")
+
+	cmd := newStoreCommand(t, `
+profile: prod
+profiles:
+  prod:
+    name_template: /{env}/{service}/{purpose}
+    env: prod
+    service: payments
+`, "--name", "signing")
+
+	_, err := app.ApplySettings(cmd)
+	require.NoError(t, err)
+
+	name, err := app.Command{}.Name(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "/prod/billing/signing", name, "the environment beats the profile")
+}
@@ -0,0 +1,437 @@
+// Package apptest runs commands against fakes of everything app.Command
+// depends on: an in-memory Parameter Store, pre-generated keys, an
+// in-memory file system, recorded child processes, a fixed clock, a fixed
+// caller identity, and given input and captured output.
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	rotator "github.com/kmesiab/go-key-rotator"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/identity"
+	"github.com/kmesiab/go-key-rotator-cli/process"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/ssmfake"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Now is the time the fake clock is set to.
+var Now = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// Account and CallerARN are the identity the fake Identity reports.
+const (
+	Account   = "123456789012"
+	CallerARN = "arn:aws:iam::123456789012:user/apptest"
+)
+
+// Env is a command's fake environment.
+type Env struct {
+	Command   app.Command
+	SSM       *ssmfake.SSM
+	Generator *Generator
+	Files     *Files
+	Processes *Processes
+	Clock     *Clock
+	Identity  *Identity
+	Stdin     *bytes.Buffer
+	Stdout    *bytes.Buffer
+	Stderr    *bytes.Buffer
+
+	config string
+}
+
+// New returns an environment whose Command is backed entirely by fakes.
+func New(t *testing.T) *Env {
+	t.Helper()
+
+	env := &Env{
+		SSM:       ssmfake.New(),
+		Generator: &Generator{},
+		Files:     &Files{},
+		Processes: &Processes{},
+		Clock:     &Clock{Time: Now},
+		Identity:  &Identity{Account: Account, ARN: CallerARN},
+		Stdin:     &bytes.Buffer{},
+		Stdout:    &bytes.Buffer{},
+		Stderr:    &bytes.Buffer{},
+		config:    filepath.Join(t.TempDir(), "config.yaml"),
+	}
+
+	env.SSM.Now = env.Clock.Now
+
+	env.Command = app.NewCommand(&aws.ParameterStore{SSM: env.SSM}, awssdk.Config{})
+	env.Command.KeyGenerator = env.Generator
+	env.Command.Files = env.Files
+	env.Command.Processes = env.Processes
+	env.Command.Clock = env.Clock
+	env.Command.Identity = env.Identity
+	env.Command.Stdin = env.Stdin
+	env.Command.Stdout = env.Stdout
+	env.Command.Stderr = env.Stderr
+
+	if keyRotator, ok := env.Command.KeyRotator.(*rotation.TransactionalRotator); ok {
+		keyRotator.Generator = env.Generator
+	}
+
+	env.WriteConfig(t, "")
+
+	return env
+}
+
+// WriteConfig replaces the config file commands read.
+func (e *Env) WriteConfig(t *testing.T, yaml string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(e.config, []byte(yaml), 0o600))
+}
+
+// Run mounts a command with mount under a root command carrying the
+// persistent flags, and executes it with flags. The output buffers are
+// reset first.
+func (e *Env) Run(mount args.MountCommandFunc, run args.CommandRunFunc, flags ...string) error {
+	return e.RunContext(context.Background(), mount, run, flags...)
+}
+
+// RunContext is Run with the command's context set to ctx, for commands
+// that run until it is cancelled.
+func (e *Env) RunContext(
+	ctx context.Context,
+	mount args.MountCommandFunc,
+	run args.CommandRunFunc,
+	flags ...string,
+) error {
+	e.Stdout.Reset()
+	e.Stderr.Reset()
+
+	root := &cobra.Command{Use: "go-rotate", SilenceErrors: true, SilenceUsage: true}
+	args.AttachConfigFlag(root)
+	args.AttachProfileFlags(root)
+	args.AttachNamingFlags(root)
+	args.AttachAWSFlags(root)
+	args.AttachOutputFlag(root)
+	args.AttachAuditLogFlag(root)
+
+	cmd, err := mount(run)
+	if err != nil {
+		return err
+	}
+
+	root.AddCommand(cmd)
+	root.SetArgs(append([]string{cmd.Name(), "--config", e.config}, flags...))
+
+	return root.ExecuteContext(ctx)
+}
+
+// StoreKeyPair stores a key pair the Generator never hands out as name.
+func (e *Env) StoreKeyPair(t *testing.T, name string) *rsa.PrivateKey {
+	t.Helper()
+
+	pool, err := pooledKeys()
+	require.NoError(t, err)
+
+	privateKey := pool[len(pool)-1]
+
+	publicKeyPEM, err := rotator.EncodePublicKeyToPEM(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	store := e.Command.ParameterStore
+	require.NoError(t, store.PutParameter(aws.MakePrivateKeyName(name),
+		string(rotator.EncodePrivateKeyToPEM(privateKey)), rotation.ParameterTypeSecureString))
+	require.NoError(t, store.PutParameter(aws.MakePublicKeyName(name),
+		string(publicKeyPEM), rotation.ParameterTypeSecureString))
+
+	return privateKey
+}
+
+var (
+	keysOnce sync.Once
+	keys     []*rsa.PrivateKey
+	keysErr  error
+)
+
+// pooledKeys returns key pairs generated once per test binary, since
+// generating RSA keys is slow.
+func pooledKeys() ([]*rsa.PrivateKey, error) {
+	keysOnce.Do(func() {
+		for i := 0; i < 3 && keysErr == nil; i++ {
+			var privateKey *rsa.PrivateKey
+
+			_, privateKey, keysErr = rotation.RSAKeyGenerator{}.GenerateKeyPair(2048)
+			keys = append(keys, privateKey)
+		}
+	})
+
+	return keys, keysErr
+}
+
+// Generator takes turns handing out two pooled key pairs, whatever the
+// size asked for. Err fails every call.
+type Generator struct {
+	Err error
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (g *Generator) GenerateKeyPair(int) (*rsa.PublicKey, *rsa.PrivateKey, error) {
+	if g.Err != nil {
+		return nil, nil, g.Err
+	}
+
+	pool, err := pooledKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	privateKey := pool[g.calls%2]
+	g.calls++
+
+	return &privateKey.PublicKey, privateKey, nil
+}
+
+// Calls returns how many key pairs were generated.
+func (g *Generator) Calls() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.calls
+}
+
+// Clock is stopped at Time. After fires immediately.
+type Clock struct {
+	Time time.Time
+}
+
+func (c *Clock) Now() time.Time {
+	return c.Time
+}
+
+func (c *Clock) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Time
+
+	return ch
+}
+
+// Identity reports a fixed caller identity. Err fails every lookup.
+type Identity struct {
+	Account string
+	ARN     string
+	Err     error
+}
+
+func (i *Identity) Caller(context.Context) (identity.Caller, error) {
+	if i.Err != nil {
+		return identity.Caller{}, i.Err
+	}
+
+	return identity.Caller{Account: i.Account, ARN: i.ARN}, nil
+}
+
+// Files is an in-memory filesystem.FileSystem. Keys written to stdout go
+// to the destination's Stdout, as on disk.
+type Files struct {
+	// CheckErr fails every Check and write.
+	CheckErr error
+
+	mu    sync.Mutex
+	files map[string][]byte
+	modes map[string]os.FileMode
+}
+
+// Read returns the contents of the file at path.
+func (f *Files) Read(path string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.files[path]
+
+	return data, ok
+}
+
+// Mode returns the mode the file at path was written with.
+func (f *Files) Mode(path string) os.FileMode {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.modes[path]
+}
+
+// Put adds a file, as if it existed before the command ran.
+func (f *Files) Put(path string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.put(path, data, filesystem.PublicKeyMode)
+}
+
+func (f *Files) Check(destination filesystem.Destination, privateKeyName, publicKeyName string) error {
+	if f.CheckErr != nil {
+		return f.CheckErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	files := destination.Paths(privateKeyName, publicKeyName)
+
+	for _, path := range []string{files.PrivateKey, files.PublicKey} {
+		if _, exists := f.files[path]; exists && !destination.Force && path != filesystem.StdoutPath {
+			return fmt.Errorf("%s: %w", path, filesystem.ErrFileExists)
+		}
+	}
+
+	return nil
+}
+
+func (f *Files) WriteKeyPair(destination filesystem.Destination, pair *types.Rotation) (filesystem.KeyPairFiles, error) {
+	files := destination.Paths(pair.PrivateKeyName, pair.PublicKeyName)
+
+	if err := f.Check(destination, pair.PrivateKeyName, pair.PublicKeyName); err != nil {
+		return files, err
+	}
+
+	publicKeyPEM, err := rotator.EncodePublicKeyToPEM(pair.PublicKey)
+	if err != nil {
+		return files, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, file := range []struct {
+		path string
+		data []byte
+		mode os.FileMode
+	}{
+		{files.PrivateKey, rotator.EncodePrivateKeyToPEM(pair.PrivateKey), filesystem.PrivateKeyMode},
+		{files.PublicKey, publicKeyPEM, filesystem.PublicKeyMode},
+	} {
+		if file.path != filesystem.StdoutPath {
+			f.put(file.path, file.data, file.mode)
+
+			continue
+		}
+
+		if _, err := destination.Stdout.Write(file.data); err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
+
+func (f *Files) ReadFile(path string) ([]byte, error) {
+	data, ok := f.Read(path)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+
+	return data, nil
+}
+
+func (f *Files) WriteFile(path string, data []byte, mode os.FileMode, options filesystem.WriteOptions) error {
+	if f.CheckErr != nil {
+		return f.CheckErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.files[path]; exists && !options.Overwrite {
+		return fmt.Errorf("%s: %w", path, filesystem.ErrFileExists)
+	}
+
+	f.put(path, data, mode)
+
+	return nil
+}
+
+func (f *Files) put(path string, data []byte, mode os.FileMode) {
+	if f.files == nil {
+		f.files = make(map[string][]byte)
+		f.modes = make(map[string]os.FileMode)
+	}
+
+	f.files[path] = append([]byte(nil), data...)
+	f.modes[path] = mode
+}
+
+// Processes is a process.Runner that records the commands it is asked to
+// run instead of starting them. Each one reads all of its stdin and writes
+// Output to its stdout.
+type Processes struct {
+	// Code is the exit code of every command. Err fails to start them.
+	Code   int
+	Err    error
+	Output string
+
+	mu   sync.Mutex
+	runs []ProcessRun
+}
+
+// ProcessRun is a command a Processes was asked to run.
+type ProcessRun struct {
+	Command []string
+	Env     []string
+	Stdin   string
+}
+
+func (p *Processes) Run(command []string, env []string, stdio process.Stdio) (int, error) {
+	if p.Err != nil {
+		return 0, p.Err
+	}
+
+	run := ProcessRun{Command: command, Env: env}
+
+	if stdio.Stdin != nil {
+		stdin, err := io.ReadAll(stdio.Stdin)
+		if err != nil {
+			return 0, err
+		}
+
+		run.Stdin = string(stdin)
+	}
+
+	if stdio.Stdout != nil {
+		if _, err := io.WriteString(stdio.Stdout, p.Output); err != nil {
+			return 0, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.runs = append(p.runs, run)
+
+	return p.Code, nil
+}
+
+// Runs returns the commands run so far, in order.
+func (p *Processes) Runs() []ProcessRun {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]ProcessRun(nil), p.runs...)
+}
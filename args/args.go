@@ -1,7 +1,14 @@
 package args
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/naming"
 )
 
 // Constants.  Real life constant values
@@ -17,9 +24,134 @@ const (
 	DefaultKeySize          = 2048
 	FlagStringSize          = "size"
 	FlagStringSizeShorthand = "s"
+
+	// arg: --config
+
+	FlagStringConfig          = "config"
+	FlagStringConfigShorthand = "c"
+
+	// arg: --config-profile, --path-prefix
+
+	FlagStringConfigProfile = "config-profile"
+	FlagStringPathPrefix    = "path-prefix"
+
+	// arg: --name-template, --env, --service
+
+	FlagStringNameTemplate = "name-template"
+	FlagStringEnv          = "env"
+	FlagStringService      = "service"
+
+	// arg: --region, --profile, --role-arn, --external-id, --mfa-serial,
+	// --mfa-token, --endpoint-url
+
+	FlagStringRegion      = "region"
+	FlagStringProfile     = "profile"
+	FlagStringRoleARN     = "role-arn"
+	FlagStringExternalID  = "external-id"
+	FlagStringMFASerial   = "mfa-serial"
+	FlagStringMFAToken    = "mfa-token"
+	FlagStringEndpointURL = "endpoint-url"
+
+	// arg: --retry-mode, --max-attempts, --timeout
+
+	FlagStringRetryMode   = "retry-mode"
+	FlagStringMaxAttempts = "max-attempts"
+	FlagStringTimeout     = "timeout"
+	DefaultRetryMode      = "standard"
+	DefaultMaxAttempts    = 3
+	DefaultTimeout        = 30 * time.Second
+
+	// arg: --kms-key-id, --tag, --tier, --expire-after
+
+	FlagStringKMSKeyID    = "kms-key-id"
+	FlagStringTag         = "tag"
+	FlagStringTier        = "tier"
+	FlagStringExpireAfter = "expire-after"
+
+	// arg: --out-dir, --private-out, --public-out, --force, --no-write, --chown
+
+	FlagStringOutDir     = "out-dir"
+	FlagStringPrivateOut = "private-out"
+	FlagStringPublicOut  = "public-out"
+	FlagStringForce      = "force"
+	FlagStringNoWrite    = "no-write"
+	FlagStringChown      = "chown"
+
+	// arg: --private-key-env, --public-key-env, --encoding
+
+	DefaultPrivateKeyEnv    = "GO_ROTATE_PRIVATE_KEY"
+	DefaultPublicKeyEnv     = "GO_ROTATE_PUBLIC_KEY"
+	EncodingPEM             = "pem"
+	EncodingBase64          = "base64"
+	FlagStringPrivateKeyEnv = "private-key-env"
+	FlagStringPublicKeyEnv  = "public-key-env"
+	FlagStringEncoding      = "encoding"
+
+	// arg: --template, --out
+
+	FlagStringTemplate          = "template"
+	FlagStringTemplateShorthand = "t"
+	FlagStringOut               = "out"
+
+	// arg: --watch, --interval, --reload-pid, --reload-signal, --reload-command
+
+	DefaultWatchInterval    = time.Minute
+	DefaultReloadSignal     = "HUP"
+	FlagStringWatch         = "watch"
+	FlagStringInterval      = "interval"
+	FlagStringReloadPID     = "reload-pid"
+	FlagStringReloadSignal  = "reload-signal"
+	FlagStringReloadCommand = "reload-command"
+
+	// arg: --output
+
+	DefaultOutput             = "text"
+	FlagStringOutput          = "output"
+	FlagStringOutputShorthand = "o"
+
+	// arg: --audit-log
+
+	FlagStringAuditLog = "audit-log"
+
+	// arg: --max-age
+
+	FlagStringMaxAge = "max-age"
+
+	// arg: --if-due
+
+	FlagStringIfDue = "if-due"
+
+	// arg: --dry-run
+
+	FlagStringDryRun = "dry-run"
+
+	// arg: --lock, --lock-ttl, --lock-wait, --lock-dir
+
+	LockBackendNone    = "none"
+	LockBackendFile    = "file"
+	LockBackendSSM     = "ssm"
+	DefaultLockBackend = LockBackendNone
+	DefaultLockTTL     = 5 * time.Minute
+	FlagStringLock     = "lock"
+	FlagStringLockTTL  = "lock-ttl"
+	FlagStringLockWait = "lock-wait"
+	FlagStringLockDir  = "lock-dir"
+
+	// arg: --health-addr
+
+	DefaultHealthAddr    = ":8080"
+	FlagStringHealthAddr = "health-addr"
+
+	// arg: --command, --account-id
+
+	FlagStringCommand   = "command"
+	FlagStringAccountID = "account-id"
 )
 
-type CommandRunFunc func(cmd *cobra.Command, args []string)
+type CommandRunFunc func(cmd *cobra.Command, args []string) error
+
+// MountCommandFunc builds a sub command around the given run function.
+type MountCommandFunc func(run CommandRunFunc) (*cobra.Command, error)
 
 func Init(rootCmd *cobra.Command, runGenerateKeys, RunRotateKeys, runFetch CommandRunFunc) error {
 	var (
@@ -48,12 +180,24 @@ func Init(rootCmd *cobra.Command, runGenerateKeys, RunRotateKeys, runFetch Comma
 	return nil
 }
 
+// Mount builds a sub command with mount and adds it to the root command.
+func Mount(rootCmd *cobra.Command, mount MountCommandFunc, run CommandRunFunc) error {
+	cmd, err := mount(run)
+	if err != nil {
+		return err
+	}
+
+	rootCmd.AddCommand(cmd)
+
+	return nil
+}
+
 func MountGenerateCommand(runGenerateKeys CommandRunFunc) (*cobra.Command, error) {
 	generateCmd := &cobra.Command{
 		Use:   "generate",
 		Short: "Generates a new public/private key pair, but does not store it",
-		Run: func(cmd *cobra.Command, args []string) {
-			runGenerateKeys(cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGenerateKeys(cmd, args)
 		},
 	}
 
@@ -67,6 +211,9 @@ func MountGenerateCommand(runGenerateKeys CommandRunFunc) (*cobra.Command, error
 		return nil, err
 	}
 
+	// --out-dir, --private-out, --public-out, --force and --chown flags
+	AttachKeyOutputFlags(generateCmd)
+
 	return generateCmd, nil
 }
 
@@ -74,8 +221,8 @@ func MountRotateCommand(RunRotateKeys CommandRunFunc) (*cobra.Command, error) {
 	rotateCommand := &cobra.Command{
 		Use:   "store",
 		Short: "Generates and stores a public/private key pair",
-		Run: func(cmd *cobra.Command, args []string) {
-			RunRotateKeys(cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunRotateKeys(cmd, args)
 		},
 	}
 
@@ -89,6 +236,30 @@ func MountRotateCommand(RunRotateKeys CommandRunFunc) (*cobra.Command, error) {
 		return nil, err
 	}
 
+	// --max-age flag
+	AttachMaxAgeFlag(rotateCommand)
+
+	// --if-due flag
+	rotateCommand.Flags().Bool(FlagStringIfDue, false,
+		"Only rotate when the key's rotation policy reports it as due, overdue or missing")
+
+	// --dry-run flag
+	rotateCommand.Flags().Bool(FlagStringDryRun, false,
+		"Validate the key, read the current pair and print the parameter writes a store would make, without making them")
+
+	// --lock flags
+	AttachLockFlags(rotateCommand)
+
+	// --out-dir, --private-out, --public-out, --force and --chown flags
+	AttachKeyOutputFlags(rotateCommand)
+
+	// --no-write flag
+	rotateCommand.Flags().Bool(FlagStringNoWrite, false,
+		"Only store the keys; do not write them to disk or stdout")
+
+	// --kms-key-id, --tag, --tier and --expire-after flags
+	AttachParameterFlags(rotateCommand)
+
 	return rotateCommand, nil
 }
 
@@ -96,8 +267,8 @@ func MountFetchCommand(runFetch CommandRunFunc) (*cobra.Command, error) {
 	getCommand := &cobra.Command{
 		Use:   "fetch",
 		Short: "Downloads your public/private key pair",
-		Run: func(cmd *cobra.Command, args []string) {
-			runFetch(cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFetch(cmd, args)
 		},
 	}
 
@@ -106,9 +277,358 @@ func MountFetchCommand(runFetch CommandRunFunc) (*cobra.Command, error) {
 		return nil, err
 	}
 
+	// --out-dir, --private-out, --public-out, --force and --chown flags
+	AttachKeyOutputFlags(getCommand)
+
+	// --watch flags
+	getCommand.Flags().Bool(FlagStringWatch, false,
+		"Keep running and rewrite the key files whenever the stored keys change")
+	getCommand.Flags().Duration(FlagStringInterval, DefaultWatchInterval,
+		"How often --watch checks the store for a new version")
+	getCommand.Flags().Int(FlagStringReloadPID, 0,
+		"Signal this process after --watch rewrites the key files")
+	getCommand.Flags().String(FlagStringReloadSignal, DefaultReloadSignal,
+		"Signal to send to --reload-pid")
+	getCommand.Flags().String(FlagStringReloadCommand, "",
+		"Shell command to run after --watch rewrites the key files")
+
 	return getCommand, nil
 }
 
+func MountStatusCommand(runStatus CommandRunFunc) (*cobra.Command, error) {
+	statusCommand := &cobra.Command{
+		Use:   "status",
+		Short: "Reports the rotation status of your key pairs",
+		Long: `
+Reports whether each key pair is OK, DUE or OVERDUE for rotation according
+to its max-age policy. Without --name, every key listed in the config file
+is reported.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd, args)
+		},
+	}
+
+	// --name flag, optional here
+	statusCommand.Flags().StringP(FlagStringName, FlagStringNameShorthand, DefaultName,
+		"Specify the name prefix of the key pair to report on")
+
+	// --max-age flag
+	AttachMaxAgeFlag(statusCommand)
+
+	return statusCommand, nil
+}
+
+func MountExecCommand(runExec CommandRunFunc) (*cobra.Command, error) {
+	execCommand := &cobra.Command{
+		Use:   "exec --name NAME -- command [args...]",
+		Short: "Runs a command with your key pair in its environment",
+		Long: `
+Fetches the key pair and runs the command with both keys in environment
+variables, so they never touch disk. Signals are forwarded to the command
+and go-rotate exits with the command's exit code.
+	`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExec(cmd, args)
+		},
+	}
+
+	// --name flag
+	if err := AttachNameFlag(execCommand); err != nil {
+		return nil, err
+	}
+
+	execCommand.Flags().String(FlagStringPrivateKeyEnv, DefaultPrivateKeyEnv,
+		"Environment variable to hold the private key. Empty to leave it out")
+	execCommand.Flags().String(FlagStringPublicKeyEnv, DefaultPublicKeyEnv,
+		"Environment variable to hold the public key. Empty to leave it out")
+	execCommand.Flags().String(FlagStringEncoding, EncodingPEM,
+		"Encoding of the keys in the environment: pem or base64")
+
+	return execCommand, nil
+}
+
+func MountRenderCommand(runRender CommandRunFunc) (*cobra.Command, error) {
+	renderCommand := &cobra.Command{
+		Use:   "render",
+		Short: "Renders a Go template with your keys into a file",
+		Long: `
+Renders a Go template into a config file. Templates can call
+privateKeyPEM, publicKeyPEM, publicKeyJWK and fingerprint with a key
+name, and indent to embed multi-line keys. The output is written
+atomically, and is only readable by its owner if it holds a private key.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRender(cmd, args)
+		},
+	}
+
+	renderCommand.Flags().StringP(FlagStringTemplate, FlagStringTemplateShorthand, "",
+		"Path to the Go template to render")
+	renderCommand.Flags().String(FlagStringOut, "",
+		"Path to write the rendered output to, or - for stdout")
+
+	for _, name := range []string{FlagStringTemplate, FlagStringOut} {
+		if err := renderCommand.MarkFlagRequired(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// --chown flag
+	renderCommand.Flags().String(FlagStringChown, "",
+		"Change the owner of the output file to user[:group], by name or numeric ID")
+
+	return renderCommand, nil
+}
+
+func MountDaemonCommand(runDaemon CommandRunFunc) (*cobra.Command, error) {
+	daemonCommand := &cobra.Command{
+		Use:   "daemon",
+		Short: "Rotates keys on a schedule until stopped",
+		Long: `
+Runs in the foreground and rotates every key listed in the config file
+on its own interval or cron schedule, taking the same --lock as store
+so scheduled and manual rotations exclude each other. Job health is
+served as JSON on /healthz. SIGTERM and SIGINT stop the daemon once any
+rotation in progress has finished.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDaemon(cmd, args)
+		},
+	}
+
+	// --health-addr flag
+	daemonCommand.Flags().String(FlagStringHealthAddr, "",
+		"Address for the health endpoint. Overrides daemon.health_addr in the config file. "+
+			"Default is "+DefaultHealthAddr)
+
+	// --lock flags
+	AttachLockFlags(daemonCommand)
+
+	// --kms-key-id, --tag, --tier and --expire-after flags
+	AttachParameterFlags(daemonCommand)
+
+	return daemonCommand, nil
+}
+
+func MountDoctorCommand(runDoctor CommandRunFunc) (*cobra.Command, error) {
+	doctorCommand := &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks that you can store and fetch a key pair",
+		Long: `
+Checks your AWS credentials, region and caller identity, then whether you
+may read, decrypt, write and delete both parameters of the key pair and
+use its KMS key. Existing keys are never changed: writes are checked by
+creating a parameter that already exists, and deletes only on parameters
+that do not exist yet, by creating and removing a probe value. The checks
+hold the rotation lock chosen by --lock.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd, args)
+		},
+	}
+
+	// --name flag
+	if err := AttachNameFlag(doctorCommand); err != nil {
+		return nil, err
+	}
+
+	// --kms-key-id flag
+	doctorCommand.Flags().String(FlagStringKMSKeyID, "",
+		"KMS key ID, ARN or alias to check. Default is the account's aws/ssm key")
+
+	// --lock flags
+	AttachLockFlags(doctorCommand)
+
+	return doctorCommand, nil
+}
+
+func MountIAMPolicyCommand(runIAMPolicy CommandRunFunc) (*cobra.Command, error) {
+	iamPolicyCommand := &cobra.Command{
+		Use:   "iam-policy",
+		Short: "Prints the least-privilege IAM policy for your key pair",
+		Long: `
+Prints the IAM policy JSON that allows exactly what the chosen commands do
+with the key pair: reading, writing and deleting its two parameters, the
+rotation lock and the KMS key. The region comes from --region or your AWS
+config; without it, or without --account-id, the ARNs match any.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runIAMPolicy(cmd, args)
+		},
+	}
+
+	// --name flag
+	if err := AttachNameFlag(iamPolicyCommand); err != nil {
+		return nil, err
+	}
+
+	iamPolicyCommand.Flags().StringArray(FlagStringCommand, nil,
+		"Command the policy is for, e.g. store or fetch. Repeat for more commands. Default is every command")
+	iamPolicyCommand.Flags().String(FlagStringAccountID, "",
+		"AWS account ID the parameters are in. Default is any account")
+	iamPolicyCommand.Flags().String(FlagStringKMSKeyID, "",
+		"KMS key ID, ARN or alias the parameters are encrypted with. Default is the account's aws/ssm key")
+	iamPolicyCommand.Flags().String(FlagStringLock, DefaultLockBackend,
+		"Lock backend store, daemon and doctor use: none, file or ssm. ssm adds the lock parameter")
+
+	return iamPolicyCommand, nil
+}
+
+// AttachConfigFlag attaches the persistent --config flag to the root command.
+func AttachConfigFlag(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().StringP(FlagStringConfig, FlagStringConfigShorthand, "",
+		"Path to a go-rotate YAML config file. Default is to read "+
+			"~/.config/go-rotate/config.yaml and ./.go-rotate.yaml")
+}
+
+// AttachProfileFlags attaches the persistent --config-profile and
+// --path-prefix flags to the root command.
+func AttachProfileFlags(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().String(FlagStringConfigProfile, "",
+		"Config file profile to take defaults from. Default is the profile named in the config file")
+	rootCmd.PersistentFlags().String(FlagStringPathPrefix, "",
+		"Prefix added to every --name, e.g. /prod/")
+}
+
+// AttachNamingFlags attaches the persistent --name-template, --env and
+// --service flags to the root command.
+func AttachNamingFlags(rootCmd *cobra.Command) {
+	flags := rootCmd.PersistentFlags()
+
+	flags.String(FlagStringNameTemplate, "",
+		"Build key names from a template of {env}, {service} and {purpose}, the --name. "+
+			"e.g. /{env}/{service}/{purpose}")
+	flags.String(FlagStringEnv, "", "Environment filled into {env} in --name-template, e.g. prod")
+	flags.String(FlagStringService, "", "Service filled into {service} in --name-template, e.g. payments")
+}
+
+// AttachAWSFlags attaches the persistent flags that configure AWS access
+// to the root command.
+func AttachAWSFlags(rootCmd *cobra.Command) {
+	flags := rootCmd.PersistentFlags()
+
+	flags.String(FlagStringRegion, "", "AWS region, e.g. us-west-2")
+	flags.String(FlagStringProfile, "", "AWS shared config profile")
+	flags.String(FlagStringRoleARN, "", "ARN of an IAM role to assume")
+	flags.String(FlagStringExternalID, "", "External ID to pass when assuming --role-arn")
+	flags.String(FlagStringMFASerial, "",
+		"Serial number or ARN of the MFA device required to assume --role-arn")
+	flags.String(FlagStringMFAToken, "",
+		"MFA token code for --mfa-serial. Prompted for on stderr when not given")
+	flags.String(FlagStringEndpointURL, "",
+		"Override the AWS endpoint URL, e.g. http://localhost:4566 for LocalStack")
+	flags.String(FlagStringRetryMode, DefaultRetryMode,
+		"How failed AWS calls are retried: standard or adaptive (which also rate limits)")
+	flags.Int(FlagStringMaxAttempts, DefaultMaxAttempts,
+		"Maximum attempts per AWS call, including the first. 1 disables retries")
+	flags.Duration(FlagStringTimeout, DefaultTimeout,
+		"Give up on a Parameter Store operation, retries included, after this long")
+}
+
+// AttachLockFlags attaches the flags controlling how concurrent rotations
+// of the same key are prevented.
+func AttachLockFlags(cmd *cobra.Command) {
+	cmd.Flags().String(FlagStringLock, DefaultLockBackend,
+		"Lock the key while rotating it: none, file (this machine only) or ssm (a lock parameter in Parameter Store)")
+	cmd.Flags().Duration(FlagStringLockTTL, DefaultLockTTL,
+		"How long a lock is held before it expires, in case the holder crashed. Must be positive. The holder renews it while rotating")
+	cmd.Flags().Duration(FlagStringLockWait, 0,
+		"How long to wait for a held lock. Default is to fail immediately")
+	cmd.Flags().String(FlagStringLockDir, os.TempDir(),
+		"Directory for lock files when --lock=file")
+}
+
+// AttachParameterFlags attaches the flags that control how both parameters
+// of a key pair are stored.
+func AttachParameterFlags(cmd *cobra.Command) {
+	cmd.Flags().String(FlagStringKMSKeyID, "",
+		"KMS key ID, ARN or alias to encrypt both parameters with. Default is the account's aws/ssm key")
+	cmd.Flags().StringArray(FlagStringTag, nil,
+		"Tag both parameters with key=value. Repeat for more tags")
+	cmd.Flags().String(FlagStringTier, "",
+		"Parameter tier: Standard, Advanced or Intelligent-Tiering. Default is Standard, or Intelligent-Tiering "+
+			"when parameter policies are attached")
+	cmd.Flags().String(FlagStringExpireAfter, "",
+		"Have Parameter Store delete both parameters this long after storing them, e.g. 90d. Needs the Advanced tier")
+}
+
+// AttachKeyOutputFlags attaches the flags that choose where key files are
+// written.
+func AttachKeyOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().String(FlagStringOutDir, "",
+		"Directory to write the key files to. Defaults to the current directory")
+	cmd.Flags().String(FlagStringPrivateOut, "",
+		"Path to write the private key to, or - for stdout. Overrides --out-dir")
+	cmd.Flags().String(FlagStringPublicOut, "",
+		"Path to write the public key to, or - for stdout. Overrides --out-dir")
+	cmd.Flags().Bool(FlagStringForce, false, "Overwrite existing key files")
+	cmd.Flags().String(FlagStringChown, "",
+		"Change the owner of the key files to user[:group], by name or numeric ID")
+}
+
+// AttachOutputFlag attaches the persistent --output flag to the root command.
+func AttachOutputFlag(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().StringP(FlagStringOutput, FlagStringOutputShorthand, DefaultOutput,
+		"Output format: text, json or yaml. Logs are always written to stderr")
+}
+
+// AttachAuditLogFlag attaches the persistent --audit-log flag to the root
+// command.
+func AttachAuditLogFlag(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().String(FlagStringAuditLog, "",
+		"Append a hash-chained record of every key operation to this file. Overrides audit.file in the config file")
+}
+
+func MountAuditCommand(runVerify CommandRunFunc) (*cobra.Command, error) {
+	auditCommand := &cobra.Command{
+		Use:   "audit",
+		Short: "Works with the audit log of key operations",
+	}
+
+	verifyCommand := &cobra.Command{
+		Use:   "verify",
+		Short: "Checks the audit log's hash chain for tampering",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(cmd, args)
+		},
+	}
+
+	auditCommand.AddCommand(verifyCommand)
+
+	return auditCommand, nil
+}
+
+func MountConfigCommand(runShow CommandRunFunc) (*cobra.Command, error) {
+	configCommand := &cobra.Command{
+		Use:   "config",
+		Short: "Works with go-rotate's configuration",
+	}
+
+	showCommand := &cobra.Command{
+		Use:   "show",
+		Short: "Shows the effective settings and where each one came from",
+		Long: `
+Shows the config files that were read and the value of every setting a
+profile can provide. Each setting comes from, in order of precedence, a
+flag, an environment variable, the active profile, or the default.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runShow(cmd, args)
+		},
+	}
+
+	configCommand.AddCommand(showCommand)
+
+	return configCommand, nil
+}
+
+func AttachMaxAgeFlag(cmd *cobra.Command) {
+	cmd.Flags().String(FlagStringMaxAge, "",
+		"Maximum key age before rotation, e.g. 90d or 720h. Overrides the config file")
+}
+
 func AttachNameFlag(cmd *cobra.Command) error {
 	// --name flag
 	cmd.Flags().StringP(
@@ -133,10 +653,120 @@ func AttachSizeFlag(cmd *cobra.Command) error {
 	return nil
 }
 
+// GetName returns the key name resolved by ResolveName, or an empty string
+// when no name was given or it cannot be resolved.
 func GetName(cmd *cobra.Command) string {
-	return cmd.Flag(FlagStringName).Value.String()
+	name, err := ResolveName(cmd)
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
+
+// ResolveName returns --name expanded by --name-template, or with
+// --path-prefix added when there is no template.
+func ResolveName(cmd *cobra.Command) (string, error) {
+	return ExpandName(cmd, GetString(cmd, FlagStringName))
+}
+
+// ExpandName builds the full key name for name, given as it would be to
+// --name, from --name-template, or by adding --path-prefix when there is no
+// template. Key names in the config file are expanded the same way.
+func ExpandName(cmd *cobra.Command, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	template := GetString(cmd, FlagStringNameTemplate)
+	if template == "" {
+		return GetString(cmd, FlagStringPathPrefix) + name, nil
+	}
+
+	if GetString(cmd, FlagStringPathPrefix) != "" {
+		return "", fmt.Errorf("--%s cannot be combined with --%s. Put the prefix in the template",
+			FlagStringPathPrefix, FlagStringNameTemplate)
+	}
+
+	return naming.Expand(template, naming.Values{
+		Env:     GetString(cmd, FlagStringEnv),
+		Service: GetString(cmd, FlagStringService),
+		Purpose: name,
+	})
 }
 
 func GetSize(cmd *cobra.Command) string {
 	return cmd.Flag(FlagStringSize).Value.String()
 }
+
+// GetString returns the value of a flag, or an empty string when the
+// command does not define it.
+func GetString(cmd *cobra.Command, name string) string {
+	if flag := cmd.Flag(name); flag != nil {
+		return flag.Value.String()
+	}
+
+	return ""
+}
+
+// GetBool returns the value of a boolean flag, or false when the command
+// does not define it.
+func GetBool(cmd *cobra.Command, name string) bool {
+	return GetString(cmd, name) == "true"
+}
+
+// GetStringArray returns the values of a repeatable flag, or nil when the
+// command does not define it.
+func GetStringArray(cmd *cobra.Command, name string) []string {
+	values, err := cmd.Flags().GetStringArray(name)
+	if err != nil {
+		return nil
+	}
+
+	return values
+}
+
+func GetInt(cmd *cobra.Command, name string) int {
+	value, _ := strconv.Atoi(GetString(cmd, name))
+
+	return value
+}
+
+func GetConfigPath(cmd *cobra.Command) string {
+	return GetString(cmd, FlagStringConfig)
+}
+
+func GetConfigProfile(cmd *cobra.Command) string {
+	return GetString(cmd, FlagStringConfigProfile)
+}
+
+func GetHealthAddr(cmd *cobra.Command) string {
+	return GetString(cmd, FlagStringHealthAddr)
+}
+
+func GetOutput(cmd *cobra.Command) string {
+	if output := GetString(cmd, FlagStringOutput); output != "" {
+		return output
+	}
+
+	return DefaultOutput
+}
+
+func GetAuditLogPath(cmd *cobra.Command) string {
+	return GetString(cmd, FlagStringAuditLog)
+}
+
+func GetMaxAge(cmd *cobra.Command) string {
+	return GetString(cmd, FlagStringMaxAge)
+}
+
+// GetDuration returns the value of a duration flag, or zero when the
+// command does not define it.
+func GetDuration(cmd *cobra.Command, name string) time.Duration {
+	d, err := time.ParseDuration(GetString(cmd, name))
+	if err != nil {
+		return 0
+	}
+
+	return d
+}
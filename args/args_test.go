@@ -34,8 +34,10 @@
 package args_test
 
 import (
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -43,16 +45,19 @@ import (
 	"github.com/kmesiab/go-key-rotator-cli/args"
 )
 
-func mockGenerateKeysRunFunc(_ *cobra.Command, _ []string) {
+func mockGenerateKeysRunFunc(_ *cobra.Command, _ []string) error {
 	// Mock implementation
+	return nil
 }
 
-func mockFetchRunFunc(_ *cobra.Command, _ []string) {
+func mockFetchRunFunc(_ *cobra.Command, _ []string) error {
 	// Mock implementation
+	return nil
 }
 
-func mockRotateKeysRunFunc(_ *cobra.Command, _ []string) {
+func mockRotateKeysRunFunc(_ *cobra.Command, _ []string) error {
 	// Mock implementation
+	return nil
 }
 
 func TestGenerateCommandSizeFlag(t *testing.T) {
@@ -122,7 +127,7 @@ func TestInit(t *testing.T) {
 	assert.True(t, foundFetch, "fetch command not added")
 }
 
-func mockCommandRunFunc(_ *cobra.Command, _ []string) {}
+func mockCommandRunFunc(_ *cobra.Command, _ []string) error { return nil }
 
 func TestInitWithError(t *testing.T) {
 	rootCmd := &cobra.Command{Use: "root"}
@@ -234,8 +239,10 @@ func TestFetchCommandMissingRequiredFlag(t *testing.T) {
 
 func TestGenerateCommandCallbackFunction(t *testing.T) {
 	var callbackInvoked bool
-	mockRunFunc := func(cmd *cobra.Command, args []string) {
+	mockRunFunc := func(cmd *cobra.Command, args []string) error {
 		callbackInvoked = true
+
+		return nil
 	}
 
 	cmd, err := args.MountGenerateCommand(mockRunFunc)
@@ -382,3 +389,285 @@ func TestGetSize(t *testing.T) {
 		t.Errorf("Expected size %s, but got %s", expectedSize, size)
 	}
 }
+
+func TestMountStatusCommand(t *testing.T) {
+	cmd, err := args.MountStatusCommand(mockCommandRunFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "status", cmd.Use)
+	assert.NotNil(t, cmd.Flags().Lookup(args.FlagStringName))
+	assert.NotNil(t, cmd.Flags().Lookup(args.FlagStringMaxAge))
+
+	// --name is optional for status
+	cmd.SetArgs([]string{})
+	assert.NoError(t, cmd.Execute())
+}
+
+func TestMount(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "root"}
+	err := args.Mount(rootCmd, args.MountStatusCommand, mockCommandRunFunc)
+	assert.NoError(t, err)
+	assert.Len(t, rootCmd.Commands(), 1)
+}
+
+func TestRotateCommandIfDueFlag(t *testing.T) {
+	cmd, err := args.MountRotateCommand(mockRotateKeysRunFunc)
+	assert.NoError(t, err)
+
+	err = cmd.ParseFlags([]string{"--if-due", "--max-age", "90d"})
+	assert.NoError(t, err)
+	assert.True(t, args.GetBool(cmd, args.FlagStringIfDue))
+	assert.Equal(t, "90d", args.GetMaxAge(cmd))
+}
+
+func TestGetConfigPath(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "root"}
+	assert.Equal(t, "", args.GetConfigPath(rootCmd))
+
+	args.AttachConfigFlag(rootCmd)
+	assert.NoError(t, rootCmd.ParseFlags([]string{"--config", "rotator.yaml"}))
+	assert.Equal(t, "rotator.yaml", args.GetConfigPath(rootCmd))
+}
+
+func TestMountDaemonCommand(t *testing.T) {
+	cmd, err := args.MountDaemonCommand(mockCommandRunFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "daemon", cmd.Use)
+
+	assert.NoError(t, cmd.ParseFlags([]string{"--health-addr", ":9090"}))
+	assert.Equal(t, ":9090", args.GetHealthAddr(cmd))
+}
+
+func TestRotateCommandLockFlags(t *testing.T) {
+	cmd, err := args.MountRotateCommand(mockRotateKeysRunFunc)
+	assert.NoError(t, err)
+
+	// Defaults
+	assert.Equal(t, args.DefaultLockBackend, args.GetString(cmd, args.FlagStringLock))
+	assert.Equal(t, args.DefaultLockTTL, args.GetDuration(cmd, args.FlagStringLockTTL))
+	assert.Equal(t, time.Duration(0), args.GetDuration(cmd, args.FlagStringLockWait))
+
+	err = cmd.ParseFlags([]string{"--lock", "ssm", "--lock-ttl", "10m", "--lock-wait", "30s"})
+	assert.NoError(t, err)
+	assert.Equal(t, args.LockBackendSSM, args.GetString(cmd, args.FlagStringLock))
+	assert.Equal(t, 10*time.Minute, args.GetDuration(cmd, args.FlagStringLockTTL))
+	assert.Equal(t, 30*time.Second, args.GetDuration(cmd, args.FlagStringLockWait))
+}
+
+func TestMountAuditCommand(t *testing.T) {
+	cmd, err := args.MountAuditCommand(mockCommandRunFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "audit", cmd.Use)
+
+	verify, _, err := cmd.Find([]string{"verify"})
+	assert.NoError(t, err)
+	assert.Equal(t, "verify", verify.Use)
+}
+
+func TestGetAuditLogPath(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	args.AttachAuditLogFlag(root)
+
+	assert.NoError(t, root.ParseFlags([]string{"--audit-log", "/tmp/audit.log"}))
+	assert.Equal(t, "/tmp/audit.log", args.GetAuditLogPath(root))
+}
+
+func TestGetOutput(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	assert.Equal(t, args.DefaultOutput, args.GetOutput(root))
+
+	args.AttachOutputFlag(root)
+	assert.Equal(t, args.DefaultOutput, args.GetOutput(root))
+
+	assert.NoError(t, root.ParseFlags([]string{"-o", "json"}))
+	assert.Equal(t, "json", args.GetOutput(root))
+}
+
+func TestMountConfigCommand(t *testing.T) {
+	cmd, err := args.MountConfigCommand(mockCommandRunFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "config", cmd.Use)
+
+	show, _, err := cmd.Find([]string{"show"})
+	assert.NoError(t, err)
+	assert.Equal(t, "show", show.Use)
+	assert.NotNil(t, show.RunE)
+}
+
+func TestAttachAWSFlags(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	args.AttachAWSFlags(root)
+
+	assert.NoError(t, root.ParseFlags([]string{
+		"--region", "eu-west-1",
+		"--profile", "prod",
+		"--role-arn", "arn:aws:iam::123456789012:role/rotator",
+		"--external-id", "partner-42",
+		"--mfa-serial", "arn:aws:iam::123456789012:mfa/me",
+		"--mfa-token", "123456",
+		"--endpoint-url", "http://localhost:4566",
+	}))
+
+	assert.Equal(t, "eu-west-1", args.GetString(root, args.FlagStringRegion))
+	assert.Equal(t, "prod", args.GetString(root, args.FlagStringProfile))
+	assert.Equal(t, "arn:aws:iam::123456789012:role/rotator", args.GetString(root, args.FlagStringRoleARN))
+	assert.Equal(t, "partner-42", args.GetString(root, args.FlagStringExternalID))
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/me", args.GetString(root, args.FlagStringMFASerial))
+	assert.Equal(t, "123456", args.GetString(root, args.FlagStringMFAToken))
+	assert.Equal(t, "http://localhost:4566", args.GetString(root, args.FlagStringEndpointURL))
+}
+
+func TestRotateCommandParameterFlags(t *testing.T) {
+	cmd, err := args.MountRotateCommand(mockRotateKeysRunFunc)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cmd.ParseFlags([]string{
+		"--kms-key-id", "alias/keys",
+		"--tag", "owner=payments",
+		"--tag", "cost-center=42",
+		"--tier", "Advanced",
+		"--expire-after", "90d",
+	}))
+
+	assert.Equal(t, "alias/keys", args.GetString(cmd, args.FlagStringKMSKeyID))
+	assert.Equal(t, []string{"owner=payments", "cost-center=42"}, args.GetStringArray(cmd, args.FlagStringTag))
+	assert.Equal(t, "Advanced", args.GetString(cmd, args.FlagStringTier))
+	assert.Equal(t, "90d", args.GetString(cmd, args.FlagStringExpireAfter))
+
+	assert.Nil(t, args.GetStringArray(&cobra.Command{}, args.FlagStringTag))
+}
+
+func TestGetNameWithPathPrefix(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	args.AttachProfileFlags(root)
+
+	cmd, err := args.MountFetchCommand(mockFetchRunFunc)
+	assert.NoError(t, err)
+	root.AddCommand(cmd)
+
+	assert.Equal(t, "", args.GetName(cmd))
+
+	assert.NoError(t, root.PersistentFlags().Set(args.FlagStringPathPrefix, "prod/"))
+	assert.Equal(t, "", args.GetName(cmd), "a prefix alone is not a name")
+
+	assert.NoError(t, cmd.Flags().Set(args.FlagStringName, "payments"))
+	assert.Equal(t, "prod/payments", args.GetName(cmd))
+}
+
+func TestResolveNameWithTemplate(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	args.AttachProfileFlags(root)
+	args.AttachNamingFlags(root)
+
+	cmd, err := args.MountFetchCommand(mockFetchRunFunc)
+	assert.NoError(t, err)
+	root.AddCommand(cmd)
+
+	assert.NoError(t, root.ParseFlags([]string{"--name-template", "/{env}/{service}/{purpose}", "--env", "prod"}))
+	assert.NoError(t, cmd.Flags().Set(args.FlagStringName, "signing"))
+
+	_, err = args.ResolveName(cmd)
+	assert.ErrorContains(t, err, "needs a value for {service}")
+	assert.Equal(t, "", args.GetName(cmd))
+
+	assert.NoError(t, root.PersistentFlags().Set(args.FlagStringService, "payments"))
+
+	name, err := args.ResolveName(cmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "/prod/payments/signing", name)
+
+	assert.NoError(t, root.PersistentFlags().Set(args.FlagStringPathPrefix, "team/"))
+
+	_, err = args.ResolveName(cmd)
+	assert.ErrorContains(t, err, "cannot be combined")
+}
+
+func TestCommandReturnsRunError(t *testing.T) {
+	runErr := errors.New("rotation failed")
+
+	cmd, err := args.MountRotateCommand(func(_ *cobra.Command, _ []string) error {
+		return runErr
+	})
+	assert.NoError(t, err)
+
+	cmd.SetArgs([]string{"--name", "testKey"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	assert.ErrorIs(t, cmd.Execute(), runErr)
+}
+
+func TestKeyOutputFlags(t *testing.T) {
+	cmd, err := args.MountRotateCommand(mockRotateKeysRunFunc)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cmd.ParseFlags([]string{
+		"--out-dir", "/keys", "--private-out", "-", "--force", "--no-write",
+	}))
+
+	assert.Equal(t, "/keys", args.GetString(cmd, args.FlagStringOutDir))
+	assert.Equal(t, "-", args.GetString(cmd, args.FlagStringPrivateOut))
+	assert.Equal(t, "", args.GetString(cmd, args.FlagStringPublicOut))
+	assert.True(t, args.GetBool(cmd, args.FlagStringForce))
+	assert.True(t, args.GetBool(cmd, args.FlagStringNoWrite))
+
+	fetchCmd, err := args.MountFetchCommand(mockFetchRunFunc)
+	assert.NoError(t, err)
+	assert.NotNil(t, fetchCmd.Flag(args.FlagStringOutDir))
+	assert.Nil(t, fetchCmd.Flag(args.FlagStringNoWrite))
+}
+
+func TestMountExecCommand(t *testing.T) {
+	var received []string
+
+	cmd, err := args.MountExecCommand(func(_ *cobra.Command, command []string) error {
+		received = command
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	cmd.SetArgs([]string{"--name", "testKey", "--encoding", "base64", "--", "./myservice", "--port", "80"})
+	assert.NoError(t, cmd.Execute())
+
+	assert.Equal(t, []string{"./myservice", "--port", "80"}, received)
+	assert.Equal(t, args.EncodingBase64, args.GetString(cmd, args.FlagStringEncoding))
+	assert.Equal(t, args.DefaultPrivateKeyEnv, args.GetString(cmd, args.FlagStringPrivateKeyEnv))
+
+	cmd, err = args.MountExecCommand(mockCommandRunFunc)
+	assert.NoError(t, err)
+
+	cmd.SetArgs([]string{"--name", "testKey"})
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	assert.Error(t, cmd.Execute(), "a command to run is required")
+}
+
+func TestMountRenderCommand(t *testing.T) {
+	cmd, err := args.MountRenderCommand(mockCommandRunFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "render", cmd.Use)
+
+	cmd.SetArgs([]string{"-t", "app.tmpl"})
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	assert.Error(t, cmd.Execute(), "--out is required")
+
+	assert.NoError(t, cmd.ParseFlags([]string{"-t", "app.tmpl", "--out", "app.yaml"}))
+	assert.Equal(t, "app.tmpl", args.GetString(cmd, args.FlagStringTemplate))
+	assert.Equal(t, "app.yaml", args.GetString(cmd, args.FlagStringOut))
+}
+
+func TestFetchCommandWatchFlags(t *testing.T) {
+	cmd, err := args.MountFetchCommand(mockFetchRunFunc)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cmd.ParseFlags([]string{
+		"--watch", "--interval", "30s", "--reload-pid", "1234", "--reload-command", "nginx -s reload",
+	}))
+
+	assert.True(t, args.GetBool(cmd, args.FlagStringWatch))
+	assert.Equal(t, 30*time.Second, args.GetDuration(cmd, args.FlagStringInterval))
+	assert.Equal(t, 1234, args.GetInt(cmd, args.FlagStringReloadPID))
+	assert.Equal(t, args.DefaultReloadSignal, args.GetString(cmd, args.FlagStringReloadSignal))
+	assert.Equal(t, "nginx -s reload", args.GetString(cmd, args.FlagStringReloadCommand))
+}
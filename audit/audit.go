@@ -0,0 +1,220 @@
+// Package audit keeps an append-only, tamper-evident record of key
+// operations.
+//
+// Records are written as JSON lines. Each record carries the hash of the
+// record before it and its own hash, computed over its JSON encoding with
+// the hash field empty. Editing, removing or reordering any record breaks
+// the chain from that point on, which Verify detects.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+)
+
+type Operation string
+
+const (
+	OperationGenerate Operation = "generate"
+	OperationStore    Operation = "store"
+	OperationFetch    Operation = "fetch"
+	OperationRotate   Operation = "rotate"
+	OperationRollback Operation = "rollback"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// lockTTL bounds how long a crashed writer can block the log.
+const lockTTL = 30 * time.Second
+
+type Record struct {
+	Sequence            int64     `json:"seq"`
+	Time                time.Time `json:"time"`
+	Actor               string    `json:"actor"`
+	Operation           Operation `json:"operation"`
+	KeyName             string    `json:"key_name"`
+	Fingerprint         string    `json:"fingerprint,omitempty"`
+	PreviousFingerprint string    `json:"previous_fingerprint,omitempty"`
+	Result              string    `json:"result"`
+	Error               string    `json:"error,omitempty"`
+	PreviousHash        string    `json:"prev_hash"`
+	Hash                string    `json:"hash"`
+}
+
+// NewRecord returns a record of op on keyName with a result derived from err.
+func NewRecord(op Operation, keyName string, err error) Record {
+	record := Record{
+		Operation: op,
+		KeyName:   keyName,
+		Result:    ResultSuccess,
+	}
+
+	if err != nil {
+		record.Result = ResultFailure
+		record.Error = err.Error()
+	}
+
+	return record
+}
+
+// ComputeHash returns the hash of the record with its Hash field cleared.
+func (r Record) ComputeHash() (string, error) {
+	r.Hash = ""
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type Recorder interface {
+	Record(record Record) error
+}
+
+// NoopRecorder discards records. It is used when auditing is disabled.
+type NoopRecorder struct{}
+
+func (NoopRecorder) Record(Record) error {
+	return nil
+}
+
+// FileLog appends records to a local file.
+type FileLog struct {
+	Path string
+}
+
+func NewFileLog(path string) *FileLog {
+	return &FileLog{Path: path}
+}
+
+// Record chains record onto the end of the log. Sequence, Time, PreviousHash
+// and Hash are filled in here. Concurrent writers are serialised with a lock
+// file next to the log.
+func (l *FileLog) Record(record Record) error {
+	locker := lock.NewFileLocker(filepath.Dir(l.Path), lockTTL)
+
+	held, err := lock.AcquireWait(locker, filepath.Base(l.Path), lockTTL)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = held.Release() }()
+
+	last, _, err := readChain(l.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	record.Sequence = last.Sequence + 1
+	record.PreviousHash = last.Hash
+	record.Time = time.Now().UTC()
+
+	if record.Hash, err = record.ComputeHash(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+// ChainError reports where a log's hash chain is broken.
+type ChainError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit log broken at line %d: %s", e.Line, e.Reason)
+}
+
+// Verify checks the hash chain of the log at path and returns the number of
+// records in it. A broken chain is reported as a *ChainError.
+func Verify(path string) (int, error) {
+	_, count, err := readChain(path)
+
+	return count, err
+}
+
+// readChain reads and verifies every record in the log, returning the last
+// record and the record count.
+func readChain(path string) (Record, int, error) {
+	var last Record
+
+	file, err := os.Open(path)
+	if err != nil {
+		return last, 0, err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	count := 0
+
+	for scanner.Scan() {
+		count++
+
+		var record Record
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return last, count, &ChainError{Line: count, Reason: "invalid JSON: " + err.Error()}
+		}
+
+		if record.PreviousHash != last.Hash {
+			return last, count, &ChainError{Line: count, Reason: "previous hash does not match the preceding record"}
+		}
+
+		if record.Sequence != last.Sequence+1 {
+			return last, count, &ChainError{Line: count, Reason: "sequence number out of order"}
+		}
+
+		hash, err := record.ComputeHash()
+		if err != nil {
+			return last, count, err
+		}
+
+		if hash != record.Hash {
+			return last, count, &ChainError{Line: count, Reason: "record hash does not match its contents"}
+		}
+
+		last = record
+	}
+
+	return last, count, scanner.Err()
+}
@@ -0,0 +1,99 @@
+package audit_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+)
+
+func writeRecords(t *testing.T, path string, n int) {
+	t.Helper()
+
+	log := audit.NewFileLog(path)
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, log.Record(audit.NewRecord(audit.OperationRotate, "my_key", nil)))
+	}
+}
+
+func TestFileLogChainsRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	writeRecords(t, path, 3)
+
+	count, err := audit.Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestNewRecordFailure(t *testing.T) {
+	record := audit.NewRecord(audit.OperationStore, "my_key", errors.New("boom"))
+
+	assert.Equal(t, audit.ResultFailure, record.Result)
+	assert.Equal(t, "boom", record.Error)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		line   int
+	}{
+		{
+			name: "edited record",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"my_key"`, `"other_key"`, 1)
+
+				return lines
+			},
+			line: 2,
+		},
+		{
+			name: "removed record",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+			line: 2,
+		},
+		{
+			name: "reordered records",
+			tamper: func(lines []string) []string {
+				lines[0], lines[1] = lines[1], lines[0]
+
+				return lines
+			},
+			line: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			writeRecords(t, path, 3)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			lines := test.tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+			_, err = audit.Verify(path)
+
+			var chainErr *audit.ChainError
+			require.ErrorAs(t, err, &chainErr)
+			assert.Equal(t, test.line, chainErr.Line)
+		})
+	}
+}
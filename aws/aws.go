@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -8,16 +9,41 @@ import (
 const (
 	PublicKeyNameSuffix  = "_pub.pem"
 	PrivateKeyNameSuffix = "_priv.pem"
+
+	// ProbeNameSuffix names the scratch parameter doctor checks a KMS key
+	// with.
+	ProbeNameSuffix = "_probe"
+)
+
+// Parameter Store naming limits. The length limit counts the parameter's
+// whole ARN, e.g. arn:aws:ssm:us-east-1:123456789012:parameter/name, not
+// just its name.
+const (
+	MaxParameterNameLength = 1011
+	MaxHierarchyDepth      = 15
+
+	// AccountIDLength is the length of the account ID in an ARN.
+	AccountIDLength = 12
+
+	// MaxRegionLength is the length of the longest region name, e.g.
+	// ap-southeast-1. It is counted when the region is not known.
+	MaxRegionLength = 14
 )
 
-const ParameterStoreNamingRequirementsString = `
-Invalid parameter store name: '%s'. 
+// ReservedPrefixes cannot start a parameter name, in any case.
+var ReservedPrefixes = []string{"aws", "ssm"}
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-zA-Z0-9_.\-/]`)
 
-Ensure the name follows AWS Parameter Store naming conventions: 
-it must start with a letter or a number, can only include 
-alphanumeric characters, hyphens (-), underscores (_), and
-forward slashes (/), and must not exceed 2048 characters in length.
-`
+// InvalidNameError lists every reason a parameter name was rejected.
+type InvalidNameError struct {
+	Name    string
+	Reasons []string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid parameter store name '%s': %s", e.Name, strings.Join(e.Reasons, "; "))
+}
 
 func MakePrivateKeyName(keyName string) string {
 	return keyName + PrivateKeyNameSuffix
@@ -27,26 +53,96 @@ func MakePublicKeyName(keyName string) string {
 	return keyName + PublicKeyNameSuffix
 }
 
-// IsValidParameterStoreName checks if a string is a valid AWS Parameter Store name.
-func IsValidParameterStoreName(name string) bool {
-	// AWS Parameter Store names can contain letters, numbers, hyphens, and underscores.
-	// They must start with a letter or number and can't exceed 2048 characters.
-	// We'll use a regular expression to validate the name.
-	// Adjust the regular expression pattern as needed.
+func MakeProbeName(keyName string) string {
+	return keyName + ProbeNameSuffix
+}
 
-	// Regular expression pattern for a valid AWS Parameter Store name.
-	pattern := `^[a-zA-Z0-9][a-zA-Z0-9_\-/]*$`
+// ValidateParameterStoreName returns an *InvalidNameError if Parameter
+// Store in region would reject name. Names may contain letters, numbers,
+// '.', '-' and '_'. A name in a hierarchy is fully qualified: it starts
+// with '/' and separates its levels with '/', at most 15 of them. An empty
+// region counts the longest one towards the name's length.
+func ValidateParameterStoreName(name, region string) error {
+	return validateName(name, "", region)
+}
 
-	// Compile the regular expression.
-	regex := regexp.MustCompile(pattern)
+// ValidateKeyName returns an *InvalidNameError if Parameter Store in region
+// would reject name or either parameter named after it, whose suffixes make
+// them longer.
+func ValidateKeyName(name, region string) error {
+	return validateName(name, PrivateKeyNameSuffix, region)
+}
+
+// ParameterARNLength returns the length of the ARN of the named parameter
+// in region, which Parameter Store limits to MaxParameterNameLength. An
+// empty region counts the longest one.
+func ParameterARNLength(name, region string) int {
+	regionLength := len(region)
+	if region == "" {
+		regionLength = MaxRegionLength
+	}
+
+	// Names without a leading slash are separated from "parameter" by one
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+
+	return len("arn:aws:ssm:") + regionLength + len(":") + AccountIDLength + len(":parameter") + len(name)
+}
+
+// validateName checks name, counting the longest suffix that will be added
+// to it and the rest of its ARN towards its length.
+func validateName(name, suffix, region string) error {
+	if name == "" {
+		return &InvalidNameError{Name: name, Reasons: []string{"the name is empty"}}
+	}
+
+	var reasons []string
+
+	if invalid := invalidNameCharacters.FindAllString(name, -1); len(invalid) > 0 {
+		reasons = append(reasons, fmt.Sprintf("only letters, numbers, '.', '-', '_' and '/' are allowed, not %q",
+			strings.Join(invalid, "")))
+	}
+
+	levels := strings.Split(strings.TrimPrefix(name, "/"), "/")
+
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+		reasons = append(reasons, "a name in a hierarchy must start with '/'")
+	}
+
+	for _, level := range levels {
+		if level == "" {
+			reasons = append(reasons, "levels of the hierarchy cannot be empty")
+
+			break
+		}
+	}
+
+	for _, prefix := range ReservedPrefixes {
+		if strings.HasPrefix(strings.ToLower(levels[0]), prefix) {
+			reasons = append(reasons, fmt.Sprintf("names cannot begin with '%s'", prefix))
+		}
+	}
+
+	if len(levels) > MaxHierarchyDepth {
+		reasons = append(reasons, fmt.Sprintf("the name is %d levels deep. At most %d are allowed",
+			len(levels), MaxHierarchyDepth))
+	}
+
+	if length := ParameterARNLength(name+suffix, region); length > MaxParameterNameLength {
+		reason := fmt.Sprintf("the parameter's ARN is %d characters long", length)
+		if suffix != "" {
+			reason += fmt.Sprintf(" with its '%s' suffix", suffix)
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s. At most %d are allowed", reason, MaxParameterNameLength))
+	}
 
-	// Check if the name matches the pattern.
-	if !regex.MatchString(name) {
-		return false
+	if len(reasons) > 0 {
+		return &InvalidNameError{Name: name, Reasons: reasons}
 	}
 
-	// Check if the name doesn't exceed 2048 characters.
-	return len(name) <= 2048
+	return nil
 }
 
 func GetFilenameFromParameterStorePath(path string) string {
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetFilenameFromParameterStorePath(t *testing.T) {
@@ -37,32 +38,93 @@ func TestGetFilenameFromParameterStorePath(t *testing.T) {
 	}
 }
 
-func TestIsValidParameterStoreName(t *testing.T) {
+// arnPrefix is what Parameter Store in us-east-1 counts towards the length
+// of a name starting with '/'.
+const arnPrefix = "arn:aws:ssm:us-east-1:123456789012:parameter"
+
+func TestParameterARNLength(t *testing.T) {
 	tests := []struct {
 		name     string
-		expected bool
+		region   string
+		expected int
 	}{
-		{"validName", true},
-		{"myapp/secrets/api_key", true},
-		{"123", true},       // Invalid: starts with a number
-		{"", false},         // Invalid: empty name
-		{longName, false},   // Invalid: exceeds 2048 characters
-		{longishName, true}, // Valid: almost 2048 characters
+		{name: "/prod/key", region: "us-east-1", expected: len(arnPrefix + "/prod/key")},
+		{name: "key", region: "us-east-1", expected: len(arnPrefix + "/key")},
+		{name: "/prod/key", region: "ap-southeast-1", expected: len(arnPrefix+"/prod/key") + 5},
+		{name: "/prod/key", expected: len(arnPrefix+"/prod/key") + MaxRegionLength - len("us-east-1")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+" in "+test.region, func(t *testing.T) {
+			assert.Equal(t, test.expected, ParameterARNLength(test.name, test.region))
+		})
+	}
+}
+
+func TestValidateParameterStoreName(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+	}{
+		{name: "validName"},
+		{name: "123"},
+		{name: "/myapp/secrets/api_key"},
+		{name: "/myapp/v1.2/api-key"},
+		{name: "/team/awsKey"},
+		{name: "/" + strings.Repeat("level/", MaxHierarchyDepth-1) + "key"},
+		{name: "/" + strings.Repeat("a", MaxParameterNameLength-len(arnPrefix)-1)},
+		{name: "", reason: "empty"},
+		{name: "myapp/secrets/api_key", reason: "must start with '/'"},
+		{name: "/myapp//api_key", reason: "cannot be empty"},
+		{name: "/myapp/", reason: "cannot be empty"},
+		{name: "/my app/$key", reason: `not " $"`},
+		{name: "awsKey", reason: "cannot begin with 'aws'"},
+		{name: "/SSM/key", reason: "cannot begin with 'ssm'"},
+		{name: "/" + strings.Repeat("level/", MaxHierarchyDepth) + "key", reason: "16 levels deep"},
+		{name: "/" + strings.Repeat("a", MaxParameterNameLength-len(arnPrefix)), reason: "ARN is 1012 characters long"},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			result := IsValidParameterStoreName(test.name)
-			assert.Equal(t, test.expected, result)
+			err := ValidateParameterStoreName(test.name, "us-east-1")
+
+			if test.reason == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var nameErr *InvalidNameError
+			require.ErrorAs(t, err, &nameErr)
+			assert.Equal(t, test.name, nameErr.Name)
+			assert.ErrorContains(t, err, test.reason)
 		})
 	}
 }
 
-// Define a long name to test the 2048-character limit max.
-var longName = "a" + strings.Repeat("b", 2050)
+func TestValidateParameterStoreNameListsEveryReason(t *testing.T) {
+	var nameErr *InvalidNameError
+
+	require.ErrorAs(t, ValidateParameterStoreName("ssm/my key", "us-east-1"), &nameErr)
+	assert.Len(t, nameErr.Reasons, 3)
+}
+
+func TestValidateKeyNameCountsTheSuffix(t *testing.T) {
+	name := "/" + strings.Repeat("a", MaxParameterNameLength-len(arnPrefix)-len(PrivateKeyNameSuffix))
+
+	assert.NoError(t, ValidateParameterStoreName(name, "us-east-1"))
+	assert.ErrorContains(t, ValidateKeyName(name, "us-east-1"), "with its '_priv.pem' suffix")
+	assert.NoError(t, ValidateKeyName(name[:len(name)-1], "us-east-1"))
+}
 
-// Define a long name to test the 2048-character limit.
-var longishName = "a" + strings.Repeat("b", 2045)
+func TestValidateKeyNameCountsTheLongestRegionWhenUnknown(t *testing.T) {
+	// The longest name us-east-1 accepts
+	name := "/" + strings.Repeat("a", MaxParameterNameLength-len(arnPrefix)-len(PrivateKeyNameSuffix)-1)
+
+	assert.NoError(t, ValidateKeyName(name, "us-east-1"))
+	assert.ErrorContains(t, ValidateKeyName(name, ""), "ARN is 1016 characters long")
+	assert.NoError(t, ValidateKeyName(name[:len(name)-5], ""))
+}
 
 func TestMakePrivateKeyName(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// RoleSessionNamePrefix starts the name of every assumed role session, so
+// go-rotate's calls are easy to find in CloudTrail.
+const RoleSessionNamePrefix = "go-rotate-"
+
+// ConfigOptions configures the AWS config every command uses. Empty fields
+// leave the SDK's own defaults in place.
+type ConfigOptions struct {
+	Region  string
+	Profile string
+
+	// RoleARN is assumed with the credentials of Profile. ExternalID and
+	// MFASerial are passed to the AssumeRole call when set. Without
+	// MFAToken, the token code is prompted for on stderr.
+	RoleARN    string
+	ExternalID string
+	MFASerial  string
+	MFAToken   string
+
+	// EndpointURL overrides the endpoint of every service, for example to
+	// use LocalStack.
+	EndpointURL string
+
+	// RetryMode is standard or adaptive. MaxAttempts counts the first
+	// attempt, so 1 disables retries.
+	RetryMode   string
+	MaxAttempts int
+}
+
+// NewConfig loads the AWS config from opts. Shared config files are always
+// read, so profiles can set their own region and role.
+func NewConfig(ctx context.Context, opts ConfigOptions) (awssdk.Config, error) {
+	var loadOptions []func(*config.LoadOptions) error
+
+	if opts.Region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(opts.Region))
+	}
+
+	if opts.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(opts.Profile))
+	}
+
+	if opts.RetryMode != "" {
+		mode, err := awssdk.ParseRetryMode(opts.RetryMode)
+		if err != nil {
+			return awssdk.Config{}, err
+		}
+
+		loadOptions = append(loadOptions, config.WithRetryMode(mode))
+	}
+
+	if opts.MaxAttempts < 0 {
+		return awssdk.Config{}, fmt.Errorf("invalid max attempts %d", opts.MaxAttempts)
+	}
+
+	if opts.MaxAttempts > 0 {
+		loadOptions = append(loadOptions, config.WithRetryMaxAttempts(opts.MaxAttempts))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return awssdk.Config{}, err
+	}
+
+	if opts.EndpointURL != "" {
+		cfg.BaseEndpoint = awssdk.String(opts.EndpointURL)
+	}
+
+	if opts.RoleARN == "" {
+		return cfg, nil
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN,
+		func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = fmt.Sprintf("%s%d", RoleSessionNamePrefix, time.Now().Unix())
+
+			if opts.ExternalID != "" {
+				o.ExternalID = awssdk.String(opts.ExternalID)
+			}
+
+			if opts.MFASerial != "" {
+				o.SerialNumber = awssdk.String(opts.MFASerial)
+				o.TokenProvider = tokenProvider(opts.MFAToken)
+			}
+		})
+
+	cfg.Credentials = awssdk.NewCredentialsCache(provider)
+
+	return cfg, nil
+}
+
+// tokenProvider returns token, or prompts for one on stderr so stdout is
+// left for command results.
+func tokenProvider(token string) func() (string, error) {
+	return func() (string, error) {
+		if token != "" {
+			return token, nil
+		}
+
+		fmt.Fprint(os.Stderr, "MFA token code: ")
+
+		var code string
+		if _, err := fmt.Fscanln(os.Stdin, &code); err != nil {
+			return "", fmt.Errorf("error reading MFA token code: %w", err)
+		}
+
+		return strings.TrimSpace(code), nil
+	}
+}
@@ -0,0 +1,123 @@
+package aws_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+)
+
+const assumeRoleResponse = `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASSUMEDKEY</AccessKeyId>
+      <SecretAccessKey>assumed-secret</SecretAccessKey>
+      <SessionToken>assumed-token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>arn:aws:sts::123456789012:assumed-role/rotator/go-rotate</Arn>
+      <AssumedRoleId>AROA:go-rotate</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+  <ResponseMetadata><RequestId>1</RequestId></ResponseMetadata>
+</AssumeRoleResponse>`
+
+// isolateAWSConfig points the SDK at static credentials and away from the
+// user's shared config files.
+func isolateAWSConfig(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "BASEKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "base-secret")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_RETRY_MODE", "")
+	t.Setenv("AWS_MAX_ATTEMPTS", "")
+}
+
+func TestNewConfigRegionAndEndpoint(t *testing.T) {
+	isolateAWSConfig(t)
+
+	cfg, err := aws.NewConfig(context.Background(), aws.ConfigOptions{
+		Region:      "eu-west-1",
+		EndpointURL: "http://localhost:4566",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, "http://localhost:4566", *cfg.BaseEndpoint)
+
+	value, err := cfg.Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "BASEKEY", value.AccessKeyID)
+}
+
+func TestNewConfigRetries(t *testing.T) {
+	isolateAWSConfig(t)
+
+	cfg, err := aws.NewConfig(context.Background(), aws.ConfigOptions{
+		Region:      "eu-west-1",
+		RetryMode:   "adaptive",
+		MaxAttempts: 5,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "adaptive", string(cfg.RetryMode))
+	assert.Equal(t, 5, cfg.RetryMaxAttempts)
+
+	_, err = aws.NewConfig(context.Background(), aws.ConfigOptions{RetryMode: "eventually"})
+	assert.Error(t, err)
+
+	_, err = aws.NewConfig(context.Background(), aws.ConfigOptions{MaxAttempts: -1})
+	assert.Error(t, err)
+}
+
+func TestNewConfigAssumesRole(t *testing.T) {
+	isolateAWSConfig(t)
+
+	var form url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(assumeRoleResponse))
+	}))
+	defer server.Close()
+
+	cfg, err := aws.NewConfig(context.Background(), aws.ConfigOptions{
+		Region:      "us-east-1",
+		RoleARN:     "arn:aws:iam::123456789012:role/rotator",
+		ExternalID:  "partner-42",
+		MFASerial:   "arn:aws:iam::111111111111:mfa/me",
+		MFAToken:    "123456",
+		EndpointURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	value, err := cfg.Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ASSUMEDKEY", value.AccessKeyID)
+
+	assert.Equal(t, "AssumeRole", form.Get("Action"))
+	assert.Equal(t, "arn:aws:iam::123456789012:role/rotator", form.Get("RoleArn"))
+	assert.Equal(t, "partner-42", form.Get("ExternalId"))
+	assert.Equal(t, "arn:aws:iam::111111111111:mfa/me", form.Get("SerialNumber"))
+	assert.Equal(t, "123456", form.Get("TokenCode"))
+	assert.True(t, strings.HasPrefix(form.Get("RoleSessionName"), aws.RoleSessionNamePrefix))
+}
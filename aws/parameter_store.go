@@ -0,0 +1,302 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// SSMAPI is the subset of the SSM client the ParameterStore uses.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput,
+		optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput,
+		optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+	DeleteParameter(ctx context.Context, params *ssm.DeleteParameterInput,
+		optFns ...func(*ssm.Options)) (*ssm.DeleteParameterOutput, error)
+	DescribeParameters(ctx context.Context, params *ssm.DescribeParametersInput,
+		optFns ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error)
+	AddTagsToResource(ctx context.Context, params *ssm.AddTagsToResourceInput,
+		optFns ...func(*ssm.Options)) (*ssm.AddTagsToResourceOutput, error)
+}
+
+// ParameterStore is the CLI's AWS Systems Manager Parameter Store client. It
+// satisfies types.ParameterStoreInterface, and therefore the go-key-rotator
+// ParameterStoreInterface as well.
+//
+// Those interfaces take no context, so every operation runs under the
+// context the store was created with, bounded by Timeout.
+type ParameterStore struct {
+	SSM SSMAPI
+
+	// Timeout bounds each operation. Zero means no limit beyond the
+	// store's context.
+	Timeout time.Duration
+
+	ctx context.Context
+}
+
+// NewParameterStore creates a ParameterStore from the given AWS config.
+// Operations are cancelled with ctx.
+func NewParameterStore(ctx context.Context, cfg awssdk.Config, timeout time.Duration) *ParameterStore {
+	return &ParameterStore{
+		SSM:     ssm.NewFromConfig(cfg),
+		Timeout: timeout,
+		ctx:     ctx,
+	}
+}
+
+// call runs operation with the store's context and timeout, and translates
+// the error it returns.
+func (p *ParameterStore) call(name string, operation func(ctx context.Context) error) error {
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	err := operation(ctx)
+
+	if p.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return types.Errorf(types.ErrorKindBackendUnavailable,
+			"%s: no response from Parameter Store within %s: %w", name, p.Timeout, err)
+	}
+
+	return translateError(name, err)
+}
+
+// GetParameter returns the decrypted value of the named parameter.
+func (p *ParameterStore) GetParameter(name string) (string, error) {
+	var value string
+
+	err := p.call(name, func(ctx context.Context) error {
+		output, err := p.SSM.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           awssdk.String(name),
+			WithDecryption: awssdk.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+
+		value = awssdk.ToString(output.Parameter.Value)
+
+		return nil
+	})
+
+	return value, err
+}
+
+// PutParameter creates or overwrites the named parameter.
+func (p *ParameterStore) PutParameter(name, value, parameterType string) error {
+	return p.call(name, func(ctx context.Context) error {
+		_, err := p.SSM.PutParameter(ctx, &ssm.PutParameterInput{
+			Name:      awssdk.String(name),
+			Value:     awssdk.String(value),
+			Type:      ssmtypes.ParameterType(parameterType),
+			Overwrite: awssdk.Bool(true),
+		})
+
+		return err
+	})
+}
+
+// PutParameterWithOptions creates the named parameter, or overwrites it
+// when options.Overwrite is set, with the given KMS key, tier and policies,
+// then tags it. Parameter Store does not accept tags when overwriting, so
+// they are added separately.
+func (p *ParameterStore) PutParameterWithOptions(name, value string, options types.PutOptions) error {
+	input := &ssm.PutParameterInput{
+		Name:      awssdk.String(name),
+		Value:     awssdk.String(value),
+		Type:      ssmtypes.ParameterType(options.Type),
+		Tier:      ssmtypes.ParameterTier(options.Tier),
+		Overwrite: awssdk.Bool(options.Overwrite),
+	}
+
+	if options.KeyID != "" {
+		input.KeyId = awssdk.String(options.KeyID)
+	}
+
+	if len(options.Policies) > 0 {
+		input.Policies = awssdk.String("[" + strings.Join(options.Policies, ",") + "]")
+	}
+
+	err := p.call(name, func(ctx context.Context) error {
+		_, err := p.SSM.PutParameter(ctx, input)
+
+		return err
+	})
+	if err != nil || len(options.Tags) == 0 {
+		return err
+	}
+
+	keys := make([]string, 0, len(options.Tags))
+	for key := range options.Tags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	tags := make([]ssmtypes.Tag, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, ssmtypes.Tag{Key: awssdk.String(key), Value: awssdk.String(options.Tags[key])})
+	}
+
+	return p.call(name, func(ctx context.Context) error {
+		_, err := p.SSM.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+			ResourceType: ssmtypes.ResourceTypeForTaggingParameter,
+			ResourceId:   awssdk.String(name),
+			Tags:         tags,
+		})
+
+		return err
+	})
+}
+
+// CreateParameter stores a new parameter and fails with
+// types.ErrParameterAlreadyExists if it already exists.
+func (p *ParameterStore) CreateParameter(name, value, parameterType string) error {
+	return p.call(name, func(ctx context.Context) error {
+		_, err := p.SSM.PutParameter(ctx, &ssm.PutParameterInput{
+			Name:      awssdk.String(name),
+			Value:     awssdk.String(value),
+			Type:      ssmtypes.ParameterType(parameterType),
+			Overwrite: awssdk.Bool(false),
+		})
+
+		return err
+	})
+}
+
+// DeleteParameter removes the named parameter and all of its versions.
+func (p *ParameterStore) DeleteParameter(name string) error {
+	return p.call(name, func(ctx context.Context) error {
+		_, err := p.SSM.DeleteParameter(ctx, &ssm.DeleteParameterInput{
+			Name: awssdk.String(name),
+		})
+
+		return err
+	})
+}
+
+// DescribeParameter returns the version and modification date of the named
+// parameter. The value is never decrypted.
+func (p *ParameterStore) DescribeParameter(name string) (*types.ParameterMetadata, error) {
+	var metadata *types.ParameterMetadata
+
+	err := p.call(name, func(ctx context.Context) error {
+		output, err := p.SSM.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           awssdk.String(name),
+			WithDecryption: awssdk.Bool(false),
+		})
+		if err != nil {
+			return err
+		}
+
+		metadata = &types.ParameterMetadata{
+			Name:             awssdk.ToString(output.Parameter.Name),
+			Version:          output.Parameter.Version,
+			LastModifiedDate: awssdk.ToTime(output.Parameter.LastModifiedDate),
+		}
+
+		return nil
+	})
+
+	return metadata, err
+}
+
+// ParameterPolicies returns the policies attached to the named parameter.
+func (p *ParameterStore) ParameterPolicies(name string) ([]types.ParameterPolicy, error) {
+	var parameters []ssmtypes.ParameterMetadata
+
+	err := p.call(name, func(ctx context.Context) error {
+		output, err := p.SSM.DescribeParameters(ctx, &ssm.DescribeParametersInput{
+			ParameterFilters: []ssmtypes.ParameterStringFilter{{
+				Key:    awssdk.String("Name"),
+				Option: awssdk.String("Equals"),
+				Values: []string{name},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+
+		parameters = output.Parameters
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parameters) == 0 {
+		return nil, fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	}
+
+	policies := make([]types.ParameterPolicy, 0, len(parameters[0].Policies))
+
+	for _, policy := range parameters[0].Policies {
+		policies = append(policies, types.ParameterPolicy{
+			Type:   awssdk.ToString(policy.PolicyType),
+			Status: awssdk.ToString(policy.PolicyStatus),
+			Text:   awssdk.ToString(policy.PolicyText),
+		})
+	}
+
+	return policies, nil
+}
+
+// translateError maps SSM error codes onto the errors declared in types.
+func translateError(name string, err error) error {
+	var (
+		apiErr     smithy.APIError
+		sendErr    *smithyhttp.RequestSendError
+		signingErr *v4.SigningError
+	)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &sendErr):
+		return types.Errorf(types.ErrorKindBackendUnavailable, "%s: %w", name, err)
+	case errors.As(err, &signingErr):
+		// Usually no credentials could be found
+		return types.Errorf(types.ErrorKindPermissionDenied, "%s: %w", name, err)
+	case !errors.As(err, &apiErr):
+		return err
+	}
+
+	switch apiErr.ErrorCode() {
+	case "ParameterNotFound":
+		return fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	case "ParameterAlreadyExists":
+		return fmt.Errorf("%s: %w", name, types.ErrParameterAlreadyExists)
+	case "TooManyUpdates":
+		return types.Errorf(types.ErrorKindConflict, "%s: %w", name, err)
+	case "AccessDeniedException", "UnrecognizedClientException", "ExpiredTokenException",
+		"InvalidKeyId":
+		return types.Errorf(types.ErrorKindPermissionDenied, "%s: %w", name, err)
+	case "InternalServerError", "ThrottlingException", "ServiceUnavailable":
+		return types.Errorf(types.ErrorKindBackendUnavailable, "%s: %w", name, err)
+	}
+
+	return err
+}
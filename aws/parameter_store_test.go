@@ -0,0 +1,196 @@
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// failingSSM fails every GetParameter call with the given error.
+type failingSSM struct {
+	aws.SSMAPI
+
+	err error
+}
+
+func (f failingSSM) GetParameter(context.Context, *ssm.GetParameterInput,
+	...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	return nil, f.err
+}
+
+func TestParameterStoreClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected types.ErrorKind
+	}{
+		{name: "not found", err: &ssmtypes.ParameterNotFound{}, expected: types.ErrorKindNotFound},
+		{name: "exists", err: &ssmtypes.ParameterAlreadyExists{}, expected: types.ErrorKindConflict},
+		{name: "too many updates", err: &ssmtypes.TooManyUpdates{}, expected: types.ErrorKindConflict},
+		{name: "access denied", err: &smithy.GenericAPIError{Code: "AccessDeniedException"},
+			expected: types.ErrorKindPermissionDenied},
+		{name: "throttled", err: &smithy.GenericAPIError{Code: "ThrottlingException"},
+			expected: types.ErrorKindBackendUnavailable},
+		{name: "unreachable", err: &smithyhttp.RequestSendError{Err: errors.New("connection refused")},
+			expected: types.ErrorKindBackendUnavailable},
+		{name: "other", err: &smithy.GenericAPIError{Code: "SomethingElse"}, expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &aws.ParameterStore{SSM: failingSSM{err: test.err}}
+
+			_, err := store.GetParameter("my_key_priv.pem")
+			assert.Error(t, err)
+			assert.Equal(t, test.expected, types.KindOf(err))
+		})
+	}
+}
+
+// hangingSSM never answers, returning only once the call's context is done.
+type hangingSSM struct {
+	aws.SSMAPI
+}
+
+func (hangingSSM) GetParameter(ctx context.Context, _ *ssm.GetParameterInput,
+	_ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func TestParameterStoreTimesOut(t *testing.T) {
+	store := &aws.ParameterStore{SSM: hangingSSM{}, Timeout: 10 * time.Millisecond}
+
+	_, err := store.GetParameter("my_key_priv.pem")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "no response from Parameter Store within 10ms")
+	assert.Equal(t, types.ErrorKindBackendUnavailable, types.KindOf(err))
+}
+
+// recordingSSM records the PutParameter and AddTagsToResource calls made
+// against it.
+type recordingSSM struct {
+	aws.SSMAPI
+
+	puts []*ssm.PutParameterInput
+	tags []*ssm.AddTagsToResourceInput
+}
+
+func (r *recordingSSM) PutParameter(_ context.Context, input *ssm.PutParameterInput,
+	_ ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
+	r.puts = append(r.puts, input)
+
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func (r *recordingSSM) AddTagsToResource(_ context.Context, input *ssm.AddTagsToResourceInput,
+	_ ...func(*ssm.Options)) (*ssm.AddTagsToResourceOutput, error) {
+	r.tags = append(r.tags, input)
+
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+func TestPutParameterWithOptions(t *testing.T) {
+	recorder := &recordingSSM{}
+	store := &aws.ParameterStore{SSM: recorder}
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := store.PutParameterWithOptions("my_key_priv.pem", "value", types.PutOptions{
+		Type:      string(ssmtypes.ParameterTypeSecureString),
+		KeyID:     "alias/keys",
+		Tier:      string(ssmtypes.ParameterTierAdvanced),
+		Tags:      map[string]string{"owner": "payments", "cost-center": "42"},
+		Policies:  []string{aws.ExpirationPolicy(expires)},
+		Overwrite: true,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, recorder.puts, 1)
+	put := recorder.puts[0]
+	assert.Equal(t, "alias/keys", *put.KeyId)
+	assert.Equal(t, ssmtypes.ParameterTierAdvanced, put.Tier)
+	assert.True(t, *put.Overwrite)
+	assert.Nil(t, put.Tags, "tags cannot be passed when overwriting")
+	assert.JSONEq(t, `[{"Type":"Expiration","Version":"1.0","Attributes":{"Timestamp":"2030-01-02T03:04:05Z"}}]`,
+		*put.Policies)
+
+	require.Len(t, recorder.tags, 1)
+	assert.Equal(t, "my_key_priv.pem", *recorder.tags[0].ResourceId)
+	assert.Equal(t, ssmtypes.ResourceTypeForTaggingParameter, recorder.tags[0].ResourceType)
+	assert.Equal(t, []ssmtypes.Tag{
+		{Key: awssdk.String("cost-center"), Value: awssdk.String("42")},
+		{Key: awssdk.String("owner"), Value: awssdk.String("payments")},
+	}, recorder.tags[0].Tags)
+}
+
+func TestPutParameterWithoutTags(t *testing.T) {
+	recorder := &recordingSSM{}
+	store := &aws.ParameterStore{SSM: recorder}
+
+	require.NoError(t, store.PutParameterWithOptions("my_key_pub.pem", "value", types.PutOptions{
+		Type: string(ssmtypes.ParameterTypeSecureString),
+		Tier: string(ssmtypes.ParameterTierStandard),
+	}))
+
+	require.Len(t, recorder.puts, 1)
+	assert.Nil(t, recorder.puts[0].KeyId)
+	assert.Nil(t, recorder.puts[0].Policies)
+	assert.False(t, *recorder.puts[0].Overwrite, "creates the parameter unless asked to overwrite")
+	assert.Empty(t, recorder.tags)
+}
+
+// describingSSM answers DescribeParameters with the given parameters.
+type describingSSM struct {
+	aws.SSMAPI
+
+	parameters []ssmtypes.ParameterMetadata
+	input      *ssm.DescribeParametersInput
+}
+
+func (d *describingSSM) DescribeParameters(_ context.Context, input *ssm.DescribeParametersInput,
+	_ ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error) {
+	d.input = input
+
+	return &ssm.DescribeParametersOutput{Parameters: d.parameters}, nil
+}
+
+func TestParameterPolicies(t *testing.T) {
+	expiration := aws.ExpirationPolicy(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	describer := &describingSSM{parameters: []ssmtypes.ParameterMetadata{{
+		Name: awssdk.String("my_key_priv.pem"),
+		Policies: []ssmtypes.ParameterInlinePolicy{{
+			PolicyType:   awssdk.String(aws.PolicyTypeExpiration),
+			PolicyStatus: awssdk.String("Pending"),
+			PolicyText:   awssdk.String(expiration),
+		}},
+	}}}
+
+	store := &aws.ParameterStore{SSM: describer}
+
+	policies, err := store.ParameterPolicies("my_key_priv.pem")
+	require.NoError(t, err)
+	assert.Equal(t, []types.ParameterPolicy{{Type: aws.PolicyTypeExpiration, Status: "Pending", Text: expiration}}, policies)
+
+	filter := describer.input.ParameterFilters[0]
+	assert.Equal(t, "Name", *filter.Key)
+	assert.Equal(t, "Equals", *filter.Option)
+	assert.Equal(t, "my_key_priv.pem", filter.Values[0])
+
+	describer.parameters = nil
+
+	_, err = store.ParameterPolicies("my_key_priv.pem")
+	assert.ErrorIs(t, err, types.ErrParameterNotFound)
+}
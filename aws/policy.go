@@ -0,0 +1,171 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Parameter tiers accepted by Parameter Store. Parameter policies need the
+// Advanced tier, which Intelligent-Tiering selects automatically.
+var ParameterTiers = []string{
+	string(ssmtypes.ParameterTierStandard),
+	string(ssmtypes.ParameterTierAdvanced),
+	string(ssmtypes.ParameterTierIntelligentTiering),
+}
+
+const day = 24 * time.Hour
+
+// Limits on parameter tags.
+const (
+	MaxTags           = 50
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
+)
+
+// ParseTier returns the Parameter Store tier matching name, ignoring case.
+func ParseTier(name string) (string, error) {
+	for _, tier := range ParameterTiers {
+		if strings.EqualFold(name, tier) {
+			return tier, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown parameter tier '%s'. Valid tiers: %s", name, strings.Join(ParameterTiers, ", "))
+}
+
+// ParseTags parses tags given as key=value. Values may be empty and may
+// contain '='.
+func ParseTags(pairs []string) (map[string]string, error) {
+	if len(pairs) > MaxTags {
+		return nil, fmt.Errorf("too many tags: %d. At most %d are allowed", len(pairs), MaxTags)
+	}
+
+	tags := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+
+		switch {
+		case !ok || key == "":
+			return nil, fmt.Errorf("invalid tag '%s'. Tags must be key=value", pair)
+		case len(key) > MaxTagKeyLength:
+			return nil, fmt.Errorf("tag key '%s' is longer than %d characters", key, MaxTagKeyLength)
+		case len(value) > MaxTagValueLength:
+			return nil, fmt.Errorf("value of tag '%s' is longer than %d characters", key, MaxTagValueLength)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return nil, fmt.Errorf("tag key '%s' uses the reserved aws: prefix", key)
+		}
+
+		if _, duplicate := tags[key]; duplicate {
+			return nil, fmt.Errorf("tag '%s' is given more than once", key)
+		}
+
+		tags[key] = value
+	}
+
+	return tags, nil
+}
+
+// Parameter policy types.
+const (
+	PolicyTypeExpiration             = "Expiration"
+	PolicyTypeExpirationNotification = "ExpirationNotification"
+	PolicyTypeNoChangeNotification   = "NoChangeNotification"
+)
+
+const (
+	policyUnitDays  = "Days"
+	policyUnitHours = "Hours"
+)
+
+type parameterPolicy struct {
+	Type       string            `json:"Type"`
+	Version    string            `json:"Version"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// ExpirationPolicy returns a parameter policy that deletes the parameter at
+// the given time.
+func ExpirationPolicy(at time.Time) string {
+	return marshalPolicy(parameterPolicy{
+		Type:       PolicyTypeExpiration,
+		Version:    "1.0",
+		Attributes: map[string]string{"Timestamp": at.UTC().Format(time.RFC3339)},
+	})
+}
+
+// ExpirationNotificationPolicy returns a parameter policy that emits an
+// EventBridge event the given time before the parameter expires. Whole days
+// are given in days; anything else is rounded up to whole hours.
+func ExpirationNotificationPolicy(before time.Duration) string {
+	amount, unit := int64(before/day), policyUnitDays
+
+	if before < day || before%day != 0 {
+		amount, unit = int64((before+time.Hour-1)/time.Hour), policyUnitHours
+	}
+
+	return marshalPolicy(parameterPolicy{
+		Type:    PolicyTypeExpirationNotification,
+		Version: "1.0",
+		Attributes: map[string]string{
+			"Before": strconv.FormatInt(max(amount, 1), 10),
+			"Unit":   unit,
+		},
+	})
+}
+
+// NoChangeNotificationPolicy returns a parameter policy that emits an
+// EventBridge event when the parameter has not changed for the given time.
+// Whole days are given in days; anything else is rounded down to whole
+// hours, so the event is never late.
+func NoChangeNotificationPolicy(after time.Duration) string {
+	amount, unit := int64(after/day), policyUnitDays
+
+	if after < day || after%day != 0 {
+		amount, unit = int64(after/time.Hour), policyUnitHours
+	}
+
+	return marshalPolicy(parameterPolicy{
+		Type:    PolicyTypeNoChangeNotification,
+		Version: "1.0",
+		Attributes: map[string]string{
+			"After": strconv.FormatInt(max(amount, 1), 10),
+			"Unit":  unit,
+		},
+	})
+}
+
+// SummarizePolicy describes a parameter policy in a few words. Policies that
+// cannot be parsed are returned as they are.
+func SummarizePolicy(text string) string {
+	var policy parameterPolicy
+
+	if err := json.Unmarshal([]byte(text), &policy); err != nil {
+		return text
+	}
+
+	attributes := policy.Attributes
+
+	switch policy.Type {
+	case PolicyTypeExpiration:
+		return "expires " + attributes["Timestamp"]
+	case PolicyTypeExpirationNotification:
+		return fmt.Sprintf("notifies %s %s before expiring", attributes["Before"], strings.ToLower(attributes["Unit"]))
+	case PolicyTypeNoChangeNotification:
+		return fmt.Sprintf("notifies after %s %s without a change", attributes["After"], strings.ToLower(attributes["Unit"]))
+	}
+
+	return text
+}
+
+func marshalPolicy(policy parameterPolicy) string {
+	// A struct of strings always marshals
+	data, _ := json.Marshal(policy)
+
+	return string(data)
+}
@@ -0,0 +1,106 @@
+package aws_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+)
+
+func TestParseTier(t *testing.T) {
+	tier, err := aws.ParseTier("advanced")
+	require.NoError(t, err)
+	assert.Equal(t, "Advanced", tier)
+
+	tier, err = aws.ParseTier("Intelligent-Tiering")
+	require.NoError(t, err)
+	assert.Equal(t, "Intelligent-Tiering", tier)
+
+	_, err = aws.ParseTier("premium")
+	assert.ErrorContains(t, err, "unknown parameter tier")
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := aws.ParseTags([]string{"owner=payments", "cost-center=42", "note=a=b", "empty="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"owner":       "payments",
+		"cost-center": "42",
+		"note":        "a=b",
+		"empty":       "",
+	}, tags)
+
+	for _, invalid := range [][]string{
+		{"owner"},
+		{"=value"},
+		{"aws:owner=me"},
+		{"owner=a", "owner=b"},
+		{strings.Repeat("k", aws.MaxTagKeyLength+1) + "=v"},
+		{"k=" + strings.Repeat("v", aws.MaxTagValueLength+1)},
+	} {
+		_, err := aws.ParseTags(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestExpirationPolicy(t *testing.T) {
+	at := time.Date(2030, 6, 1, 12, 0, 0, 0, time.FixedZone("PDT", -7*60*60))
+
+	assert.JSONEq(t, `{"Type":"Expiration","Version":"1.0","Attributes":{"Timestamp":"2030-06-01T19:00:00Z"}}`,
+		aws.ExpirationPolicy(at))
+}
+
+func TestExpirationNotificationPolicy(t *testing.T) {
+	tests := []struct {
+		before   time.Duration
+		expected string
+	}{
+		{before: 7 * 24 * time.Hour, expected: `{"Before":"7","Unit":"Days"}`},
+		{before: 36 * time.Hour, expected: `{"Before":"36","Unit":"Hours"}`},
+		{before: 90 * time.Minute, expected: `{"Before":"2","Unit":"Hours"}`},
+		{before: 0, expected: `{"Before":"1","Unit":"Hours"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.before.String(), func(t *testing.T) {
+			assert.JSONEq(t,
+				`{"Type":"ExpirationNotification","Version":"1.0","Attributes":`+test.expected+`}`,
+				aws.ExpirationNotificationPolicy(test.before))
+		})
+	}
+}
+
+func TestNoChangeNotificationPolicy(t *testing.T) {
+	tests := []struct {
+		after    time.Duration
+		expected string
+	}{
+		{after: 83 * 24 * time.Hour, expected: `{"After":"83","Unit":"Days"}`},
+		{after: 36 * time.Hour, expected: `{"After":"36","Unit":"Hours"}`},
+		{after: 90 * time.Minute, expected: `{"After":"1","Unit":"Hours"}`},
+		{after: 0, expected: `{"After":"1","Unit":"Hours"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.after.String(), func(t *testing.T) {
+			assert.JSONEq(t,
+				`{"Type":"NoChangeNotification","Version":"1.0","Attributes":`+test.expected+`}`,
+				aws.NoChangeNotificationPolicy(test.after))
+		})
+	}
+}
+
+func TestSummarizePolicy(t *testing.T) {
+	at := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "expires 2030-06-01T00:00:00Z", aws.SummarizePolicy(aws.ExpirationPolicy(at)))
+	assert.Equal(t, "notifies 7 days before expiring",
+		aws.SummarizePolicy(aws.ExpirationNotificationPolicy(7*24*time.Hour)))
+	assert.Equal(t, "notifies after 30 days without a change",
+		aws.SummarizePolicy(aws.NoChangeNotificationPolicy(30*24*time.Hour)))
+	assert.Equal(t, "not json", aws.SummarizePolicy("not json"))
+}
@@ -0,0 +1,62 @@
+package cmd_audit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+type AuditCommand struct {
+	app.Command
+}
+
+// VerifyResult reports whether an audit log's hash chain is intact.
+type VerifyResult struct {
+	Path    string `json:"path" yaml:"path"`
+	Records int    `json:"records" yaml:"records"`
+	Intact  bool   `json:"intact" yaml:"intact"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (r VerifyResult) Text() string {
+	if !r.Intact {
+		return fmt.Sprintf("\n🚨 Audit log %s failed verification: %s\n", r.Path, r.Error)
+	}
+
+	return fmt.Sprintf("\n🔏 Audit log %s is intact: %d records verified\n", r.Path, r.Records)
+}
+
+func (app AuditCommand) Verify(cmd *cobra.Command, _ []string) error {
+	path, err := app.AuditLogPath(cmd)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "failed to load config: %w", err)
+	}
+
+	if path == "" {
+		return types.Errorf(types.ErrorKindValidation, "no audit log configured. Pass --%s or set audit.file in the config file",
+			args.FlagStringAuditLog)
+	}
+
+	result := VerifyResult{Path: path, Intact: true}
+
+	result.Records, err = audit.Verify(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return types.Errorf(types.ErrorKindNotFound, "audit log %s does not exist", path)
+	}
+
+	if err != nil {
+		result.Intact = false
+		result.Error = err.Error()
+	}
+
+	app.Print(cmd, result)
+
+	return err
+}
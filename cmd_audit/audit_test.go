@@ -0,0 +1,124 @@
+package cmd_audit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_audit"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// writeLog records two key operations in a new log at path.
+func writeLog(t *testing.T, path string) {
+	t.Helper()
+
+	log := audit.NewFileLog(path)
+	require.NoError(t, log.Record(audit.NewRecord(audit.OperationStore, "/prod/signing", nil)))
+	require.NoError(t, log.Record(audit.NewRecord(audit.OperationRotate, "/prod/signing", nil)))
+}
+
+func TestAuditVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		flag   bool
+		setup  func(t *testing.T, path string)
+
+		kind     types.ErrorKind
+		chain    bool
+		expected cmd_audit.VerifyResult
+	}{
+		{
+			name:     "verifies an intact log",
+			flag:     true,
+			setup:    writeLog,
+			expected: cmd_audit.VerifyResult{Records: 2, Intact: true},
+		},
+		{
+			name:     "reads the log from the config file",
+			config:   "audit:\n  file: %s\n",
+			setup:    writeLog,
+			expected: cmd_audit.VerifyResult{Records: 2, Intact: true},
+		},
+		{
+			name: "reports an edited log",
+			flag: true,
+			setup: func(t *testing.T, path string) {
+				writeLog(t, path)
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+
+				edited := strings.Replace(string(data), `"rotate"`, `"fetch"`, 1)
+				require.NoError(t, os.WriteFile(path, []byte(edited), 0o600))
+			},
+			chain:    true,
+			expected: cmd_audit.VerifyResult{Records: 2},
+		},
+		{
+			name: "reports a missing log",
+			flag: true,
+			kind: types.ErrorKindNotFound,
+		},
+		{
+			name: "requires a log",
+			kind: types.ErrorKindValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			if test.config != "" {
+				env.WriteConfig(t, fmt.Sprintf(test.config, path))
+			}
+
+			if test.setup != nil {
+				test.setup(t, path)
+			}
+
+			flags := []string{"verify", "-o", "json"}
+			if test.flag {
+				flags = append(flags, "--audit-log", path)
+			}
+
+			command := cmd_audit.AuditCommand{Command: env.Command}
+			err := env.Run(args.MountAuditCommand, command.Verify, flags...)
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			}
+
+			var result cmd_audit.VerifyResult
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+
+			test.expected.Path = path
+
+			if test.chain {
+				var chainErr *audit.ChainError
+				require.ErrorAs(t, err, &chainErr)
+				assert.Equal(t, 2, chainErr.Line)
+
+				test.expected.Error = err.Error()
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cmd_config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+type ConfigCommand struct {
+	app.Command
+}
+
+// ShowResult lists the config files read, the profiles they define and the
+// effective value of each setting with where it came from.
+type ShowResult struct {
+	Files    []string     `json:"files" yaml:"files"`
+	Profiles []string     `json:"profiles" yaml:"profiles"`
+	Settings app.Settings `json:"settings" yaml:"settings"`
+}
+
+func (r ShowResult) Text() string {
+	var b strings.Builder
+
+	if len(r.Files) == 0 {
+		b.WriteString("\n📄 No config file found\n")
+	} else {
+		fmt.Fprintf(&b, "\n📄 Config files: %s\n", strings.Join(r.Files, ", "))
+	}
+
+	if len(r.Profiles) > 0 {
+		fmt.Fprintf(&b, "👤 Profiles: %s\n", strings.Join(r.Profiles, ", "))
+	}
+
+	b.WriteString("\n")
+
+	for _, setting := range r.Settings {
+		value := setting.Value
+		if value == "" {
+			value = "-"
+		}
+
+		fmt.Fprintf(&b, "   %-13s %-24s (%s)\n", setting.Name, value, setting.Source)
+	}
+
+	return b.String()
+}
+
+func (app ConfigCommand) Show(cmd *cobra.Command, _ []string) error {
+	cfg, err := app.Config(cmd)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "failed to load config: %w", err)
+	}
+
+	settings, err := app.Settings(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	app.Print(cmd, ShowResult{
+		Files:    cfg.Files,
+		Profiles: cfg.ProfileNames(),
+		Settings: settings,
+	})
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd_config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_config"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const profileConfig = `
+profile: staging
+profiles:
+  staging:
+    region: us-west-2
+    key_size: 4096
+  prod:
+    region: us-east-1
+`
+
+func TestConfigShow(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		flags  []string
+
+		kind     types.ErrorKind
+		profiles []string
+		expected []app.Setting
+	}{
+		{
+			name:     "shows the default profile",
+			config:   profileConfig,
+			profiles: []string{"prod", "staging"},
+			expected: []app.Setting{
+				{Name: app.SettingProfile, Value: "staging", Source: app.SourceConfig},
+				{Name: app.SettingRegion, Value: "us-west-2", Source: app.SourceProfile},
+				{Name: app.SettingKeySize, Value: "4096", Source: app.SourceProfile},
+			},
+		},
+		{
+			name:     "shows the selected profile",
+			config:   profileConfig,
+			flags:    []string{"--config-profile", "prod"},
+			profiles: []string{"prod", "staging"},
+			expected: []app.Setting{
+				{Name: app.SettingProfile, Value: "prod", Source: app.SourceFlag},
+				{Name: app.SettingRegion, Value: "us-east-1", Source: app.SourceProfile},
+				{Name: app.SettingKeySize, Value: "2048", Source: app.SourceDefault},
+			},
+		},
+		{
+			name:     "prefers flags to the profile",
+			config:   profileConfig,
+			flags:    []string{"--region", "eu-west-1"},
+			profiles: []string{"prod", "staging"},
+			expected: []app.Setting{
+				{Name: app.SettingRegion, Value: "eu-west-1", Source: app.SourceFlag},
+			},
+		},
+		{
+			name:     "shows the defaults without profiles",
+			profiles: []string{},
+			expected: []app.Setting{
+				{Name: app.SettingProfile, Source: app.SourceDefault},
+				{Name: app.SettingBackend, Value: "ssm", Source: app.SourceDefault},
+			},
+		},
+		{
+			name:   "rejects an unknown profile",
+			config: profileConfig,
+			flags:  []string{"--config-profile", "dev"},
+			kind:   types.ErrorKindValidation,
+		},
+		{
+			name:   "rejects an invalid config file",
+			config: "profiles: [",
+			kind:   types.ErrorKindValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, variable := range []string{app.EnvProfile, app.EnvRegion, app.EnvKeySize, app.EnvBackend} {
+				t.Setenv(variable, "")
+			}
+
+			env := apptest.New(t)
+			env.WriteConfig(t, test.config)
+
+			command := cmd_config.ConfigCommand{Command: env.Command}
+			err := env.Run(args.MountConfigCommand, command.Show, append([]string{"show", "-o", "json"}, test.flags...)...)
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var result cmd_config.ShowResult
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+			assert.Len(t, result.Files, 1)
+			assert.Equal(t, test.profiles, result.Profiles)
+
+			for _, setting := range test.expected {
+				assert.Contains(t, result.Settings, setting)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package cmd_daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/scheduler"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type DaemonCommand struct {
+	app.Command
+}
+
+func (app DaemonCommand) Run(cmd *cobra.Command, _ []string) error {
+	cfg, err := app.Config(cmd)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "failed to load config: %w", err)
+	}
+
+	if len(cfg.Files) == 0 {
+		return types.Errorf(types.ErrorKindValidation, "the daemon requires a config file. Pass --%s", args.FlagStringConfig)
+	}
+
+	jobs, err := Jobs(cfg, app.AWSConfig.Region)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "invalid schedule: %w", err)
+	}
+
+	// Refuse options the store cannot apply now, not at the first rotation
+	for _, job := range jobs {
+		if _, err := app.options(cmd, cfg, job); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(app.Context(cmd), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	sched := scheduler.NewScheduler(app.KeyRotator, app.Clock, jobs)
+	sched.Rotate = func(job scheduler.Job) error {
+		return app.rotate(cmd, cfg, job)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", sched)
+
+	server := &http.Server{
+		Addr:              healthAddr(cmd, cfg),
+		Handler:           mux,
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Logf("Health endpoint stopped: %s", err).Error()
+		}
+	}()
+
+	klog.Logf("Scheduling %d keys. Health endpoint on %s/healthz", len(jobs), server.Addr).Info()
+
+	runErr := sched.Run(ctx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		klog.Logf("Error stopping health endpoint: %s", err).Error()
+	}
+
+	klog.Logf("Daemon stopped.").Info()
+
+	if runErr != nil {
+		return fmt.Errorf("scheduler stopped: %w", runErr)
+	}
+
+	return nil
+}
+
+// rotate rotates a scheduled key the way store does, running the same
+// hooks, rollbacks, audit records and webhook events, and storing both
+// halves with the same options.
+func (app DaemonCommand) rotate(cmd *cobra.Command, cfg *config.Config, job scheduler.Job) error {
+	request, err := app.NewRotationRequest(cmd, job.Name, job.KeySize)
+	if err != nil {
+		return err
+	}
+
+	if request.Options, err = app.options(cmd, cfg, job); err != nil {
+		return err
+	}
+
+	_, err = app.RotateKeyPair(cmd, cfg, request)
+
+	return err
+}
+
+// options returns the options the job's key pair is stored with, as chosen
+// by the parameter flags and the key's policy. Expiry is measured from the
+// time of the rotation.
+func (app DaemonCommand) options(cmd *cobra.Command, cfg *config.Config, job scheduler.Job) (types.PutOptions, error) {
+	options, _, err := app.ParameterOptions(cmd, cfg.PolicyFor(job.Name), app.Clock.Now())
+	if err != nil {
+		return options, types.NewError(types.ErrorKindValidation, err)
+	}
+
+	if _, err := app.Rotator(options); err != nil {
+		return options, types.NewError(types.ErrorKindValidation, err)
+	}
+
+	return options, nil
+}
+
+// Jobs builds a scheduler job for every key in the config, whose names must
+// be valid in region. Each key must set exactly one of interval or
+// schedule.
+func Jobs(cfg *config.Config, region string) ([]scheduler.Job, error) {
+	jobs := make([]scheduler.Job, 0, len(cfg.Keys))
+
+	for _, key := range cfg.Keys {
+		if err := aws.ValidateKeyName(key.Name, region); err != nil {
+			return nil, err
+		}
+
+		job := scheduler.Job{Name: key.Name, KeySize: key.Size}
+
+		if job.KeySize == 0 {
+			job.KeySize = args.DefaultKeySize
+		}
+
+		switch {
+		case key.Interval > 0 && key.Schedule != "":
+			return nil, fmt.Errorf("key '%s' sets both interval and schedule", key.Name)
+		case key.Interval > 0:
+			job.Schedule = scheduler.IntervalSchedule(key.Interval)
+		case key.Schedule != "":
+			schedule, err := scheduler.ParseCron(key.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("key '%s': %w", key.Name, err)
+			}
+
+			job.Schedule = schedule
+		default:
+			return nil, fmt.Errorf("key '%s' needs an interval or a schedule", key.Name)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+func healthAddr(cmd *cobra.Command, cfg *config.Config) string {
+	if addr := args.GetHealthAddr(cmd); addr != "" {
+		return addr
+	}
+
+	if cfg.Daemon.HealthAddr != "" {
+		return cfg.Daemon.HealthAddr
+	}
+
+	return args.DefaultHealthAddr
+}
@@ -0,0 +1,284 @@
+package cmd_daemon_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_daemon"
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// stoppingClock fires its first timer at once, moving the time forward,
+// and stops the daemon when it is asked for a second.
+type stoppingClock struct {
+	*apptest.Clock
+
+	stop  context.CancelFunc
+	fired bool
+}
+
+func (c *stoppingClock) After(d time.Duration) <-chan time.Time {
+	if c.fired {
+		c.stop()
+
+		return nil
+	}
+
+	c.fired = true
+	c.Time = c.Time.Add(d)
+
+	return c.Clock.After(d)
+}
+
+// runDaemon runs the daemon until it has rotated every key once.
+func runDaemon(t *testing.T, env *apptest.Env, flags ...string) error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	env.Command.Clock = &stoppingClock{Clock: env.Clock, stop: cancel}
+
+	command := cmd_daemon.DaemonCommand{Command: env.Command}
+
+	return env.RunContext(ctx, args.MountDaemonCommand, command.Run,
+		append([]string{"--health-addr", "127.0.0.1:0"}, flags...)...)
+}
+
+func readAudit(t *testing.T, path string) []audit.Record {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer func() { _ = file.Close() }()
+
+	var records []audit.Record
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record audit.Record
+
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	require.NoError(t, scanner.Err())
+
+	return records
+}
+
+func TestDaemonRotatesLikeStore(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "payments")
+
+	dir := t.TempDir()
+	auditLog := filepath.Join(dir, "audit.log")
+	hookLog := filepath.Join(dir, "hooks.log")
+
+	env.WriteConfig(t, fmt.Sprintf(`
+audit:
+  file: %s
+hooks:
+  pre_rotate:
+    - command: ["sh", "-c", "echo $GO_ROTATE_EVENT $GO_ROTATE_KEY_NAME >> %s"]
+  post_rotate:
+    - command: ["sh", "-c", "echo $GO_ROTATE_EVENT $GO_ROTATE_KEY_NAME >> %s"]
+keys:
+  - name: payments
+    interval: 24h
+`, auditLog, hookLog, hookLog))
+
+	require.NoError(t, runDaemon(t, env))
+	assert.Equal(t, 1, env.Generator.Calls())
+
+	hooksRun, err := os.ReadFile(hookLog)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pre_rotate payments", "post_rotate payments"},
+		strings.Split(strings.TrimSpace(string(hooksRun)), "\n"))
+
+	records := readAudit(t, auditLog)
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.OperationRotate, records[0].Operation)
+	assert.Equal(t, audit.ResultSuccess, records[0].Result)
+	assert.NotEmpty(t, records[0].Fingerprint)
+	assert.NotEmpty(t, records[0].PreviousFingerprint)
+	assert.NotEqual(t, records[0].PreviousFingerprint, records[0].Fingerprint)
+}
+
+func TestDaemonRollsBackWhenAHookAsks(t *testing.T) {
+	env := apptest.New(t)
+	previous := env.StoreKeyPair(t, "payments")
+
+	auditLog := filepath.Join(t.TempDir(), "audit.log")
+
+	env.WriteConfig(t, fmt.Sprintf(`
+audit:
+  file: %s
+hooks:
+  post_rotate:
+    - command: ["false"]
+      rollback_on_failure: true
+keys:
+  - name: payments
+    interval: 24h
+`, auditLog))
+
+	require.NoError(t, runDaemon(t, env))
+
+	stored, err := rotation.ReadPrivateKey(env.Command.ParameterStore, "payments_priv.pem")
+	require.NoError(t, err)
+	assert.True(t, previous.Equal(stored))
+
+	records := readAudit(t, auditLog)
+	require.Len(t, records, 2)
+	assert.Equal(t, audit.ResultFailure, records[0].Result)
+	assert.Equal(t, audit.OperationRollback, records[1].Operation)
+	assert.Equal(t, audit.ResultSuccess, records[1].Result)
+}
+
+func TestDaemonTakesTheRotationLock(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "payments")
+	env.WriteConfig(t, `
+keys:
+  - name: payments
+    interval: 24h
+`)
+
+	dir := t.TempDir()
+
+	held, err := lock.NewFileLocker(dir, time.Minute).Acquire("payments")
+	require.NoError(t, err)
+
+	require.NoError(t, runDaemon(t, env, "--lock", "file", "--lock-dir", dir))
+	assert.Zero(t, env.Generator.Calls(), "rotated a key locked by another rotation")
+
+	require.NoError(t, held.Release())
+}
+
+func TestDaemonRestoresWithParameterOptions(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "payments")
+	env.WriteConfig(t, `
+hooks:
+  post_rotate:
+    - command: ["false"]
+      rollback_on_failure: true
+keys:
+  - name: payments
+    interval: 24h
+`)
+
+	require.NoError(t, runDaemon(t, env, "--kms-key-id", "alias/keys"))
+	assert.Equal(t, 1, env.Generator.Calls())
+
+	// Both the rotation and the rollback that undid it used the KMS key
+	for _, name := range []string{"payments_priv.pem", "payments_pub.pem"} {
+		parameter, ok := env.SSM.Parameter(name)
+		require.True(t, ok)
+		assert.Equal(t, "alias/keys", parameter.KeyID, name)
+		assert.Len(t, parameter.Versions, 3, name)
+	}
+}
+
+func TestDaemonAppliesParameterPolicies(t *testing.T) {
+	maxAge := policy.Policy{MaxAge: 90 * 24 * time.Hour}
+	noChange := aws.NoChangeNotificationPolicy(maxAge.MaxAge - maxAge.EffectiveDueWindow())
+
+	// The daemon rotates once the first interval has passed
+	expiresAt := apptest.Now.Add(24 * time.Hour).Add(30 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		flags    []string
+		policies []string
+	}{
+		{
+			name:     "notifies when a key is not rotated",
+			policies: []string{noChange},
+		},
+		{
+			name:  "expires the key",
+			flags: []string{"--expire-after", "30d"},
+			policies: []string{
+				noChange,
+				aws.ExpirationPolicy(expiresAt),
+				aws.ExpirationNotificationPolicy(maxAge.EffectiveDueWindow()),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			env.StoreKeyPair(t, "payments")
+			env.WriteConfig(t, `
+keys:
+  - name: payments
+    max_age: 90d
+    interval: 24h
+`)
+
+			require.NoError(t, runDaemon(t, env, test.flags...))
+			assert.Equal(t, 1, env.Generator.Calls())
+
+			for _, name := range []string{"payments_priv.pem", "payments_pub.pem"} {
+				parameter, ok := env.SSM.Parameter(name)
+				require.True(t, ok)
+				assert.Equal(t, test.policies, parameter.Policies, name)
+				assert.Equal(t, ssmtypes.ParameterTierAdvanced, parameter.Tier, name)
+			}
+		})
+	}
+}
+
+func TestDaemonExpandsKeyNames(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "/staging/payments")
+	env.WriteConfig(t, `
+keys:
+  - name: payments
+    interval: 24h
+`)
+
+	require.NoError(t, runDaemon(t, env, "--path-prefix", "/staging/"))
+
+	parameter, ok := env.SSM.Parameter("/staging/payments_priv.pem")
+	require.True(t, ok)
+	assert.Len(t, parameter.Versions, 2)
+
+	_, ok = env.SSM.Parameter("payments_priv.pem")
+	assert.False(t, ok)
+}
+
+func TestDaemonRejectsInvalidParameterOptions(t *testing.T) {
+	env := apptest.New(t)
+	env.WriteConfig(t, `
+keys:
+  - name: payments
+    interval: 24h
+`)
+
+	err := runDaemon(t, env, "--tier", "Standard", "--expire-after", "30d")
+	assert.Equal(t, types.ErrorKindValidation, types.KindOf(err))
+	assert.Zero(t, env.Generator.Calls())
+}
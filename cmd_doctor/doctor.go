@@ -0,0 +1,410 @@
+package cmd_doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// ProbeValue is written to parameters that do not exist yet to check that
+// they can be written, and to the scratch parameter named by
+// aws.MakeProbeName to check the KMS key, and removed again.
+const ProbeValue = "go-rotate doctor probe"
+
+type DoctorCommand struct {
+	app.Command
+}
+
+// Outcomes of a Check.
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+var statusIcons = map[string]string{
+	StatusOK:      "✅",
+	StatusWarning: "⚠️",
+	StatusFailed:  "❌",
+	StatusSkipped: "⏭️",
+}
+
+// Check is the outcome of a single doctor check.
+type Check struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+
+	err error
+}
+
+// Result lists the checks run for a key pair.
+type Result struct {
+	Name   string  `json:"name" yaml:"name"`
+	Checks []Check `json:"checks" yaml:"checks"`
+}
+
+func (r Result) Text() string {
+	var text strings.Builder
+
+	fmt.Fprintf(&text, "\n🩺 Checking '%s':\n\n", r.Name)
+
+	failed := 0
+
+	for _, check := range r.Checks {
+		fmt.Fprintf(&text, "   %s %s: %s\n", statusIcons[check.Status], check.Name, check.Detail)
+
+		if check.Status == StatusFailed {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(&text, "\n❌ %d of %d checks failed. 'go-rotate iam-policy --name %s' prints the "+
+			"permissions go-rotate needs.\n", failed, len(r.Checks), r.Name)
+	} else {
+		text.WriteString("\n✅ No problems found.\n")
+	}
+
+	return text.String()
+}
+
+func ok(name, detail string) Check {
+	return Check{Name: name, Status: StatusOK, Detail: detail}
+}
+
+func warning(name, detail string) Check {
+	return Check{Name: name, Status: StatusWarning, Detail: detail}
+}
+
+func skipped(name, detail string) Check {
+	return Check{Name: name, Status: StatusSkipped, Detail: detail}
+}
+
+func failed(name string, err error) Check {
+	return Check{Name: name, Status: StatusFailed, Detail: err.Error(), err: err}
+}
+
+func (app DoctorCommand) Run(cmd *cobra.Command, _ []string) error {
+	name, err := app.Name(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx := app.Context(cmd)
+	result := Result{Name: name}
+
+	credentials := app.checkCredentials(ctx)
+	region := app.checkRegion()
+	result.Checks = append(result.Checks, credentials, region)
+
+	if credentials.Status != StatusOK || region.Status != StatusOK {
+		result.Checks = append(result.Checks, skipped("identity", "needs credentials and a region"),
+			skipped("parameters", "needs credentials and a region"))
+	} else {
+		result.Checks = append(result.Checks, app.checkIdentity(ctx))
+		result.Checks = append(result.Checks, app.checkLockedKeyPair(cmd, name)...)
+	}
+
+	app.Print(cmd, result)
+
+	var errs []error
+
+	for _, check := range result.Checks {
+		if check.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, check.err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkCredentials checks that credentials can be found, and refreshed when
+// they come from an assumed role.
+func (app DoctorCommand) checkCredentials(ctx context.Context) Check {
+	const check = "credentials"
+
+	if app.AWSConfig.Credentials == nil {
+		return failed(check, types.Errorf(types.ErrorKindPermissionDenied,
+			"no AWS credentials found. Set AWS_PROFILE or --profile, or the AWS_ACCESS_KEY_ID and "+
+				"AWS_SECRET_ACCESS_KEY environment variables"))
+	}
+
+	credentials, err := app.AWSConfig.Credentials.Retrieve(ctx)
+	if err != nil {
+		return failed(check, types.Errorf(types.ErrorKindPermissionDenied, "unable to load AWS credentials: %w", err))
+	}
+
+	if credentials.Source == "" {
+		return ok(check, "found")
+	}
+
+	return ok(check, "from "+credentials.Source)
+}
+
+func (app DoctorCommand) checkRegion() Check {
+	const check = "region"
+
+	if app.AWSConfig.Region == "" {
+		return failed(check, types.Errorf(types.ErrorKindValidation,
+			"no AWS region set. Set --%s, AWS_REGION or a region in your AWS profile", args.FlagStringRegion))
+	}
+
+	return ok(check, app.AWSConfig.Region)
+}
+
+func (app DoctorCommand) checkIdentity(ctx context.Context) Check {
+	const check = "identity"
+
+	caller, err := app.Identity.Caller(ctx)
+	if err != nil {
+		return failed(check, types.Errorf(types.ErrorKindPermissionDenied,
+			"unable to look up the caller identity with STS: %w", err))
+	}
+
+	return ok(check, fmt.Sprintf("%s (account %s)", caller.ARN, caller.Account))
+}
+
+// checkLockedKeyPair checks the key pair while holding the lock chosen by
+// --lock, so probes never race a rotation of the key.
+func (app DoctorCommand) checkLockedKeyPair(cmd *cobra.Command, name string) []Check {
+	const check = "lock"
+
+	request, err := app.NewRotationRequest(cmd, name, 0)
+	if err != nil {
+		return []Check{failed(check, err), skipped("parameters", "needs the lock")}
+	}
+
+	held, err := lock.AcquireWait(request.Locker, name, request.LockWait)
+	if errors.Is(err, lock.ErrLocked) {
+		err = types.Errorf(types.ErrorKindConflict, "'%s' is being rotated: %w", name, err)
+	}
+
+	if err != nil {
+		return []Check{failed(check, err), skipped("parameters", "needs the lock")}
+	}
+
+	stopRenewing := lock.KeepAlive(held, request.LockTTL)
+
+	defer func() {
+		if err := stopRenewing(); err != nil {
+			klog.Logf("Error renewing lock on '%s': %s\n", name, err).Warn()
+		}
+
+		if err := held.Release(); err != nil {
+			klog.Logf("Error releasing lock on '%s': %s\n", name, err).Warn()
+		}
+	}()
+
+	checks := app.checkKeyPair(name, args.GetString(cmd, args.FlagStringKMSKeyID))
+
+	if _, unlocked := request.Locker.(lock.NoopLocker); unlocked {
+		return checks
+	}
+
+	return append([]Check{ok(check, "held while checking the parameters")}, checks...)
+}
+
+// checkKeyPair checks both halves of the key pair, and the KMS key unless
+// probing a half already encrypted with it.
+func (app DoctorCommand) checkKeyPair(name, keyID string) []Check {
+	var (
+		checks    []Check
+		encrypted bool
+	)
+
+	for _, parameter := range []string{aws.MakePrivateKeyName(name), aws.MakePublicKeyName(name)} {
+		parameterChecks, probed := app.checkParameter(parameter, keyID)
+
+		checks = append(checks, parameterChecks...)
+		encrypted = encrypted || probed
+	}
+
+	if keyID != "" && !encrypted {
+		checks = append(checks, app.checkKMSKey(name, keyID))
+	}
+
+	return checks
+}
+
+// checkParameter checks that parameter can be read, decrypted, written and
+// deleted without changing a stored key. A parameter that exists is
+// written with an operation that fails because it exists, and is not
+// deleted. A parameter that does not exist is created with a probe value,
+// encrypted with keyID, read back and deleted. It reports whether a probe
+// was written.
+func (app DoctorCommand) checkParameter(parameter, keyID string) ([]Check, bool) {
+	var (
+		read    = "read " + parameter
+		decrypt = "decrypt " + parameter
+		write   = "write " + parameter
+		remove  = "delete " + parameter
+	)
+
+	metadata, err := app.ParameterStore.DescribeParameter(parameter)
+
+	switch {
+	case errors.Is(err, types.ErrParameterNotFound):
+		return app.probeParameter(parameter, keyID, warning(read, "does not exist yet"))
+	case err != nil:
+		return []Check{
+			failed(read, err),
+			skipped(decrypt, "needs read access"),
+			skipped(write, "needs read access"),
+			skipped(remove, "needs read access"),
+		}, false
+	}
+
+	checks := []Check{ok(read, fmt.Sprintf("version %d", metadata.Version))}
+
+	if _, err := app.ParameterStore.GetParameter(parameter); err != nil {
+		checks = append(checks, failed(decrypt, err))
+	} else {
+		checks = append(checks, ok(decrypt, "decrypted"))
+	}
+
+	// Parameter Store checks permissions before it finds the parameter exists
+	switch err := app.ParameterStore.CreateParameter(parameter, ProbeValue, rotation.ParameterTypeSecureString); {
+	case errors.Is(err, types.ErrParameterAlreadyExists):
+		checks = append(checks, ok(write, existingWrite))
+	case err != nil:
+		checks = append(checks, failed(write, err))
+	default:
+		// Deleted since it was read, so the probe was stored in its place
+		return app.removeProbe(parameter, append(checks, ok(write, "wrote a probe value"))), false
+	}
+
+	return append(checks, skipped(remove, existingDelete)), false
+}
+
+// Details of the checks on a parameter that exists.
+const (
+	existingWrite  = "allowed, checked without overwriting the key"
+	existingDelete = "not checked: deleting would remove the key"
+)
+
+// probeParameter creates a probe value in parameter, which did not exist
+// when it was read, reads it back and deletes it.
+func (app DoctorCommand) probeParameter(parameter, keyID string, read Check) ([]Check, bool) {
+	var (
+		decrypt = "decrypt " + parameter
+		write   = "write " + parameter
+		remove  = "delete " + parameter
+	)
+
+	checks := []Check{read}
+
+	var err error
+
+	// The probe is only ever created, so a key stored since the parameter
+	// was read is left alone
+	options, supported := app.ParameterStore.(types.ParameterOptionsStore)
+	if keyID != "" && supported {
+		err = options.PutParameterWithOptions(parameter, ProbeValue, types.PutOptions{
+			Type:  rotation.ParameterTypeSecureString,
+			KeyID: keyID,
+		})
+	} else {
+		err = app.ParameterStore.CreateParameter(parameter, ProbeValue, rotation.ParameterTypeSecureString)
+	}
+
+	if errors.Is(err, types.ErrParameterAlreadyExists) {
+		return append(checks, ok(write, existingWrite), skipped(decrypt, "not checked: stored since it was read"),
+			skipped(remove, existingDelete)), false
+	}
+
+	if err != nil {
+		return append(checks,
+			failed(write, err),
+			skipped(decrypt, "needs write access"),
+			skipped(remove, "needs write access"),
+		), false
+	}
+
+	detail := "wrote a probe value"
+	if keyID != "" && supported {
+		detail += " encrypted with " + keyID
+	}
+
+	checks = append(checks, ok(write, detail))
+
+	if value, err := app.ParameterStore.GetParameter(parameter); err != nil {
+		checks = append(checks, failed(decrypt, err))
+	} else if value != ProbeValue {
+		checks = append(checks, failed(decrypt, fmt.Errorf("read back '%s' instead of the probe value", value)))
+	} else {
+		checks = append(checks, ok(decrypt, "decrypted the probe value"))
+	}
+
+	return app.removeProbe(parameter, checks), keyID != "" && supported
+}
+
+// checkKMSKey checks that keyID can encrypt and decrypt parameters, by
+// storing a probe value encrypted with it in the key's scratch parameter,
+// reading it back and deleting it. The key pair itself is never written.
+func (app DoctorCommand) checkKMSKey(name, keyID string) Check {
+	check := "kms key " + keyID
+	parameter := aws.MakeProbeName(name)
+
+	options, supported := app.ParameterStore.(types.ParameterOptionsStore)
+	if !supported {
+		return skipped(check, "not checked: the parameter store does not take a KMS key")
+	}
+
+	// A probe left behind by an interrupted run is overwritten
+	err := options.PutParameterWithOptions(parameter, ProbeValue, types.PutOptions{
+		Type:      rotation.ParameterTypeSecureString,
+		KeyID:     keyID,
+		Overwrite: true,
+	})
+	if err != nil {
+		return failed(check, fmt.Errorf("unable to write a probe value to '%s': %w", parameter, err))
+	}
+
+	value, err := app.ParameterStore.GetParameter(parameter)
+	deleteErr := app.ParameterStore.DeleteParameter(parameter)
+
+	switch {
+	case err != nil:
+		return failed(check, fmt.Errorf("unable to read back the probe value from '%s': %w", parameter, err))
+	case value != ProbeValue:
+		return failed(check, fmt.Errorf("read back '%s' from '%s' instead of the probe value", value, parameter))
+	case deleteErr != nil:
+		return failed(check, fmt.Errorf("the probe value is still stored in '%s': %w", parameter, deleteErr))
+	}
+
+	return ok(check, "encrypted and decrypted a probe value in "+parameter)
+}
+
+// removeProbe deletes the probe value written to parameter, unless the
+// parameter no longer holds it.
+func (app DoctorCommand) removeProbe(parameter string, checks []Check) []Check {
+	remove := "delete " + parameter
+
+	value, err := app.ParameterStore.GetParameter(parameter)
+	if err != nil {
+		return append(checks, failed(remove, fmt.Errorf("not deleted: unable to read back the probe value: %w", err)))
+	}
+
+	if value != ProbeValue {
+		return append(checks, skipped(remove, "not deleted: the probe value was replaced"))
+	}
+
+	if err := app.ParameterStore.DeleteParameter(parameter); err != nil {
+		return append(checks, failed(remove, fmt.Errorf("the probe value is still stored: %w", err)))
+	}
+
+	return append(checks, ok(remove, "removed the probe value"))
+}
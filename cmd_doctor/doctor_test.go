@@ -0,0 +1,242 @@
+package cmd_doctor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_doctor"
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+	"github.com/kmesiab/go-key-rotator-cli/ssmfake"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const (
+	privateKey = "/prod/signing_priv.pem"
+	publicKey  = "/prod/signing_pub.pem"
+)
+
+func TestDoctor(t *testing.T) {
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDeniedException"}
+
+	tests := []struct {
+		name  string
+		flags []string
+		setup func(t *testing.T, env *apptest.Env)
+
+		kind     types.ErrorKind
+		expected map[string]string
+	}{
+		{
+			name:  "checks an existing key pair without changing it",
+			flags: []string{"--kms-key-id", "alias/keys"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			expected: map[string]string{
+				"credentials":           cmd_doctor.StatusOK,
+				"region":                cmd_doctor.StatusOK,
+				"identity":              cmd_doctor.StatusOK,
+				"read " + privateKey:    cmd_doctor.StatusOK,
+				"decrypt " + privateKey: cmd_doctor.StatusOK,
+				"write " + privateKey:   cmd_doctor.StatusOK,
+				"delete " + privateKey:  cmd_doctor.StatusSkipped,
+				"read " + publicKey:     cmd_doctor.StatusOK,
+				"decrypt " + publicKey:  cmd_doctor.StatusOK,
+				"write " + publicKey:    cmd_doctor.StatusOK,
+				"delete " + publicKey:   cmd_doctor.StatusSkipped,
+				"kms key alias/keys":    cmd_doctor.StatusOK,
+			},
+		},
+		{
+			name:  "reports a KMS key that cannot encrypt",
+			flags: []string{"--kms-key-id", "alias/keys"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.SSM.Inject(ssmfake.Fault{
+					Operation: ssmfake.OperationPutParameter,
+					Name:      aws.MakeProbeName("/prod/signing"),
+					Err:       accessDenied,
+				})
+			},
+			kind: types.ErrorKindPermissionDenied,
+			expected: map[string]string{
+				"write " + privateKey: cmd_doctor.StatusOK,
+				"kms key alias/keys":  cmd_doctor.StatusFailed,
+			},
+		},
+		{
+			name:  "probes a new key pair",
+			flags: []string{"--kms-key-id", "alias/keys"},
+			expected: map[string]string{
+				"read " + privateKey:    cmd_doctor.StatusWarning,
+				"write " + privateKey:   cmd_doctor.StatusOK,
+				"decrypt " + privateKey: cmd_doctor.StatusOK,
+				"delete " + privateKey:  cmd_doctor.StatusOK,
+				"kms key alias/keys":    "",
+			},
+		},
+		{
+			name:  "leaves a key stored during the probe alone",
+			flags: []string{"--kms-key-id", "alias/keys"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				// Stored between reading the parameter and probing it
+				env.SSM.Inject(ssmfake.Fault{
+					Operation: ssmfake.OperationGetParameter,
+					Name:      privateKey,
+					Err:       &ssmtypes.ParameterNotFound{},
+					Times:     1,
+				})
+			},
+			expected: map[string]string{
+				"read " + privateKey:    cmd_doctor.StatusWarning,
+				"write " + privateKey:   cmd_doctor.StatusOK,
+				"decrypt " + privateKey: cmd_doctor.StatusSkipped,
+				"delete " + privateKey:  cmd_doctor.StatusSkipped,
+			},
+		},
+		{
+			name:  "reports a held lock",
+			flags: []string{"--lock", "ssm"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				_, err := lock.NewParameterStoreLocker(env.Command.ParameterStore, time.Hour).Acquire("/prod/signing")
+				require.NoError(t, err)
+			},
+			kind: types.ErrorKindConflict,
+			expected: map[string]string{
+				"lock":       cmd_doctor.StatusFailed,
+				"parameters": cmd_doctor.StatusSkipped,
+			},
+		},
+		{
+			name: "reports denied reads",
+			setup: func(_ *testing.T, env *apptest.Env) {
+				env.SSM.Inject(ssmfake.Fault{Operation: ssmfake.OperationGetParameter, Err: accessDenied})
+			},
+			kind: types.ErrorKindPermissionDenied,
+			expected: map[string]string{
+				"read " + privateKey:  cmd_doctor.StatusFailed,
+				"write " + privateKey: cmd_doctor.StatusSkipped,
+			},
+		},
+		{
+			name: "reports denied writes",
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.SSM.Inject(ssmfake.Fault{Operation: ssmfake.OperationPutParameter, Err: accessDenied})
+			},
+			kind: types.ErrorKindPermissionDenied,
+			expected: map[string]string{
+				"read " + privateKey:  cmd_doctor.StatusOK,
+				"write " + privateKey: cmd_doctor.StatusFailed,
+			},
+		},
+		{
+			name:  "reports a missing region",
+			setup: func(_ *testing.T, env *apptest.Env) { env.Command.AWSConfig.Region = "" },
+			kind:  types.ErrorKindValidation,
+			expected: map[string]string{
+				"region":     cmd_doctor.StatusFailed,
+				"parameters": cmd_doctor.StatusSkipped,
+			},
+		},
+		{
+			name:  "reports missing credentials",
+			setup: func(_ *testing.T, env *apptest.Env) { env.Command.AWSConfig.Credentials = nil },
+			kind:  types.ErrorKindPermissionDenied,
+			expected: map[string]string{
+				"credentials": cmd_doctor.StatusFailed,
+				"identity":    cmd_doctor.StatusSkipped,
+			},
+		},
+		{
+			name:  "reports identity lookup failures",
+			setup: func(_ *testing.T, env *apptest.Env) { env.Identity.Err = errors.New("expired token") },
+			kind:  types.ErrorKindPermissionDenied,
+			expected: map[string]string{
+				"identity":           cmd_doctor.StatusFailed,
+				"read " + privateKey: cmd_doctor.StatusWarning,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			env.Command.AWSConfig = awssdk.Config{
+				Region:      "us-east-1",
+				Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+			}
+
+			if test.setup != nil {
+				test.setup(t, env)
+			}
+
+			stored, _ := env.SSM.Parameter(privateKey)
+
+			command := cmd_doctor.DoctorCommand{Command: env.Command}
+			err := env.Run(args.MountDoctorCommand, command.Run,
+				append(test.flags, "--name", "/prod/signing", "-o", "json")...)
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+			} else {
+				require.NoError(t, err)
+			}
+
+			var result cmd_doctor.Result
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+
+			statuses := make(map[string]string, len(result.Checks))
+			for _, check := range result.Checks {
+				statuses[check.Name] = check.Status
+			}
+
+			for name, status := range test.expected {
+				assert.Equal(t, status, statuses[name], name)
+			}
+
+			// Stored keys are never changed, and probes never left behind
+			after, _ := env.SSM.Parameter(privateKey)
+			assert.Equal(t, stored, after)
+
+			_, probed := env.SSM.Parameter(aws.MakeProbeName("/prod/signing"))
+			assert.False(t, probed)
+		})
+	}
+}
+
+func TestDoctorHoldsTheRotationLock(t *testing.T) {
+	env := apptest.New(t)
+	env.Command.AWSConfig = awssdk.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}
+
+	command := cmd_doctor.DoctorCommand{Command: env.Command}
+	require.NoError(t, env.Run(args.MountDoctorCommand, command.Run,
+		"--name", "/prod/signing", "--lock", "ssm", "-o", "json"))
+
+	var result cmd_doctor.Result
+	require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+	assert.Contains(t, result.Checks, cmd_doctor.Check{
+		Name:   "lock",
+		Status: cmd_doctor.StatusOK,
+		Detail: "held while checking the parameters",
+	})
+
+	// Released once the checks are done
+	held, err := lock.NewParameterStoreLocker(env.Command.ParameterStore, time.Hour).Acquire("/prod/signing")
+	require.NoError(t, err)
+	assert.NoError(t, held.Release())
+}
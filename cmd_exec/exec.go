@@ -0,0 +1,87 @@
+package cmd_exec
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/process"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+type ExecCommand struct {
+	app.Command
+}
+
+func (app ExecCommand) Run(cmd *cobra.Command, command []string) error {
+	name, err := app.Name(cmd)
+	if err != nil {
+		return err
+	}
+
+	encoding := args.GetString(cmd, args.FlagStringEncoding)
+	if encoding != args.EncodingPEM && encoding != args.EncodingBase64 {
+		return types.Errorf(types.ErrorKindValidation, "unknown encoding '%s'; expected %s or %s",
+			encoding, args.EncodingPEM, args.EncodingBase64)
+	}
+
+	privateKeyPEM, err := app.ParameterStore.GetParameter(aws.MakePrivateKeyName(name))
+	if err != nil {
+		app.Audit(cmd, audit.NewRecord(audit.OperationFetch, name, err))
+
+		return fmt.Errorf("failed to fetch private key for '%s': %w", name, err)
+	}
+
+	publicKeyPEM, err := app.ParameterStore.GetParameter(aws.MakePublicKeyName(name))
+	if err != nil {
+		app.Audit(cmd, audit.NewRecord(audit.OperationFetch, name, err))
+
+		return fmt.Errorf("failed to fetch public key for '%s': %w", name, err)
+	}
+
+	record := audit.NewRecord(audit.OperationFetch, name, nil)
+	record.Fingerprint, _ = rotation.FingerprintPEM(publicKeyPEM)
+	app.Audit(cmd, record)
+
+	env := os.Environ()
+	env = appendKey(env, args.GetString(cmd, args.FlagStringPrivateKeyEnv), privateKeyPEM, encoding)
+	env = appendKey(env, args.GetString(cmd, args.FlagStringPublicKeyEnv), publicKeyPEM, encoding)
+
+	klog.Logf("Running %s with key pair '%s'", command[0], name).Info()
+
+	code, err := app.Processes.Run(command, env, process.Stdio{
+		Stdin:  app.Stdin,
+		Stdout: app.Stdout,
+		Stderr: app.Stderr,
+	})
+	if err != nil {
+		return types.Errorf(types.ErrorKindNotFound, "failed to start %s: %w", command[0], err)
+	}
+
+	if code != 0 {
+		return &types.ExitError{Code: code}
+	}
+
+	return nil
+}
+
+// appendKey adds the key to env under variable, unless variable is empty.
+func appendKey(env []string, variable, keyPEM, encoding string) []string {
+	if variable == "" {
+		return env
+	}
+
+	if encoding == args.EncodingBase64 {
+		keyPEM = base64.StdEncoding.EncodeToString([]byte(keyPEM))
+	}
+
+	return append(env, variable+"="+keyPEM)
+}
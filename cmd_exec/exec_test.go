@@ -0,0 +1,145 @@
+package cmd_exec_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_exec"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+func TestExec(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		setup func(t *testing.T, env *apptest.Env)
+
+		kind     types.ErrorKind
+		exitCode int
+		encode   func(string) string
+		public   bool
+	}{
+		{
+			name:   "runs the command with the key pair",
+			flags:  []string{"--name", "/prod/signing", "--", "deploy", "--now"},
+			setup:  func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			encode: func(key string) string { return key },
+			public: true,
+		},
+		{
+			name:  "encodes the keys in base64",
+			flags: []string{"--name", "/prod/signing", "--encoding", "base64", "--", "deploy", "--now"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			encode: func(key string) string {
+				return base64.StdEncoding.EncodeToString([]byte(key))
+			},
+			public: true,
+		},
+		{
+			name:   "leaves out a key with an empty variable",
+			flags:  []string{"--name", "/prod/signing", "--public-key-env", "", "--", "deploy", "--now"},
+			setup:  func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			encode: func(key string) string { return key },
+		},
+		{
+			name:  "exits with the command's exit code",
+			flags: []string{"--name", "/prod/signing", "--", "deploy", "--now"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.Processes.Code = 3
+			},
+			exitCode: 3,
+			encode:   func(key string) string { return key },
+			public:   true,
+		},
+		{
+			name:  "reports a command that cannot start",
+			flags: []string{"--name", "/prod/signing", "--", "deploy", "--now"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.Processes.Err = errors.New("executable file not found")
+			},
+			kind: types.ErrorKindNotFound,
+		},
+		{
+			name:  "reports missing keys",
+			flags: []string{"--name", "/prod/signing", "--", "deploy", "--now"},
+			kind:  types.ErrorKindNotFound,
+		},
+		{
+			name:  "rejects an unknown encoding",
+			flags: []string{"--name", "/prod/signing", "--encoding", "hex", "--", "deploy", "--now"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			kind:  types.ErrorKindValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			if test.setup != nil {
+				test.setup(t, env)
+			}
+
+			command := cmd_exec.ExecCommand{Command: env.Command}
+			err := env.Run(args.MountExecCommand, command.Run, test.flags...)
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+				assert.Empty(t, env.Processes.Runs())
+
+				return
+			}
+
+			if test.exitCode != 0 {
+				var exitErr *types.ExitError
+				require.ErrorAs(t, err, &exitErr)
+				assert.Equal(t, test.exitCode, exitErr.Code)
+			} else {
+				require.NoError(t, err)
+			}
+
+			runs := env.Processes.Runs()
+			require.Len(t, runs, 1)
+			assert.Equal(t, []string{"deploy", "--now"}, runs[0].Command)
+
+			privateKey, err := env.Command.ParameterStore.GetParameter("/prod/signing_priv.pem")
+			require.NoError(t, err)
+			assert.Contains(t, runs[0].Env, args.DefaultPrivateKeyEnv+"="+test.encode(privateKey))
+
+			if !test.public {
+				for _, variable := range runs[0].Env {
+					assert.False(t, strings.HasPrefix(variable, args.DefaultPublicKeyEnv+"="), variable)
+				}
+
+				return
+			}
+
+			publicKey, err := env.Command.ParameterStore.GetParameter("/prod/signing_pub.pem")
+			require.NoError(t, err)
+			assert.Contains(t, runs[0].Env, args.DefaultPublicKeyEnv+"="+test.encode(publicKey))
+		})
+	}
+}
+
+func TestExecConnectsStdio(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "/prod/signing")
+	env.Stdin.WriteString("input")
+	env.Processes.Output = "output"
+
+	command := cmd_exec.ExecCommand{Command: env.Command}
+	require.NoError(t, env.Run(args.MountExecCommand, command.Run, "--name", "/prod/signing", "--", "deploy"))
+
+	runs := env.Processes.Runs()
+	require.Len(t, runs, 1)
+	assert.Equal(t, "input", runs[0].Stdin)
+	assert.Equal(t, "output", env.Stdout.String())
+}
@@ -3,14 +3,16 @@ package cmd_fetch
 import (
 	"fmt"
 
-	rotator "github.com/kmesiab/go-key-rotator"
 	klog "github.com/kmesiab/go-klogger"
 
 	"github.com/kmesiab/go-key-rotator-cli/app"
 	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
 	"github.com/kmesiab/go-key-rotator-cli/aws"
 	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
 	"github.com/kmesiab/go-key-rotator-cli/types"
+	"github.com/kmesiab/go-key-rotator-cli/watch"
 
 	"github.com/spf13/cobra"
 )
@@ -19,58 +21,124 @@ type FetchCommand struct {
 	app.Command
 }
 
-func (app FetchCommand) Run(cmd *cobra.Command, _ []string) {
-	klog.Logf("Fetching keys!").Info()
+// Result describes a downloaded key pair.
+type Result struct {
+	Name                string `json:"name" yaml:"name"`
+	PublicKeyParameter  string `json:"public_key_parameter" yaml:"public_key_parameter"`
+	PrivateKeyParameter string `json:"private_key_parameter" yaml:"private_key_parameter"`
+	PublicKeyFile       string `json:"public_key_file" yaml:"public_key_file"`
+	PrivateKeyFile      string `json:"private_key_file" yaml:"private_key_file"`
+	Fingerprint         string `json:"fingerprint" yaml:"fingerprint"`
+	Version             int64  `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+func (r Result) Text() string {
+	return fmt.Sprintf(`
+🔐 Downoaded RSA key pair with names:
+	
+   💾 Public Key: %s
+   💾 Private Key: %s
+`,
+		r.PublicKeyParameter,
+		r.PrivateKeyParameter,
+	)
+}
 
-	if !aws.IsValidParameterStoreName(args.GetName(cmd)) {
-		klog.Logf("Invalid Parameter Store name '%s'. Requirements: %s", args.GetName(cmd),
-			aws.ParameterStoreNamingRequirementsString).Error()
+func (app FetchCommand) Run(cmd *cobra.Command, _ []string) error {
+	klog.Logf("Fetching keys!").Info()
 
-		return
+	if _, err := app.Name(cmd); err != nil {
+		return err
 	}
 
 	pubKeyName := aws.MakePublicKeyName(args.GetName(cmd))
 	privKeyName := aws.MakePrivateKeyName(args.GetName(cmd))
 
-	keyRotator := rotator.NewKeyRotator(rotator.NewAWSParameterStore(app.AWSSession))
+	if args.GetBool(cmd, args.FlagStringWatch) {
+		return app.watch(cmd, privKeyName, pubKeyName)
+	}
+
+	destination := app.Destination(cmd)
+	if err := app.Files.Check(destination, privKeyName, pubKeyName); err != nil {
+		return err
+	}
 
-	privateKey, err := keyRotator.GetCurrentRSAPrivateKey(privKeyName)
+	result, err := app.fetch(cmd, destination, privKeyName, pubKeyName)
 	if err != nil {
-		klog.Logf("Failed to fetch private key for '%s'. Ensure the key "+
-			"exists and you have the necessary permissions.", privKeyName).Add("error", err).Error()
+		return err
+	}
+
+	app.Print(cmd, result)
+
+	return nil
+}
 
-		return
+// fetch downloads the key pair and writes it to destination.
+func (app FetchCommand) fetch(
+	cmd *cobra.Command,
+	destination filesystem.Destination,
+	privKeyName, pubKeyName string,
+) (*Result, error) {
+	// The CLI's parameter store classifies AWS errors
+	privateKey, err := rotation.ReadPrivateKey(app.ParameterStore, privKeyName)
+	if err != nil {
+		app.Audit(cmd, audit.NewRecord(audit.OperationFetch, args.GetName(cmd), err))
+
+		return nil, readError(args.GetName(cmd), "private", privKeyName, err)
 	}
 
-	publicKey, err := keyRotator.GetCurrentRSAPublicKey(pubKeyName)
+	publicKey, err := rotation.ReadPublicKey(app.ParameterStore, pubKeyName)
 	if err != nil {
-		klog.Logf("Failed to fetch public key for '%s'. Ensure the key "+
-			"exists and you have the necessary permissions.", pubKeyName).Add("error", err).Error()
+		app.Audit(cmd, audit.NewRecord(audit.OperationFetch, args.GetName(cmd), err))
 
-		return
+		return nil, readError(args.GetName(cmd), "public", pubKeyName, err)
+	}
+
+	if !privateKey.PublicKey.Equal(publicKey) {
+		return nil, fmt.Errorf("'%s': %w", args.GetName(cmd), watch.ErrMismatchedPair)
 	}
 
 	rotatorResult := &types.Rotation{
 		PublicKey:      publicKey,
 		PrivateKey:     privateKey,
-		PublicKeyName:  aws.GetFilenameFromParameterStorePath(pubKeyName),
-		PrivateKeyName: aws.GetFilenameFromParameterStorePath(privKeyName),
+		PublicKeyName:  pubKeyName,
+		PrivateKeyName: privKeyName,
 	}
 
-	err = filesystem.WriteAllKeysToFile(rotatorResult, keyRotator)
+	record := audit.NewRecord(audit.OperationFetch, args.GetName(cmd), nil)
+	record.Fingerprint, _ = rotation.Fingerprint(publicKey)
+
+	files, err := app.Files.WriteKeyPair(destination, rotatorResult)
 	if err != nil {
-		klog.Logf("Failed to write keys to file for '%s': %s. Check file permissions and "+
-			"availability of file system.", args.GetName(cmd), err).Error()
-		return
+		app.Audit(cmd, audit.NewRecord(audit.OperationFetch, args.GetName(cmd), err))
+
+		return nil, fmt.Errorf("failed to write keys to file for '%s'. Check file permissions and "+
+			"availability of file system: %w", args.GetName(cmd), err)
 	}
 
-	fmt.Printf(`
-🔐 Downoaded RSA key pair with names:
-	
-   💾 Public Key: %s
-   💾 Private Key: %s
-`,
-		pubKeyName,
-		privKeyName,
-	)
+	app.Audit(cmd, record)
+
+	return &Result{
+		Name:                args.GetName(cmd),
+		PublicKeyParameter:  pubKeyName,
+		PrivateKeyParameter: privKeyName,
+		PublicKeyFile:       files.PublicKey,
+		PrivateKeyFile:      files.PrivateKey,
+		Fingerprint:         record.Fingerprint,
+	}, nil
+}
+
+// readError explains why a half of the key pair could not be read.
+func readError(name, half, parameter string, err error) error {
+	switch types.KindOf(err) {
+	case types.ErrorKindNotFound:
+		return fmt.Errorf("the %s key of '%s' is not stored as '%s'. Run 'go-rotate store --name %s' "+
+			"to create it: %w", half, name, parameter, name, err)
+	case types.ErrorKindPermissionDenied:
+		return fmt.Errorf("not allowed to read or decrypt the %s key '%s'. Run 'go-rotate doctor "+
+			"--name %s' to see which permission is missing: %w", half, parameter, name, err)
+	}
+
+	return fmt.Errorf("failed to fetch the %s key '%s'. Run 'go-rotate doctor --name %s' to check "+
+		"your setup: %w", half, parameter, name, err)
 }
@@ -0,0 +1,112 @@
+package cmd_fetch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_fetch"
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+	"github.com/kmesiab/go-key-rotator-cli/watch"
+)
+
+func TestFetch(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		setup func(t *testing.T, env *apptest.Env)
+
+		kind types.ErrorKind
+		err  error
+	}{
+		{
+			name:  "writes the stored pair",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+		},
+		{
+			name:  "overwrites with --force",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys", "--force"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.Files.Put("/keys/signing_priv.pem", []byte("old"))
+			},
+		},
+		{
+			name:  "reports missing keys",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			kind:  types.ErrorKindNotFound,
+		},
+		{
+			name:  "refuses to overwrite",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.Files.Put("/keys/signing_priv.pem", []byte("old"))
+			},
+			err: filesystem.ErrFileExists,
+		},
+		{
+			name:  "refuses mismatched halves",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+
+				_, other, err := env.Generator.GenerateKeyPair(2048)
+				require.NoError(t, err)
+
+				require.NoError(t, env.Command.ParameterStore.PutParameter("/prod/signing_priv.pem",
+					string(rotator.EncodePrivateKeyToPEM(other)), "SecureString"))
+			},
+			err: watch.ErrMismatchedPair,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			if test.setup != nil {
+				test.setup(t, env)
+			}
+
+			command := cmd_fetch.FetchCommand{Command: env.Command}
+			err := env.Run(args.MountFetchCommand, command.Run, append(test.flags, "-o", "json")...)
+
+			switch {
+			case test.kind != "":
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			case test.err != nil:
+				assert.ErrorIs(t, err, test.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var fetched cmd_fetch.Result
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &fetched))
+
+			for path, parameter := range map[string]string{
+				fetched.PrivateKeyFile: fetched.PrivateKeyParameter,
+				fetched.PublicKeyFile:  fetched.PublicKeyParameter,
+			} {
+				stored, err := env.Command.ParameterStore.GetParameter(parameter)
+				require.NoError(t, err)
+
+				written, ok := env.Files.Read(path)
+				require.True(t, ok, path)
+				assert.Equal(t, stored, string(written))
+			}
+
+			assert.Equal(t, filesystem.PrivateKeyMode, env.Files.Mode(fetched.PrivateKeyFile))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package cmd_fetch
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/process"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+	"github.com/kmesiab/go-key-rotator-cli/watch"
+)
+
+// watch keeps the key files in step with the store until interrupted. The
+// files are owned by the watcher, so they are always overwritten.
+func (app FetchCommand) watch(cmd *cobra.Command, privKeyName, pubKeyName string) error {
+	reloadSignal, err := process.ParseSignal(args.GetString(cmd, args.FlagStringReloadSignal))
+	if err != nil {
+		return types.NewError(types.ErrorKindValidation, err)
+	}
+
+	destination := app.Destination(cmd)
+	destination.Force = true
+
+	if destination.Streams() {
+		return types.Errorf(types.ErrorKindValidation, "--%s cannot write keys to stdout", args.FlagStringWatch)
+	}
+
+	if err := app.Files.Check(destination, privKeyName, pubKeyName); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(app.Context(cmd), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	synced := false
+
+	watcher := &watch.Watcher{
+		Store:          app.ParameterStore,
+		PrivateKeyName: privKeyName,
+		PublicKeyName:  pubKeyName,
+		Interval:       args.GetDuration(cmd, args.FlagStringInterval),
+		Clock:          app.Clock,
+		OnChange: func(version watch.Version) error {
+			result, err := app.fetch(cmd, destination, privKeyName, pubKeyName)
+			if err != nil {
+				return err
+			}
+
+			result.Version = version.PrivateKey
+			app.Print(cmd, result)
+
+			// The first sync only puts the files in place
+			if synced {
+				reload(ctx, cmd, reloadSignal)
+			}
+
+			synced = true
+
+			return nil
+		},
+	}
+
+	klog.Logf("Watching '%s' for new versions every %s", args.GetName(cmd), watcher.Interval).Info()
+
+	if err := watcher.Run(ctx); err != nil {
+		return err
+	}
+
+	klog.Logf("Stopped watching '%s'.", args.GetName(cmd)).Info()
+
+	return nil
+}
+
+// reload tells the consumer of the key files that they changed. Failures
+// are only logged; the files are already in place.
+func reload(ctx context.Context, cmd *cobra.Command, sig os.Signal) {
+	if pid := args.GetInt(cmd, args.FlagStringReloadPID); pid > 0 {
+		if err := process.Signal(pid, sig); err != nil {
+			klog.Logf("Error signalling process %d: %s", pid, err).Error()
+		}
+	}
+
+	if command := args.GetString(cmd, args.FlagStringReloadCommand); command != "" {
+		if err := process.Shell(ctx, command); err != nil {
+			klog.Logf("Error running reload command: %s", err).Error()
+		}
+	}
+}
@@ -4,86 +4,90 @@ import (
 	"fmt"
 	"strconv"
 
-	rotator "github.com/kmesiab/go-key-rotator"
 	klog "github.com/kmesiab/go-klogger"
 	"github.com/spf13/cobra"
 
 	"github.com/kmesiab/go-key-rotator-cli/app"
 	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
 	"github.com/kmesiab/go-key-rotator-cli/aws"
-	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
 )
 
 type GenerateCommand struct {
 	app.Command
 }
 
-func (app GenerateCommand) Run(cmd *cobra.Command, _ []string) {
+// Result describes a generated key pair.
+type Result struct {
+	Name           string `json:"name" yaml:"name"`
+	KeySize        int64  `json:"key_size" yaml:"key_size"`
+	PublicKeyFile  string `json:"public_key_file" yaml:"public_key_file"`
+	PrivateKeyFile string `json:"private_key_file" yaml:"private_key_file"`
+	Fingerprint    string `json:"fingerprint" yaml:"fingerprint"`
+}
 
-	if !aws.IsValidParameterStoreName(args.GetName(cmd)) {
-		klog.Logf(aws.ParameterStoreNamingRequirementsString, args.GetName(cmd)).Error()
+func (r Result) Text() string {
+	return fmt.Sprintf(`
+🔐 Generated %d bit RSA key pair with names:
+	
+   💾 Public Key: %s
+   💾 Private Key: %s
+`,
+		r.KeySize,
+		r.PublicKeyFile,
+		r.PrivateKeyFile,
+	)
+}
+
+func (app GenerateCommand) Run(cmd *cobra.Command, _ []string) error {
 
-		return
+	if _, err := app.Name(cmd); err != nil {
+		return err
 	}
 
 	klog.Logf("Generating new keys! ").Info()
 
-	keyRotator := rotator.NewKeyRotator(
-		rotator.NewAWSParameterStore(app.AWSSession),
-	)
-
 	size := args.GetSize(cmd)
 	sizeInt, err := strconv.ParseInt(size, 10, 64)
 
 	if err != nil || sizeInt < 2048 || sizeInt > 4096 {
-
-		klog.Logf("Invalid key size: %s. Key size must "+
-			"be between %d and %d bits.", size, 2048, 4096).Error()
-
-		return
+		return types.Errorf(types.ErrorKindValidation, "invalid key size: %s. Key size must "+
+			"be between %d and %d bits", size, 2048, 4096)
 	}
 
-	publicKey, privateKey, err := keyRotator.GenerateKeyPair(int(sizeInt))
+	publicKey, privateKey, err := app.KeyGenerator.GenerateKeyPair(int(sizeInt))
 
 	if err != nil {
-		klog.Logf("Failed to generate RSA key pair with size %s bits: %d\n",
-			size, err).Error()
+		app.Audit(cmd, audit.NewRecord(audit.OperationGenerate, args.GetName(cmd), err))
 
-		return
+		return fmt.Errorf("failed to generate RSA key pair with size %s bits: %w", size, err)
 	}
 
-	publicKeyPEMBytes, err := rotator.EncodePublicKeyToPEM(publicKey)
-	privateKeyPEMBytes := rotator.EncodePrivateKeyToPEM(privateKey)
-
+	files, err := app.Files.WriteKeyPair(app.Destination(cmd), &types.Rotation{
+		PublicKey:      publicKey,
+		PrivateKey:     privateKey,
+		PublicKeyName:  aws.MakePublicKeyName(args.GetName(cmd)),
+		PrivateKeyName: aws.MakePrivateKeyName(args.GetName(cmd)),
+	})
 	if err != nil {
-		klog.Logf("Failed to encode RSA public key to PEM format: %s\n", err).Error()
-
-		return
+		return fmt.Errorf("failed to write key pair: %w", err)
 	}
 
-	pubKeyName := aws.MakePublicKeyName(args.GetName(cmd))
-	privKeyName := aws.MakePrivateKeyName(args.GetName(cmd))
-
-	if err := filesystem.WritePEMToFile(pubKeyName, publicKeyPEMBytes); err != nil {
-		klog.Logf("Failed to write public key to file: %s\n", pubKeyName).Error()
+	fingerprint, _ := rotation.Fingerprint(publicKey)
 
-		return
-	}
-
-	if err := filesystem.WritePEMToFile(privKeyName, privateKeyPEMBytes); err != nil {
-		klog.Logf("Failed to write private key to file: %s\n", privKeyName).Error()
+	record := audit.NewRecord(audit.OperationGenerate, args.GetName(cmd), nil)
+	record.Fingerprint = fingerprint
+	app.Audit(cmd, record)
 
-		return
-	}
+	app.Print(cmd, Result{
+		Name:           args.GetName(cmd),
+		KeySize:        sizeInt,
+		PublicKeyFile:  files.PublicKey,
+		PrivateKeyFile: files.PrivateKey,
+		Fingerprint:    fingerprint,
+	})
 
-	fmt.Printf(`
-🔐 Generated %d bit RSA key pair with names:
-	
-   💾 Public Key: %s
-   💾 Private Key: %s
-`,
-		sizeInt,
-		pubKeyName,
-		privKeyName,
-	)
+	return nil
 }
@@ -0,0 +1,112 @@
+package cmd_generate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_generate"
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/ssmfake"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		setup func(env *apptest.Env)
+
+		kind        types.ErrorKind
+		err         error
+		files       []string
+		keyOnStdout bool
+	}{
+		{
+			name:  "writes both halves",
+			flags: []string{"--name", "signing", "--out-dir", "/keys"},
+			files: []string{"/keys/signing_priv.pem", "/keys/signing_pub.pem"},
+		},
+		{
+			name:  "uses the name template",
+			flags: []string{"--name", "signing", "--name-template", "/{env}/{purpose}", "--env", "prod"},
+			files: []string{"signing_priv.pem", "signing_pub.pem"},
+		},
+		{
+			name:        "streams the private key",
+			flags:       []string{"--name", "signing", "--private-out", "-", "--public-out", "/keys/pub.pem"},
+			files:       []string{"/keys/pub.pem"},
+			keyOnStdout: true,
+		},
+		{
+			name:  "rejects small keys",
+			flags: []string{"--name", "signing", "--size", "1024"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "rejects invalid names",
+			flags: []string{"--name", "/aws/signing"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "refuses to overwrite",
+			flags: []string{"--name", "signing", "--out-dir", "/keys"},
+			setup: func(env *apptest.Env) { env.Files.Put("/keys/signing_pub.pem", []byte("old")) },
+			err:   filesystem.ErrFileExists,
+		},
+		{
+			name:  "reports generator failures",
+			flags: []string{"--name", "signing"},
+			setup: func(env *apptest.Env) { env.Generator.Err = errors.New("no entropy") },
+			err:   errors.New("no entropy"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			if test.setup != nil {
+				test.setup(env)
+			}
+
+			command := cmd_generate.GenerateCommand{Command: env.Command}
+			err := env.Run(args.MountGenerateCommand, command.Run, append(test.flags, "-o", "json")...)
+
+			switch {
+			case test.kind != "":
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			case test.err != nil:
+				assert.ErrorContains(t, err, test.err.Error())
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			for _, path := range test.files {
+				_, ok := env.Files.Read(path)
+				assert.True(t, ok, path)
+			}
+
+			result := env.Stdout
+			if test.keyOnStdout {
+				assert.Contains(t, env.Stdout.String(), "BEGIN RSA PRIVATE KEY")
+				result = env.Stderr
+			}
+
+			var generated cmd_generate.Result
+			require.NoError(t, json.Unmarshal(result.Bytes(), &generated))
+			assert.Equal(t, int64(2048), generated.KeySize)
+			assert.NotEmpty(t, generated.Fingerprint)
+
+			assert.Zero(t, env.SSM.Calls(ssmfake.OperationPutParameter))
+		})
+	}
+}
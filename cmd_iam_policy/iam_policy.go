@@ -0,0 +1,54 @@
+package cmd_iam_policy
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/iam"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+type IAMPolicyCommand struct {
+	app.Command
+}
+
+// Result is the policy document. Its text form is the policy JSON too, so
+// it can be redirected straight into a file.
+type Result struct {
+	iam.Policy `yaml:",inline"`
+}
+
+func (r Result) Text() string {
+	text, err := json.MarshalIndent(r.Policy, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(text) + "\n"
+}
+
+func (app IAMPolicyCommand) Run(cmd *cobra.Command, _ []string) error {
+	name, err := app.Name(cmd)
+	if err != nil {
+		return err
+	}
+
+	policy, err := iam.NewPolicy(iam.Options{
+		Name:     name,
+		Commands: args.GetStringArray(cmd, args.FlagStringCommand),
+		Region:   app.AWSConfig.Region,
+		Account:  args.GetString(cmd, args.FlagStringAccountID),
+		KeyID:    args.GetString(cmd, args.FlagStringKMSKeyID),
+		Lock:     args.GetString(cmd, args.FlagStringLock) == args.LockBackendSSM,
+	})
+	if err != nil {
+		return types.NewError(types.ErrorKindValidation, err)
+	}
+
+	app.Print(cmd, Result{Policy: policy})
+
+	return nil
+}
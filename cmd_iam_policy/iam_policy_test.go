@@ -0,0 +1,102 @@
+package cmd_iam_policy_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_iam_policy"
+	"github.com/kmesiab/go-key-rotator-cli/iam"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const (
+	privateKeyARN = "arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_priv.pem"
+	publicKeyARN  = "arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_pub.pem"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  []string
+		region string
+
+		kind     types.ErrorKind
+		expected []iam.Statement
+	}{
+		{
+			name:   "prints the policy for the chosen commands",
+			flags:  []string{"--name", "/prod/signing", "--command", "fetch", "--account-id", "123456789012"},
+			region: "us-east-1",
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+			},
+		},
+		{
+			name:  "matches any region and account without them",
+			flags: []string{"--name", "/prod/signing", "--command", "fetch"},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{
+						"arn:aws:ssm:*:*:parameter/prod/signing_priv.pem",
+						"arn:aws:ssm:*:*:parameter/prod/signing_pub.pem",
+					}},
+			},
+		},
+		{
+			name: "adds the lock parameter with --lock ssm",
+			flags: []string{"--name", "/prod/signing", "--command", "store", "--account-id", "123456789012",
+				"--lock", "ssm"},
+			region: "us-east-1",
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "WriteKeyPair", Effect: "Allow",
+					Action:   []string{"ssm:PutParameter", "ssm:DeleteParameter", "ssm:AddTagsToResource"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "HoldRotationLock", Effect: "Allow",
+					Action:   []string{"ssm:GetParameter", "ssm:PutParameter", "ssm:DeleteParameter"},
+					Resource: []string{"arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_lock"}},
+			},
+		},
+		{
+			name:  "rejects unknown commands",
+			flags: []string{"--name", "/prod/signing", "--command", "generate"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "rejects an invalid name",
+			flags: []string{"--name", "/aws/signing"},
+			kind:  types.ErrorKindValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			env.Command.AWSConfig.Region = test.region
+
+			command := cmd_iam_policy.IAMPolicyCommand{Command: env.Command}
+			err := env.Run(args.MountIAMPolicyCommand, command.Run, test.flags...)
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			// The text output is the policy document itself
+			var policy iam.Policy
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &policy))
+			assert.Equal(t, iam.PolicyVersion, policy.Version)
+			assert.Equal(t, test.expected, policy.Statement)
+		})
+	}
+}
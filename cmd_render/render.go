@@ -0,0 +1,91 @@
+package cmd_render
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/render"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+type RenderCommand struct {
+	app.Command
+}
+
+// Result describes a rendered template.
+type Result struct {
+	Template string   `json:"template" yaml:"template"`
+	Out      string   `json:"out" yaml:"out"`
+	Keys     []string `json:"keys" yaml:"keys"`
+	Mode     string   `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+func (r Result) Text() string {
+	return fmt.Sprintf("\n📝 Rendered %s to %s using keys %v\n", r.Template, r.Out, r.Keys)
+}
+
+func (app RenderCommand) Run(cmd *cobra.Command, _ []string) error {
+	templatePath := args.GetString(cmd, args.FlagStringTemplate)
+	out := args.GetString(cmd, args.FlagStringOut)
+
+	owner, err := filesystem.ParseOwner(args.GetString(cmd, args.FlagStringChown))
+	if err != nil {
+		return err
+	}
+
+	text, err := app.Files.ReadFile(templatePath)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "failed to read template: %w", err)
+	}
+
+	renderer := render.NewRenderer(app.ParameterStore)
+	renderer.Region = app.AWSConfig.Region
+	renderer.ExpandName = func(name string) (string, error) {
+		return args.ExpandName(cmd, name)
+	}
+
+	rendered, err := renderer.Render(templatePath, string(text))
+
+	for _, name := range renderer.Keys() {
+		app.Audit(cmd, audit.NewRecord(audit.OperationFetch, name, nil))
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", templatePath, err)
+	}
+
+	result := Result{Template: templatePath, Out: out, Keys: renderer.Keys()}
+
+	if out == filesystem.StdoutPath {
+		if _, err := app.Stdout.Write(rendered); err != nil {
+			return err
+		}
+
+		app.PrintTo(cmd, app.Stderr, result)
+
+		return nil
+	}
+
+	mode := filesystem.PublicKeyMode
+	if renderer.UsedPrivateKey() {
+		mode = filesystem.PrivateKeyMode
+	}
+
+	// Rendered files are regenerated from the store, so they are replaced
+	options := filesystem.WriteOptions{Overwrite: true, Owner: owner}
+
+	if err := app.Files.WriteFile(out, rendered, mode, options); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+
+	result.Mode = fmt.Sprintf("%#o", mode)
+
+	app.Print(cmd, result)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package cmd_render_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_render"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		flags    []string
+		setup    func(t *testing.T, env *apptest.Env)
+
+		kind      types.ErrorKind
+		err       string
+		parameter string
+		expected  cmd_render.Result
+	}{
+		{
+			name:      "renders a public key",
+			template:  `{{ publicKeyPEM "/prod/signing" }}`,
+			flags:     []string{"--out", "/etc/app/key.pem"},
+			setup:     func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			parameter: "/prod/signing_pub.pem",
+			expected: cmd_render.Result{
+				Out:  "/etc/app/key.pem",
+				Keys: []string{"/prod/signing"},
+				Mode: "0644",
+			},
+		},
+		{
+			name:      "keeps a rendered private key private",
+			template:  `{{ privateKeyPEM "/prod/signing" }}`,
+			flags:     []string{"--out", "/etc/app/key.pem"},
+			setup:     func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			parameter: "/prod/signing_priv.pem",
+			expected: cmd_render.Result{
+				Out:  "/etc/app/key.pem",
+				Keys: []string{"/prod/signing"},
+				Mode: "0600",
+			},
+		},
+		{
+			name:     "replaces a rendered file",
+			template: `{{ publicKeyPEM "/prod/signing" }}`,
+			flags:    []string{"--out", "/etc/app/key.pem"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.Files.Put("/etc/app/key.pem", []byte("old"))
+			},
+			parameter: "/prod/signing_pub.pem",
+			expected: cmd_render.Result{
+				Out:  "/etc/app/key.pem",
+				Keys: []string{"/prod/signing"},
+				Mode: "0644",
+			},
+		},
+		{
+			name:  "reports a missing template",
+			flags: []string{"--out", "/etc/app/key.pem"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:     "reports missing keys",
+			template: `{{ publicKeyPEM "/prod/signing" }}`,
+			flags:    []string{"--out", "/etc/app/key.pem"},
+			err:      "failed to render",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			if test.setup != nil {
+				test.setup(t, env)
+			}
+
+			if test.template != "" {
+				env.Files.Put("/templates/key.tmpl", []byte(test.template))
+			}
+
+			command := cmd_render.RenderCommand{Command: env.Command}
+			err := env.Run(args.MountRenderCommand, command.Run,
+				append(test.flags, "--template", "/templates/key.tmpl", "-o", "json")...)
+
+			if test.kind != "" || test.err != "" {
+				if test.kind != "" {
+					assert.Equal(t, test.kind, types.KindOf(err))
+				} else {
+					assert.ErrorContains(t, err, test.err)
+				}
+
+				_, written := env.Files.Read("/etc/app/key.pem")
+				assert.False(t, written)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var result cmd_render.Result
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+
+			test.expected.Template = "/templates/key.tmpl"
+			assert.Equal(t, test.expected, result)
+
+			expected, err := env.Command.ParameterStore.GetParameter(test.parameter)
+			require.NoError(t, err)
+
+			rendered, ok := env.Files.Read("/etc/app/key.pem")
+			require.True(t, ok)
+			assert.Equal(t, expected, string(rendered))
+			assert.Equal(t, test.expected.Mode, fmt.Sprintf("%#o", env.Files.Mode("/etc/app/key.pem")))
+		})
+	}
+}
+
+func TestRenderToStdout(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "/prod/signing")
+	env.Files.Put("/templates/key.tmpl", []byte(`key_id: {{ fingerprint "/prod/signing" }}`))
+
+	command := cmd_render.RenderCommand{Command: env.Command}
+	require.NoError(t, env.Run(args.MountRenderCommand, command.Run,
+		"--template", "/templates/key.tmpl", "--out", "-", "-o", "json"))
+
+	assert.Contains(t, env.Stdout.String(), "key_id: SHA256:")
+
+	// The result goes to stderr, keeping stdout to the rendered file
+	var result cmd_render.Result
+	require.NoError(t, json.Unmarshal(env.Stderr.Bytes(), &result))
+	assert.Equal(t, cmd_render.Result{
+		Template: "/templates/key.tmpl",
+		Out:      "-",
+		Keys:     []string{"/prod/signing"},
+	}, result)
+}
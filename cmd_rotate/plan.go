@@ -0,0 +1,168 @@
+package cmd_rotate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Operations a PlannedWrite performs on a parameter.
+const (
+	OperationCreate    = "create"
+	OperationOverwrite = "overwrite"
+)
+
+var operationLabels = map[string]string{
+	OperationCreate:    "Create",
+	OperationOverwrite: "Overwrite",
+}
+
+// PlannedWrite is a parameter write a --dry-run store would make.
+type PlannedWrite struct {
+	Parameter      string            `json:"parameter" yaml:"parameter"`
+	Operation      string            `json:"operation" yaml:"operation"`
+	Type           string            `json:"type" yaml:"type"`
+	CurrentVersion int64             `json:"current_version,omitempty" yaml:"current_version,omitempty"`
+	Tier           string            `json:"tier,omitempty" yaml:"tier,omitempty"`
+	KeyID          string            `json:"kms_key_id,omitempty" yaml:"kms_key_id,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Policies       []string          `json:"policies,omitempty" yaml:"policies,omitempty"`
+}
+
+// Plan describes what a store would do, without doing it.
+type Plan struct {
+	Name      string         `json:"name" yaml:"name"`
+	DryRun    bool           `json:"dry_run" yaml:"dry_run"`
+	Action    string         `json:"action" yaml:"action"`
+	Status    policy.Status  `json:"status,omitempty" yaml:"status,omitempty"`
+	KeySize   int64          `json:"key_size" yaml:"key_size"`
+	Writes    []PlannedWrite `json:"writes" yaml:"writes"`
+	Files     []string       `json:"files,omitempty" yaml:"files,omitempty"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+func (p Plan) Text() string {
+	if p.Action == ActionSkipped {
+		return fmt.Sprintf("\n🧪 Dry run: key '%s' is %s, rotation would be skipped.\n", p.Name, p.Status)
+	}
+
+	text := fmt.Sprintf("\n🧪 Dry run: a %d bit key pair would be %s as '%s'. Nothing was changed.\n\n",
+		p.KeySize, p.Action, p.Name)
+
+	for _, write := range p.Writes {
+		details := []string{write.Type}
+
+		if write.Operation == OperationOverwrite {
+			details = append(details, fmt.Sprintf("version %d → %d", write.CurrentVersion, write.CurrentVersion+1))
+		}
+
+		if write.Tier != "" {
+			details = append(details, write.Tier+" tier")
+		}
+
+		if write.KeyID != "" {
+			details = append(details, "KMS key "+write.KeyID)
+		}
+
+		text += fmt.Sprintf("   ✏️ %s %s (%s)\n", operationLabels[write.Operation], write.Parameter,
+			strings.Join(details, ", "))
+
+		if len(write.Tags) > 0 {
+			text += fmt.Sprintf("      🏷️ Tags: %s\n", formatTags(write.Tags))
+		}
+
+		for _, summary := range write.Policies {
+			text += fmt.Sprintf("      ⌛ Policy: %s\n", summary)
+		}
+	}
+
+	for _, path := range p.Files {
+		if path == filesystem.StdoutPath {
+			path = "stdout"
+		}
+
+		text += fmt.Sprintf("   💾 Write %s\n", path)
+	}
+
+	return text
+}
+
+// formatTags lists tags as sorted key=value pairs.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+// plan reads, but never writes, both halves of the key pair to describe what
+// storing it with options would do. The reads double as a check that the
+// caller may access the parameters: a missing parameter is planned as
+// created, while any other error, such as a denied read, is returned.
+func (app RotateCommand) plan(
+	cfg *config.Config,
+	name, privKeyName, pubKeyName string,
+	ifDue bool,
+	options types.PutOptions,
+) (Plan, error) {
+	plan := Plan{Name: name, DryRun: true, Action: ActionStored}
+
+	if ifDue {
+		status, err := app.RotationStatus(cfg, name)
+		if err != nil {
+			return plan, fmt.Errorf("unable to determine whether '%s' is due for rotation: %w", name, err)
+		}
+
+		if !status.NeedsRotation() {
+			plan.Action = ActionSkipped
+			plan.Status = status
+
+			return plan, nil
+		}
+	}
+
+	policies := make([]string, 0, len(options.Policies))
+	for _, text := range options.Policies {
+		policies = append(policies, aws.SummarizePolicy(text))
+	}
+
+	for _, parameter := range []string{privKeyName, pubKeyName} {
+		write := PlannedWrite{
+			Parameter: parameter,
+			Operation: OperationCreate,
+			Type:      options.Type,
+			Tier:      options.Tier,
+			KeyID:     options.KeyID,
+			Tags:      options.Tags,
+			Policies:  policies,
+		}
+
+		metadata, err := app.ParameterStore.DescribeParameter(parameter)
+
+		switch {
+		case errors.Is(err, types.ErrParameterNotFound):
+		case err != nil:
+			return plan, fmt.Errorf("unable to read '%s': %w", parameter, err)
+		default:
+			write.Operation = OperationOverwrite
+			write.CurrentVersion = metadata.Version
+			plan.Action = ActionRotated
+		}
+
+		plan.Writes = append(plan.Writes, write)
+	}
+
+	return plan, nil
+}
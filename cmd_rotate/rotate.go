@@ -1,40 +1,75 @@
 package cmd_rotate
 
 import (
-	"crypto/rsa"
 	"fmt"
 	"strconv"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws/session"
 	klog "github.com/kmesiab/go-klogger"
 	"github.com/spf13/cobra"
 
 	"github.com/kmesiab/go-key-rotator-cli/app"
 	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/audit"
 	"github.com/kmesiab/go-key-rotator-cli/aws"
-	"github.com/kmesiab/go-key-rotator-cli/filesystem"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
 	"github.com/kmesiab/go-key-rotator-cli/types"
 )
 
-var (
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
-)
-
 type RotateCommand struct {
 	app.Command
+}
 
-	Session    *session.Session
-	KeyRotator types.KeyRotatorInterface
+// Actions reported in a Result.
+const (
+	ActionStored  = "stored"
+	ActionRotated = "rotated"
+	ActionSkipped = "skipped"
+)
+
+// Result describes the outcome of a store.
+type Result struct {
+	Name                string        `json:"name" yaml:"name"`
+	Action              string        `json:"action" yaml:"action"`
+	Status              policy.Status `json:"status,omitempty" yaml:"status,omitempty"`
+	PublicKeyParameter  string        `json:"public_key_parameter" yaml:"public_key_parameter"`
+	PrivateKeyParameter string        `json:"private_key_parameter" yaml:"private_key_parameter"`
+	PublicKeyFile       string        `json:"public_key_file,omitempty" yaml:"public_key_file,omitempty"`
+	PrivateKeyFile      string        `json:"private_key_file,omitempty" yaml:"private_key_file,omitempty"`
+	Version             int64         `json:"version,omitempty" yaml:"version,omitempty"`
+	Fingerprint         string        `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	PreviousFingerprint string        `json:"previous_fingerprint,omitempty" yaml:"previous_fingerprint,omitempty"`
+	Tier                string        `json:"tier,omitempty" yaml:"tier,omitempty"`
+	ExpiresAt           *time.Time    `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
 }
 
-func (app RotateCommand) Run(cmd *cobra.Command, _ []string) {
-	klog.Logf("Rotating new keys...").Info()
+func (r Result) Text() string {
+	if r.Action == ActionSkipped {
+		return fmt.Sprintf("\n✅ Key '%s' is %s, skipped rotation.\n", r.Name, r.Status)
+	}
+
+	text := fmt.Sprintf(`
+🔐 Generated and stored keys:
+	
+   💾 Public Key: %s
+   💾 Private Key: %s
+`,
+		r.PublicKeyParameter,
+		r.PrivateKeyParameter,
+	)
 
-	if !aws.IsValidParameterStoreName(args.GetName(cmd)) {
-		klog.Logf(aws.ParameterStoreNamingRequirementsString, args.GetName(cmd)).Error()
+	if r.ExpiresAt != nil {
+		text += fmt.Sprintf("   ⌛ Expires: %s (%s tier)\n", r.ExpiresAt.Format(time.RFC3339), r.Tier)
+	}
+
+	return text
+}
 
-		return
+func (app RotateCommand) Run(cmd *cobra.Command, _ []string) error {
+	klog.Logf("Rotating new keys...").Info()
+
+	if _, err := app.Name(cmd); err != nil {
+		return err
 	}
 
 	pubKeyName := aws.MakePublicKeyName(args.GetName(cmd))
@@ -44,41 +79,125 @@ func (app RotateCommand) Run(cmd *cobra.Command, _ []string) {
 	sizeInt, err := strconv.ParseInt(size, 10, 64)
 
 	if err != nil || sizeInt < 2048 || sizeInt > 4096 {
+		return types.Errorf(types.ErrorKindValidation, "invalid key size: %s. Key size must "+
+			"be between %d and %d bits", size, 2048, 4096)
+	}
+
+	cfg, err := app.Config(cmd)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "failed to load config: %w", err)
+	}
+
+	if err := cfg.OverrideMaxAge(args.GetMaxAge(cmd)); err != nil {
+		return types.Errorf(types.ErrorKindValidation, "invalid max age: %w", err)
+	}
+
+	options, expiresAt, err := app.ParameterOptions(cmd, cfg.PolicyFor(args.GetName(cmd)), app.Clock.Now())
+	if err != nil {
+		return types.NewError(types.ErrorKindValidation, err)
+	}
 
-		klog.Logf("Invalid key size: %s. Key size must "+
-			"be between %d and %d bits.", size, 2048, 4096).Error()
+	// Refuse options the store cannot apply before planning or rotating
+	if _, err := app.Rotator(options); err != nil {
+		return types.NewError(types.ErrorKindValidation, err)
+	}
+
+	noWrite := args.GetBool(cmd, args.FlagStringNoWrite)
+	destination := app.Destination(cmd)
+
+	if noWrite && (destination.Dir != "" || destination.PrivateKeyPath != "" || destination.PublicKeyPath != "") {
+		return types.Errorf(types.ErrorKindValidation, "--%s cannot be combined with --%s, --%s or --%s",
+			args.FlagStringNoWrite, args.FlagStringOutDir, args.FlagStringPrivateOut, args.FlagStringPublicOut)
+	}
+
+	// Refuse before rotating, not after, if the key files cannot be written
+	if !noWrite {
+		if err := app.Files.Check(destination, privKeyName, pubKeyName); err != nil {
+			return err
+		}
+	}
+
+	if args.GetBool(cmd, args.FlagStringDryRun) {
+		plan, err := app.plan(cfg, args.GetName(cmd), privKeyName, pubKeyName,
+			args.GetBool(cmd, args.FlagStringIfDue), options)
+		if err != nil {
+			return err
+		}
 
-		return
+		plan.KeySize = sizeInt
+		plan.ExpiresAt = expiresAt
+
+		if !noWrite && plan.Action != ActionSkipped {
+			files := destination.Paths(privKeyName, pubKeyName)
+			plan.Files = []string{files.PrivateKey, files.PublicKey}
+		}
+
+		// Nothing is streamed to stdout, so the plan always goes there
+		app.PrintTo(cmd, app.Stdout, plan)
+
+		return nil
 	}
 
-	// Generate and rotate the keys
-	privateKey, publicKey, err = app.KeyRotator.Rotate(privKeyName, pubKeyName, int(sizeInt))
+	request, err := app.NewRotationRequest(cmd, args.GetName(cmd), int(sizeInt))
+	if err != nil {
+		return err
+	}
+
+	request.Options = options
 
+	rotated, err := app.RotateKeyPair(cmd, cfg, request)
 	if err != nil {
-		klog.Logf("Error rotating keys: %s\n", err).Error()
+		return err
+	}
 
-		return
+	if rotated.Skipped {
+		app.Print(cmd, Result{
+			Name:                args.GetName(cmd),
+			Action:              ActionSkipped,
+			Status:              rotated.Status,
+			PublicKeyParameter:  pubKeyName,
+			PrivateKeyParameter: privKeyName,
+		})
+
+		return nil
 	}
 
 	rotationResult := &types.Rotation{
-		PublicKey:      publicKey,
-		PrivateKey:     privateKey,
+		PublicKey:      rotated.PublicKey,
+		PrivateKey:     rotated.PrivateKey,
 		PublicKeyName:  pubKeyName,
 		PrivateKeyName: privKeyName,
 	}
 
-	// Save the keys to disk
-	if err = filesystem.WriteAllKeysToFile(rotationResult, app.KeyRotator); err != nil {
-		klog.Logf("Error saving keys to disk: %s\n", err).Error()
+	result := Result{
+		Name:                args.GetName(cmd),
+		Action:              ActionRotated,
+		PublicKeyParameter:  pubKeyName,
+		PrivateKeyParameter: privKeyName,
+		Version:             rotated.Payload.Version,
+		Fingerprint:         rotated.Payload.Fingerprint,
+		PreviousFingerprint: rotated.Payload.PreviousFingerprint,
+		Tier:                options.Tier,
+		ExpiresAt:           expiresAt,
 	}
 
-	fmt.Printf(`
-🔐 Generated and stored keys:
-	
-   💾 Public Key: %s
-   💾 Private Key: %s
-`,
-		pubKeyName,
-		privKeyName,
-	)
+	if rotated.Operation == audit.OperationStore {
+		result.Action = ActionStored
+	}
+
+	if !noWrite {
+		files, err := app.Files.WriteKeyPair(destination, rotationResult)
+		if err != nil {
+			app.Print(cmd, result)
+
+			return fmt.Errorf("keys were stored but could not be written: %w", err)
+		}
+
+		result.PublicKeyFile = files.PublicKey
+		result.PrivateKeyFile = files.PrivateKey
+	}
+
+	app.Print(cmd, result)
+
+	return nil
 }
@@ -0,0 +1,324 @@
+package cmd_rotate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_rotate"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/ssmfake"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+func TestStore(t *testing.T) {
+	expiresAt := apptest.Now.Add(30 * 24 * time.Hour)
+	overdue := apptest.Now.Add(100 * 24 * time.Hour)
+	maxAge := policy.Policy{MaxAge: 90 * 24 * time.Hour}
+
+	tests := []struct {
+		name  string
+		flags []string
+		setup func(t *testing.T, env *apptest.Env)
+
+		kind     types.ErrorKind
+		err      error
+		expected cmd_rotate.Result
+		versions int64
+		policies []string
+	}{
+		{
+			name:  "stores a new key",
+			flags: []string{"--name", "/prod/signing", "--no-write"},
+			expected: cmd_rotate.Result{
+				Action:  cmd_rotate.ActionStored,
+				Version: 1,
+			},
+			versions: 1,
+		},
+		{
+			name:  "rotates an existing key",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			expected: cmd_rotate.Result{
+				Action:         cmd_rotate.ActionRotated,
+				Version:        2,
+				PrivateKeyFile: "/keys/signing_priv.pem",
+				PublicKeyFile:  "/keys/signing_pub.pem",
+			},
+			versions: 2,
+		},
+		{
+			name:  "skips a key that is not due",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--if-due", "--max-age", "90d"},
+			setup: func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			expected: cmd_rotate.Result{
+				Action: cmd_rotate.ActionSkipped,
+				Status: policy.StatusOK,
+			},
+			versions: 1,
+		},
+		{
+			name:  "rotates a key that is overdue",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--if-due", "--max-age", "90d"},
+			setup: func(t *testing.T, env *apptest.Env) {
+				env.StoreKeyPair(t, "/prod/signing")
+				env.Clock.Time = overdue
+			},
+			expected: cmd_rotate.Result{
+				Action:  cmd_rotate.ActionRotated,
+				Version: 2,
+				Tier:    string(ssmtypes.ParameterTierIntelligentTiering),
+			},
+			versions: 2,
+			policies: []string{aws.NoChangeNotificationPolicy(maxAge.MaxAge - maxAge.EffectiveDueWindow())},
+		},
+		{
+			name:  "attaches no policies to a Standard tier key",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--max-age", "90d", "--tier", "Standard"},
+			expected: cmd_rotate.Result{
+				Action:  cmd_rotate.ActionStored,
+				Version: 1,
+				Tier:    string(ssmtypes.ParameterTierStandard),
+			},
+			versions: 1,
+		},
+		{
+			name:  "expires the key",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--expire-after", "30d"},
+			expected: cmd_rotate.Result{
+				Action:    cmd_rotate.ActionStored,
+				Version:   1,
+				Tier:      string(ssmtypes.ParameterTierIntelligentTiering),
+				ExpiresAt: &expiresAt,
+			},
+			versions: 1,
+			policies: []string{
+				aws.ExpirationPolicy(expiresAt),
+				aws.ExpirationNotificationPolicy(policy.Policy{MaxAge: 30 * 24 * time.Hour}.EffectiveDueWindow()),
+			},
+		},
+		{
+			name:  "rejects --expire-after on the Standard tier",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--expire-after", "30d", "--tier", "Standard"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "rejects --no-write with --out-dir",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--out-dir", "/keys"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "rejects a zero --lock-ttl",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--lock", "file", "--lock-dir", "/locks", "--lock-ttl", "0s"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "rejects a negative --lock-ttl",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--lock", "ssm", "--lock-ttl", "-1m"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "ignores --lock-ttl without a lock",
+			flags: []string{"--name", "/prod/signing", "--no-write", "--lock-ttl", "0s"},
+			expected: cmd_rotate.Result{
+				Action:  cmd_rotate.ActionStored,
+				Version: 1,
+			},
+			versions: 1,
+		},
+		{
+			name:  "stores nothing when generation fails",
+			flags: []string{"--name", "/prod/signing", "--no-write"},
+			setup: func(_ *testing.T, env *apptest.Env) { env.Generator.Err = errors.New("no entropy") },
+			err:   errors.New("no entropy"),
+		},
+		{
+			name:  "stores nothing when the files cannot be written",
+			flags: []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			setup: func(_ *testing.T, env *apptest.Env) { env.Files.CheckErr = errors.New("read-only") },
+			err:   errors.New("read-only"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			if test.setup != nil {
+				test.setup(t, env)
+			}
+
+			command := cmd_rotate.RotateCommand{Command: env.Command}
+			err := env.Run(args.MountRotateCommand, command.Run, append(test.flags, "-o", "json")...)
+
+			if test.kind != "" || test.err != nil {
+				if test.kind != "" {
+					assert.Equal(t, test.kind, types.KindOf(err))
+				} else {
+					assert.ErrorContains(t, err, test.err.Error())
+				}
+
+				_, stored := env.SSM.Parameter("/prod/signing_priv.pem")
+				assert.False(t, stored)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var result cmd_rotate.Result
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+
+			test.expected.Name = "/prod/signing"
+			test.expected.PrivateKeyParameter = "/prod/signing_priv.pem"
+			test.expected.PublicKeyParameter = "/prod/signing_pub.pem"
+
+			if result.Action != cmd_rotate.ActionSkipped {
+				assert.NotEmpty(t, result.Fingerprint)
+				test.expected.Fingerprint = result.Fingerprint
+				test.expected.PreviousFingerprint = result.PreviousFingerprint
+			}
+
+			assert.Equal(t, test.expected, result)
+
+			parameter, ok := env.SSM.Parameter("/prod/signing_priv.pem")
+			require.True(t, ok)
+			assert.Equal(t, test.versions, parameter.Latest().Version)
+			assert.Equal(t, ssmtypes.ParameterTypeSecureString, parameter.Type)
+			assert.Equal(t, test.policies, parameter.Policies)
+
+			for _, path := range []string{result.PrivateKeyFile, result.PublicKeyFile} {
+				if path != "" {
+					_, ok := env.Files.Read(path)
+					assert.True(t, ok, path)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreRollsBackWhenParameterStoreFails(t *testing.T) {
+	env := apptest.New(t)
+	env.StoreKeyPair(t, "/prod/signing")
+
+	env.SSM.Inject(ssmfake.Fault{
+		Operation: ssmfake.OperationPutParameter,
+		Name:      "/prod/signing_pub.pem",
+		Err:       &ssmtypes.InternalServerError{},
+		Times:     1,
+	})
+
+	command := cmd_rotate.RotateCommand{Command: env.Command}
+	err := env.Run(args.MountRotateCommand, command.Run, "--name", "/prod/signing", "--no-write")
+	assert.Equal(t, types.ErrorKindBackendUnavailable, types.KindOf(err))
+
+	privateKey, err := env.Command.ParameterStore.GetParameter("/prod/signing_priv.pem")
+	require.NoError(t, err)
+
+	parameter, _ := env.SSM.Parameter("/prod/signing_priv.pem")
+	assert.Equal(t, parameter.Versions[0].Value, privateKey)
+}
+
+func TestStoreDryRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		setup func(t *testing.T, env *apptest.Env)
+
+		kind       types.ErrorKind
+		action     string
+		operations []string
+		files      []string
+	}{
+		{
+			name:       "plans a new key",
+			flags:      []string{"--name", "/prod/signing", "--out-dir", "/keys"},
+			action:     cmd_rotate.ActionStored,
+			operations: []string{cmd_rotate.OperationCreate, cmd_rotate.OperationCreate},
+			files:      []string{"/keys/signing_priv.pem", "/keys/signing_pub.pem"},
+		},
+		{
+			name:       "plans a rotation",
+			flags:      []string{"--name", "/prod/signing", "--no-write", "--tag", "team=payments", "--expire-after", "30d"},
+			setup:      func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			action:     cmd_rotate.ActionRotated,
+			operations: []string{cmd_rotate.OperationOverwrite, cmd_rotate.OperationOverwrite},
+		},
+		{
+			name:   "plans a skipped rotation",
+			flags:  []string{"--name", "/prod/signing", "--no-write", "--if-due", "--max-age", "90d"},
+			setup:  func(t *testing.T, env *apptest.Env) { env.StoreKeyPair(t, "/prod/signing") },
+			action: cmd_rotate.ActionSkipped,
+		},
+		{
+			name:  "validates options",
+			flags: []string{"--name", "/prod/signing", "--tier", "Standard", "--expire-after", "30d"},
+			kind:  types.ErrorKindValidation,
+		},
+		{
+			name:  "checks the key can be read",
+			flags: []string{"--name", "/prod/signing"},
+			setup: func(_ *testing.T, env *apptest.Env) {
+				env.SSM.Inject(ssmfake.Fault{
+					Operation: ssmfake.OperationGetParameter,
+					Err:       &smithy.GenericAPIError{Code: "AccessDeniedException"},
+				})
+			},
+			kind: types.ErrorKindPermissionDenied,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			if test.setup != nil {
+				test.setup(t, env)
+			}
+
+			puts := env.SSM.Calls(ssmfake.OperationPutParameter)
+
+			command := cmd_rotate.RotateCommand{Command: env.Command}
+			err := env.Run(args.MountRotateCommand, command.Run, append(test.flags, "--dry-run", "-o", "json")...)
+
+			assert.Equal(t, puts, env.SSM.Calls(ssmfake.OperationPutParameter))
+			assert.Zero(t, env.Generator.Calls())
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var plan cmd_rotate.Plan
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &plan))
+
+			assert.True(t, plan.DryRun)
+			assert.Equal(t, test.action, plan.Action)
+			assert.Equal(t, test.files, plan.Files)
+
+			var operations []string
+			for _, write := range plan.Writes {
+				assert.Equal(t, ssmtypes.ParameterTypeSecureString, ssmtypes.ParameterType(write.Type))
+				operations = append(operations, write.Operation)
+			}
+
+			assert.Equal(t, test.operations, operations)
+
+			for _, path := range test.files {
+				_, written := env.Files.Read(path)
+				assert.False(t, written, path)
+			}
+		})
+	}
+}
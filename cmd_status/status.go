@@ -0,0 +1,178 @@
+package cmd_status
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/spf13/cobra"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+var statusIcons = map[policy.Status]string{
+	policy.StatusOK:        "✅",
+	policy.StatusDue:       "⏰",
+	policy.StatusOverdue:   "🚨",
+	policy.StatusMissing:   "❓",
+	policy.StatusUnmanaged: "➖",
+}
+
+type StatusCommand struct {
+	app.Command
+}
+
+// Result reports the rotation status of each requested key.
+type Result struct {
+	Keys []KeyStatus `json:"keys" yaml:"keys"`
+}
+
+type KeyStatus struct {
+	Name        string        `json:"name" yaml:"name"`
+	Status      policy.Status `json:"status,omitempty" yaml:"status,omitempty"`
+	LastRotated *time.Time    `json:"last_rotated,omitempty" yaml:"last_rotated,omitempty"`
+	Age         string        `json:"age,omitempty" yaml:"age,omitempty"`
+	MaxAge      string        `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	Error       string        `json:"error,omitempty" yaml:"error,omitempty"`
+
+	// Policies are the parameter policies attached to the private key.
+	Policies []types.ParameterPolicy `json:"parameter_policies,omitempty" yaml:"parameter_policies,omitempty"`
+}
+
+func newKeyStatus(name string, keyPolicy policy.Policy, lastRotated, now time.Time) KeyStatus {
+	status := KeyStatus{
+		Name:   name,
+		Status: keyPolicy.Evaluate(lastRotated, now),
+	}
+
+	if !lastRotated.IsZero() {
+		status.LastRotated = &lastRotated
+		status.Age = policy.FormatDuration(now.Sub(lastRotated))
+	}
+
+	if keyPolicy.Managed() {
+		status.MaxAge = policy.FormatDuration(keyPolicy.MaxAge)
+	}
+
+	return status
+}
+
+func (r Result) Text() string {
+	var text strings.Builder
+
+	text.WriteString("\n🔐 Rotation status:\n\n")
+
+	for _, key := range r.Keys {
+		if key.Error != "" {
+			fmt.Fprintf(&text, "   ⚠️ %s: %s\n", key.Name, key.Error)
+
+			continue
+		}
+
+		fmt.Fprintf(&text, "   %s %s: %s%s\n", statusIcons[key.Status], key.Name, key.Status, key.describe())
+
+		for _, parameterPolicy := range key.Policies {
+			fmt.Fprintf(&text, "      📜 %s (%s)\n", aws.SummarizePolicy(parameterPolicy.Text), parameterPolicy.Status)
+		}
+	}
+
+	return text.String()
+}
+
+func (app StatusCommand) Run(cmd *cobra.Command, _ []string) error {
+	cfg, err := app.Config(cmd)
+	if err != nil {
+		return types.Errorf(types.ErrorKindValidation, "failed to load config: %w", err)
+	}
+
+	if err := cfg.OverrideMaxAge(args.GetMaxAge(cmd)); err != nil {
+		return types.Errorf(types.ErrorKindValidation, "invalid max age: %w", err)
+	}
+
+	names := cfg.KeyNames()
+
+	if args.GetString(cmd, args.FlagStringName) != "" {
+		name, err := app.Name(cmd)
+		if err != nil {
+			return err
+		}
+
+		names = []string{name}
+	}
+
+	if len(names) == 0 {
+		return types.Errorf(types.ErrorKindValidation, "no keys to report on. Pass --%s or list keys in the config file",
+			args.FlagStringName)
+	}
+
+	var errs []error
+
+	now := app.Clock.Now()
+	result := Result{Keys: make([]KeyStatus, 0, len(names))}
+
+	for _, name := range names {
+		if err := aws.ValidateKeyName(name, app.AWSConfig.Region); err != nil {
+			errs = append(errs, types.NewError(types.ErrorKindValidation, err))
+			result.Keys = append(result.Keys, KeyStatus{Name: name, Error: err.Error()})
+
+			continue
+		}
+
+		keyPolicy := cfg.PolicyFor(name)
+
+		lastRotated, err := policy.LastRotated(app.ParameterStore,
+			aws.MakePrivateKeyName(name), aws.MakePublicKeyName(name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read metadata for '%s'. Ensure you have the "+
+				"necessary permissions: %w", name, err))
+			result.Keys = append(result.Keys, KeyStatus{Name: name, Error: err.Error()})
+
+			continue
+		}
+
+		status := newKeyStatus(name, keyPolicy, lastRotated, now)
+
+		if !lastRotated.IsZero() {
+			if status.Policies, err = app.parameterPolicies(name); err != nil {
+				klog.Logf("Unable to read the parameter policies of '%s': %s", name, err).Warn()
+			}
+		}
+
+		result.Keys = append(result.Keys, status)
+	}
+
+	app.Print(cmd, result)
+
+	return errors.Join(errs...)
+}
+
+// parameterPolicies returns the policies attached to the key's private
+// parameter. Stores that cannot report policies report none.
+func (app StatusCommand) parameterPolicies(name string) ([]types.ParameterPolicy, error) {
+	store, ok := app.ParameterStore.(types.ParameterPolicyStore)
+	if !ok {
+		return nil, nil
+	}
+
+	return store.ParameterPolicies(aws.MakePrivateKeyName(name))
+}
+
+func (k KeyStatus) describe() string {
+	if k.Age == "" {
+		return ""
+	}
+
+	description := " (age " + k.Age
+
+	if k.MaxAge != "" {
+		description += ", max age " + k.MaxAge
+	}
+
+	return description + ")"
+}
@@ -0,0 +1,104 @@
+package cmd_status_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/apptest"
+	"github.com/kmesiab/go-key-rotator-cli/args"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_status"
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const statusConfig = `
+max_age: 90d
+keys:
+  - name: /prod/signing
+  - name: /prod/missing
+`
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		age   time.Duration
+
+		kind     types.ErrorKind
+		expected map[string]policy.Status
+	}{
+		{
+			name:     "reports every configured key",
+			expected: map[string]policy.Status{"/prod/signing": policy.StatusOK, "/prod/missing": policy.StatusMissing},
+		},
+		{
+			name:     "reports keys inside the due window",
+			age:      85 * 24 * time.Hour,
+			flags:    []string{"--name", "/prod/signing"},
+			expected: map[string]policy.Status{"/prod/signing": policy.StatusDue},
+		},
+		{
+			name:     "reports overdue keys",
+			age:      91 * 24 * time.Hour,
+			flags:    []string{"--name", "/prod/signing"},
+			expected: map[string]policy.Status{"/prod/signing": policy.StatusOverdue},
+		},
+		{
+			name:     "applies --max-age",
+			age:      91 * 24 * time.Hour,
+			flags:    []string{"--name", "/prod/signing", "--max-age", "180d"},
+			expected: map[string]policy.Status{"/prod/signing": policy.StatusOK},
+		},
+		{
+			name:  "rejects invalid names",
+			flags: []string{"--name", "/aws/signing"},
+			kind:  types.ErrorKindValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := apptest.New(t)
+			env.WriteConfig(t, statusConfig)
+			env.StoreKeyPair(t, "/prod/signing")
+			env.Clock.Time = env.Clock.Time.Add(test.age)
+
+			command := cmd_status.StatusCommand{Command: env.Command}
+			err := env.Run(args.MountStatusCommand, command.Run, append(test.flags, "-o", "json")...)
+
+			if test.kind != "" {
+				assert.Equal(t, test.kind, types.KindOf(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var result cmd_status.Result
+			require.NoError(t, json.Unmarshal(env.Stdout.Bytes(), &result))
+
+			statuses := make(map[string]policy.Status, len(result.Keys))
+			for _, key := range result.Keys {
+				statuses[key.Name] = key.Status
+			}
+
+			assert.Equal(t, test.expected, statuses)
+		})
+	}
+}
+
+func TestStatusRejectsAnInvalidNameTemplate(t *testing.T) {
+	env := apptest.New(t)
+	env.WriteConfig(t, "max_age: 90d\n")
+
+	command := cmd_status.StatusCommand{Command: env.Command}
+	err := env.Run(args.MountStatusCommand, command.Run,
+		"--name", "signing", "--name-template", "/{env}/{team}/{purpose}", "--env", "prod")
+
+	assert.Equal(t, types.ErrorKindValidation, types.KindOf(err))
+	assert.ErrorContains(t, err, "unknown placeholder {team}")
+}
@@ -0,0 +1,289 @@
+// Package config loads the go-rotate YAML configuration file.
+//
+// A minimal configuration applies one rotation policy to every key, and
+// optionally overrides it per key:
+//
+//	max_age: 90d
+//	due_window: 7d
+//	keys:
+//	  - name: payments
+//	    max_age: 30d
+//
+// The daemon command additionally reads a schedule for each key, given as
+// either an interval or a cron expression:
+//
+//	daemon:
+//	  health_addr: ":8080"
+//	keys:
+//	  - name: payments
+//	    size: 4096
+//	    interval: 24h
+//	  - name: auth
+//	    schedule: "0 3 * * 1"
+//
+// Hooks run executables around each rotation performed by store or daemon:
+//
+//	hooks:
+//	  pre_rotate:
+//	    - command: ["/usr/local/bin/drain", "payments"]
+//	  post_rotate:
+//	    - command: ["systemctl", "restart", "payments"]
+//	      timeout: 1m
+//	      rollback_on_failure: true
+//	  on_failure:
+//	    - command: ["/usr/local/bin/page-oncall"]
+//
+// Webhooks receive a signed JSON event after every change to a key:
+//
+//	webhooks:
+//	  - url: https://keys.example.com/events
+//	    secret_env: KEY_EVENTS_SECRET
+//	    events: ["key.rotated"]
+//
+// Key operations are recorded in a hash-chained audit log:
+//
+//	audit:
+//	  file: /var/log/go-rotate/audit.log
+//
+// Profiles hold defaults for the commands run against an environment. The
+// profile named by profile is used unless another is selected:
+//
+//	profile: staging
+//	profiles:
+//	  staging:
+//	    region: us-west-2
+//	    aws_profile: staging
+//	    path_prefix: /staging/
+//	    output: json
+//	  prod:
+//	    backend: ssm
+//	    region: us-east-1
+//	    role_arn: arn:aws:iam::123456789012:role/key-rotator
+//	    external_id: go-rotate
+//	    key_type: rsa
+//	    key_size: 4096
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+)
+
+type Config struct {
+	MaxAge    Duration        `yaml:"max_age"`
+	DueWindow Duration        `yaml:"due_window"`
+	Keys      []KeyConfig     `yaml:"keys"`
+	Daemon    DaemonConfig    `yaml:"daemon"`
+	Hooks     HooksConfig     `yaml:"hooks"`
+	Webhooks  []WebhookConfig `yaml:"webhooks"`
+	Audit     AuditConfig     `yaml:"audit"`
+
+	// Profile names the profile used when none is selected.
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Files lists the config files that were read, in order.
+	Files []string `yaml:"-"`
+}
+
+type KeyConfig struct {
+	Name      string   `yaml:"name"`
+	Size      int      `yaml:"size"`
+	MaxAge    Duration `yaml:"max_age"`
+	DueWindow Duration `yaml:"due_window"`
+	Interval  Duration `yaml:"interval"`
+	Schedule  string   `yaml:"schedule"`
+}
+
+type DaemonConfig struct {
+	HealthAddr string `yaml:"health_addr"`
+}
+
+type AuditConfig struct {
+	File string `yaml:"file"`
+}
+
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+
+	// Secret signs each request. SecretEnv names an environment variable
+	// holding the secret instead, keeping it out of the config file.
+	Secret    string `yaml:"secret"`
+	SecretEnv string `yaml:"secret_env"`
+
+	// Events limits the event types sent to this webhook. Empty means all.
+	Events  []string `yaml:"events"`
+	Timeout Duration `yaml:"timeout"`
+
+	// Retries is the number of additional attempts after a failed
+	// delivery. Nil uses the default.
+	Retries *int `yaml:"retries"`
+}
+
+// SigningSecret returns the secret used to sign requests to the webhook.
+func (w WebhookConfig) SigningSecret() string {
+	if w.SecretEnv != "" {
+		return os.Getenv(w.SecretEnv)
+	}
+
+	return w.Secret
+}
+
+type HooksConfig struct {
+	PreRotate  []HookConfig `yaml:"pre_rotate"`
+	PostRotate []HookConfig `yaml:"post_rotate"`
+	OnFailure  []HookConfig `yaml:"on_failure"`
+}
+
+type HookConfig struct {
+	// Command is the executable followed by its arguments. It is run
+	// directly, not through a shell.
+	Command []string `yaml:"command"`
+	Timeout Duration `yaml:"timeout"`
+
+	// RollbackOnFailure restores the previous key pair when this
+	// post_rotate hook fails. It has no effect on other hooks.
+	RollbackOnFailure bool `yaml:"rollback_on_failure"`
+}
+
+// Duration is a time.Duration that unmarshals from strings accepted by
+// policy.ParseDuration, such as "90d" or "12h".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := policy.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return policy.FormatDuration(time.Duration(d)), nil
+}
+
+// Load reads the configuration file at path. An empty path yields an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	if path == "" {
+		return cfg, nil
+	}
+
+	if err := cfg.read(path); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// read applies the configuration file at path on top of c.
+func (c *Config) read(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	return c.parse(path, data)
+}
+
+// parse applies the config file data read from path.
+func (c *Config) parse(path string, data []byte) error {
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	c.Files = append(c.Files, path)
+
+	return nil
+}
+
+// Key returns the configuration for the named key, or nil if the key is not
+// listed.
+func (c *Config) Key(name string) *KeyConfig {
+	for i := range c.Keys {
+		if c.Keys[i].Name == name {
+			return &c.Keys[i]
+		}
+	}
+
+	return nil
+}
+
+// KeyNames returns the names of every key listed in the configuration.
+func (c *Config) KeyNames() []string {
+	names := make([]string, 0, len(c.Keys))
+
+	for _, key := range c.Keys {
+		names = append(names, key.Name)
+	}
+
+	return names
+}
+
+// ExpandKeyNames replaces the name of every listed key with expand's
+// result, so keys can be named the way --name is and expanded into full
+// key names by the same rule.
+func (c *Config) ExpandKeyNames(expand func(name string) (string, error)) error {
+	for i := range c.Keys {
+		name, err := expand(c.Keys[i].Name)
+		if err != nil {
+			return fmt.Errorf("key '%s': %w", c.Keys[i].Name, err)
+		}
+
+		c.Keys[i].Name = name
+	}
+
+	return nil
+}
+
+// OverrideMaxAge replaces the global and per-key max ages with value, as
+// parsed by policy.ParseDuration. An empty value leaves the config untouched.
+func (c *Config) OverrideMaxAge(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	maxAge, err := policy.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	c.MaxAge = Duration(maxAge)
+
+	for i := range c.Keys {
+		c.Keys[i].MaxAge = 0
+	}
+
+	return nil
+}
+
+// PolicyFor returns the rotation policy for the named key. Per-key settings
+// take precedence over the global ones.
+func (c *Config) PolicyFor(name string) policy.Policy {
+	p := policy.Policy{
+		MaxAge:    time.Duration(c.MaxAge),
+		DueWindow: time.Duration(c.DueWindow),
+	}
+
+	if key := c.Key(name); key != nil {
+		if key.MaxAge > 0 {
+			p.MaxAge = time.Duration(key.MaxAge)
+		}
+
+		if key.DueWindow > 0 {
+			p.DueWindow = time.Duration(key.DueWindow)
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/config"
+)
+
+const day = 24 * time.Hour
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+	return path
+}
+
+func TestLoadEmptyPath(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Keys)
+	assert.False(t, cfg.PolicyFor("anything").Managed())
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := config.Load(filepath.Join(t.TempDir(), "nope.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoadInvalidDuration(t *testing.T) {
+	_, err := config.Load(writeConfig(t, "max_age: soon\n"))
+	assert.Error(t, err)
+}
+
+func TestPolicyFor(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+max_age: 90d
+due_window: 7d
+keys:
+  - name: payments
+    max_age: 30d
+  - name: auth
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"payments", "auth"}, cfg.KeyNames())
+
+	payments := cfg.PolicyFor("payments")
+	assert.Equal(t, 30*day, payments.MaxAge)
+	assert.Equal(t, 7*day, payments.DueWindow)
+
+	assert.Equal(t, 90*day, cfg.PolicyFor("auth").MaxAge)
+	assert.Equal(t, 90*day, cfg.PolicyFor("unlisted").MaxAge)
+}
+
+func TestOverrideMaxAge(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+max_age: 90d
+keys:
+  - name: payments
+    max_age: 30d
+`))
+	require.NoError(t, err)
+
+	require.NoError(t, cfg.OverrideMaxAge("10d"))
+	assert.Equal(t, 10*day, cfg.PolicyFor("payments").MaxAge)
+
+	assert.Error(t, cfg.OverrideMaxAge("later"))
+}
+
+func TestExpandKeyNames(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+keys:
+  - name: payments
+    max_age: 30d
+  - name: auth
+`))
+	require.NoError(t, err)
+
+	require.NoError(t, cfg.ExpandKeyNames(func(name string) (string, error) {
+		return "/staging/" + name, nil
+	}))
+	assert.Equal(t, []string{"/staging/payments", "/staging/auth"}, cfg.KeyNames())
+	assert.Equal(t, 30*day, cfg.PolicyFor("/staging/payments").MaxAge)
+
+	err = cfg.ExpandKeyNames(func(string) (string, error) { return "", errors.New("no {env}") })
+	assert.ErrorContains(t, err, "key '/staging/payments': no {env}")
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	klog "github.com/kmesiab/go-klogger"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProjectFile is read from the working directory.
+	ProjectFile = ".go-rotate.yaml"
+
+	// UserFile is read from the user's config directory.
+	UserFile = "go-rotate/config.yaml"
+)
+
+const (
+	BackendSSM = "ssm"
+	KeyTypeRSA = "rsa"
+)
+
+// RetryModes are the AWS SDK retry modes a profile can select.
+var RetryModes = []string{"standard", "adaptive"}
+
+// Profile holds defaults for a group of commands, such as those run against
+// one environment. Empty fields leave the built-in defaults in place.
+type Profile struct {
+	Backend     string `yaml:"backend"`
+	Region      string `yaml:"region"`
+	AWSProfile  string `yaml:"aws_profile"`
+	RoleARN     string `yaml:"role_arn"`
+	ExternalID  string `yaml:"external_id"`
+	MFASerial   string `yaml:"mfa_serial"`
+	EndpointURL string `yaml:"endpoint_url"`
+	PathPrefix  string `yaml:"path_prefix"`
+
+	// NameTemplate builds key names from Env, Service and the --name, e.g.
+	// /{env}/{service}/{purpose}. It replaces PathPrefix.
+	NameTemplate string `yaml:"name_template"`
+	Env          string `yaml:"env"`
+	Service      string `yaml:"service"`
+
+	KeyType string `yaml:"key_type"`
+	KeySize int    `yaml:"key_size"`
+	Output  string `yaml:"output"`
+
+	// RetryMode, MaxAttempts and Timeout control how AWS calls are retried
+	// and how long an operation may take, e.g. "10s".
+	RetryMode   string        `yaml:"retry_mode"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// account returns only the settings of p that decide which AWS account,
+// region and service see the keys, and with which credentials.
+func (p Profile) account() Profile {
+	return Profile{
+		Region:      p.Region,
+		AWSProfile:  p.AWSProfile,
+		RoleARN:     p.RoleARN,
+		ExternalID:  p.ExternalID,
+		MFASerial:   p.MFASerial,
+		EndpointURL: p.EndpointURL,
+	}
+}
+
+// withAccount returns p with the account settings of from. See account.
+func (p Profile) withAccount(from Profile) Profile {
+	p.Region, p.AWSProfile, p.RoleARN = from.Region, from.AWSProfile, from.RoleARN
+	p.ExternalID, p.MFASerial, p.EndpointURL = from.ExternalID, from.MFASerial, from.EndpointURL
+
+	return p
+}
+
+// Validate returns an error if the profile selects something go-rotate
+// does not support.
+func (p Profile) Validate() error {
+	if p.Backend != "" && p.Backend != BackendSSM {
+		return fmt.Errorf("unsupported backend '%s'. Supported: %s", p.Backend, BackendSSM)
+	}
+
+	if p.KeyType != "" && p.KeyType != KeyTypeRSA {
+		return fmt.Errorf("unsupported key type '%s'. Supported: %s", p.KeyType, KeyTypeRSA)
+	}
+
+	if p.KeySize < 0 {
+		return fmt.Errorf("invalid key size %d", p.KeySize)
+	}
+
+	if p.RetryMode != "" && !slices.Contains(RetryModes, p.RetryMode) {
+		return fmt.Errorf("unsupported retry mode '%s'. Supported: %v", p.RetryMode, RetryModes)
+	}
+
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("invalid max attempts %d", p.MaxAttempts)
+	}
+
+	if p.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %s", p.Timeout)
+	}
+
+	return nil
+}
+
+// DefaultPaths returns the config files read when none is given, in the
+// order they are applied: the user's file, then the project's.
+func DefaultPaths() []string {
+	var paths []string
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config")
+		}
+	}
+
+	if dir != "" {
+		paths = append(paths, filepath.Join(dir, UserFile))
+	}
+
+	return append(paths, ProjectFile)
+}
+
+// Discover loads every default config file that exists. Settings in later
+// files override those in earlier ones. With no files, the configuration
+// is empty. The project file comes with whatever directory go-rotate runs
+// in, so it cannot set everything the user's file can; see readProject.
+func Discover() (*Config, error) {
+	cfg := &Config{}
+
+	for _, path := range DefaultPaths() {
+		read := cfg.read
+		if path == ProjectFile {
+			read = cfg.readProject
+		}
+
+		if err := read(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// ActiveProfile returns the named profile, or the config's default profile
+// when name is empty. With neither, the profile is empty.
+func (c *Config) ActiveProfile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Profile
+	}
+
+	if name == "" {
+		return Profile{}, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile '%s' not found. Profiles: %v", name, c.ProfileNames())
+	}
+
+	if err := profile.Validate(); err != nil {
+		return Profile{}, fmt.Errorf("profile '%s': %w", name, err)
+	}
+
+	return profile, nil
+}
+
+// ProfileNames returns the names of every profile, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// readProject reads the project file like read, but ignores the settings
+// only a file the user chose may set: hooks run programs, webhooks receive
+// key events, the audit log records them, and the default profile and a
+// profile's region, credentials, role and endpoint decide which account and
+// service see the keys. Those the files read before set are kept. Pass the
+// project file with --config to use them.
+func (c *Config) readProject(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var project Config
+
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	hooks, webhooks, auditFile, defaultProfile, profiles := c.Hooks, c.Webhooks, c.Audit.File, c.Profile,
+		maps.Clone(c.Profiles)
+
+	if err := c.parse(path, data); err != nil {
+		return err
+	}
+
+	var ignored []string
+
+	if !reflect.DeepEqual(project.Hooks, HooksConfig{}) {
+		ignored = append(ignored, "hooks")
+		c.Hooks = hooks
+	}
+
+	if project.Webhooks != nil {
+		ignored = append(ignored, "webhooks")
+		c.Webhooks = webhooks
+	}
+
+	if project.Audit.File != "" {
+		ignored = append(ignored, "audit.file")
+		c.Audit.File = auditFile
+	}
+
+	if project.Profile != "" {
+		ignored = append(ignored, "the default profile")
+		c.Profile = defaultProfile
+	}
+
+	// A profile is replaced as a whole, so leaving them out would clear them
+	for _, name := range project.ProfileNames() {
+		if project.Profiles[name].account() != (Profile{}) {
+			ignored = append(ignored, fmt.Sprintf("the AWS account settings of profile '%s'", name))
+		}
+
+		c.Profiles[name] = c.Profiles[name].withAccount(profiles[name])
+	}
+
+	if len(ignored) > 0 {
+		klog.Logf("Ignoring %s in %s. Pass it with --config to use them",
+			strings.Join(ignored, ", "), path).Warn()
+	}
+
+	return nil
+}
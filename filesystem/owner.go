@@ -0,0 +1,80 @@
+package filesystem
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Owner is the user and group a key file is chowned to. An ID of -1 leaves
+// it unchanged.
+type Owner struct {
+	UID int
+	GID int
+}
+
+// ParseOwner parses "user", "user:group" or ":group", where each part is a
+// name or a numeric ID. An empty spec returns nil.
+func ParseOwner(spec string) (*Owner, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	owner := &Owner{UID: -1, GID: -1}
+	userName, groupName, _ := strings.Cut(spec, ":")
+
+	if userName != "" {
+		uid, err := lookupID(userName, func(name string) (string, error) {
+			found, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+
+			return found.Uid, nil
+		})
+		if err != nil {
+			return nil, types.Errorf(types.ErrorKindValidation, "unknown user '%s': %w", userName, err)
+		}
+
+		owner.UID = uid
+	}
+
+	if groupName != "" {
+		gid, err := lookupID(groupName, func(name string) (string, error) {
+			found, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+
+			return found.Gid, nil
+		})
+		if err != nil {
+			return nil, types.Errorf(types.ErrorKindValidation, "unknown group '%s': %w", groupName, err)
+		}
+
+		owner.GID = gid
+	}
+
+	return owner, nil
+}
+
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	id, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("non-numeric ID %s", id)
+	}
+
+	return value, nil
+}
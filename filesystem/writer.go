@@ -0,0 +1,52 @@
+package filesystem
+
+import (
+	"os"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Writer writes key pairs and rendered files. Commands write through it so
+// tests can keep their output off disk.
+type Writer interface {
+	// Check returns an error if the key pair could not be written to
+	// destination. See Destination.Check.
+	Check(destination Destination, privateKeyName, publicKeyName string) error
+
+	// WriteKeyPair writes the key pair to destination.
+	WriteKeyPair(destination Destination, pair *types.Rotation) (KeyPairFiles, error)
+
+	// WriteFile atomically writes data to path. See WriteFile.
+	WriteFile(path string, data []byte, mode os.FileMode, options WriteOptions) error
+}
+
+// Reader reads the files commands take as input, such as templates.
+type Reader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+// FileSystem is what commands read from and write to.
+type FileSystem interface {
+	Reader
+	Writer
+}
+
+// Disk is the FileSystem that reads and writes the local file system, and
+// writes keys to the destination's Stdout.
+type Disk struct{}
+
+func (Disk) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (Disk) Check(destination Destination, privateKeyName, publicKeyName string) error {
+	return destination.Check(privateKeyName, publicKeyName)
+}
+
+func (Disk) WriteKeyPair(destination Destination, pair *types.Rotation) (KeyPairFiles, error) {
+	return destination.WriteKeyPair(pair)
+}
+
+func (Disk) WriteFile(path string, data []byte, mode os.FileMode, options WriteOptions) error {
+	return WriteFile(path, data, mode, options)
+}
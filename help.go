@@ -6,7 +6,7 @@ import (
 )
 
 func runShowHelp(cmd *cobra.Command, args []string) {
-	subCmd, _, err := rootCmd.Find(args)
+	subCmd, _, err := cmd.Root().Find(args)
 
 	if err != nil || subCmd == nil {
 		// Show the primary help message
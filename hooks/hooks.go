@@ -0,0 +1,150 @@
+// Package hooks runs the executables configured to run around a rotation.
+//
+// Each hook receives details of the rotation as environment variables and
+// the PEM encoded public key on stdin. Hooks for an event run in order and
+// stop at the first failure. Hook output is sent to stderr.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	klog "github.com/kmesiab/go-klogger"
+
+	"github.com/kmesiab/go-key-rotator-cli/config"
+)
+
+type Event string
+
+const (
+	EventPreRotate  Event = "pre_rotate"
+	EventPostRotate Event = "post_rotate"
+	EventOnFailure  Event = "on_failure"
+)
+
+// DefaultTimeout bounds hooks that do not configure a timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Environment variables passed to every hook.
+const (
+	EnvEvent               = "GO_ROTATE_EVENT"
+	EnvKeyName             = "GO_ROTATE_KEY_NAME"
+	EnvVersion             = "GO_ROTATE_VERSION"
+	EnvFingerprint         = "GO_ROTATE_FINGERPRINT"
+	EnvPreviousFingerprint = "GO_ROTATE_PREVIOUS_FINGERPRINT"
+	EnvPrivateKeyParameter = "GO_ROTATE_PRIVATE_KEY_PARAMETER"
+	EnvPublicKeyParameter  = "GO_ROTATE_PUBLIC_KEY_PARAMETER"
+	EnvPublicKeyPEM        = "GO_ROTATE_PUBLIC_KEY_PEM"
+	EnvError               = "GO_ROTATE_ERROR"
+)
+
+// Payload describes the rotation a hook is running for. PreviousFingerprint
+// identifies the key pair stored before the rotation. Fingerprint, Version
+// and PublicKeyPEM describe the new key pair in post_rotate hooks; in
+// pre_rotate and on_failure hooks PublicKeyPEM holds the previous public
+// key, if there was one.
+type Payload struct {
+	KeyName             string
+	PrivateKeyName      string
+	PublicKeyName       string
+	Version             int64
+	Fingerprint         string
+	PreviousFingerprint string
+	PublicKeyPEM        []byte
+	Err                 error
+}
+
+// Error reports which hook failed.
+type Error struct {
+	Event Event
+	Hook  config.HookConfig
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s hook %v failed: %s", e.Event, e.Hook.Command, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RollbackRequested reports whether err is a failed hook that asked for the
+// previous key pair to be restored.
+func RollbackRequested(err error) bool {
+	var hookErr *Error
+
+	return errors.As(err, &hookErr) && hookErr.Event == EventPostRotate && hookErr.Hook.RollbackOnFailure
+}
+
+// Run runs the hooks for event in order, stopping at the first failure,
+// which is returned as an *Error.
+func Run(ctx context.Context, event Event, hooks []config.HookConfig, payload Payload) error {
+	for _, hook := range hooks {
+		if err := run(ctx, event, hook, payload); err != nil {
+			return &Error{Event: event, Hook: hook, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func run(ctx context.Context, event Event, hook config.HookConfig, payload Payload) error {
+	if len(hook.Command) == 0 {
+		return errors.New("no command configured")
+	}
+
+	timeout := time.Duration(hook.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	klog.Logf("Running %s hook %v", event, hook.Command).Info()
+
+	// #nosec G204 -- hook commands come from the operator's config file
+	command := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	command.Env = append(os.Environ(), environment(event, payload)...)
+	command.Stdin = bytes.NewReader(payload.PublicKeyPEM)
+	command.Stdout = os.Stderr
+	command.Stderr = os.Stderr
+	command.WaitDelay = time.Second
+
+	err := command.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return err
+}
+
+func environment(event Event, payload Payload) []string {
+	env := []string{
+		EnvEvent + "=" + string(event),
+		EnvKeyName + "=" + payload.KeyName,
+		EnvPrivateKeyParameter + "=" + payload.PrivateKeyName,
+		EnvPublicKeyParameter + "=" + payload.PublicKeyName,
+		EnvFingerprint + "=" + payload.Fingerprint,
+		EnvPreviousFingerprint + "=" + payload.PreviousFingerprint,
+		EnvPublicKeyPEM + "=" + string(payload.PublicKeyPEM),
+	}
+
+	if payload.Version > 0 {
+		env = append(env, EnvVersion+"="+strconv.FormatInt(payload.Version, 10))
+	}
+
+	if payload.Err != nil {
+		env = append(env, EnvError+"="+payload.Err.Error())
+	}
+
+	return env
+}
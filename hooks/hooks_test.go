@@ -0,0 +1,100 @@
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/hooks"
+)
+
+func shell(script string) config.HookConfig {
+	return config.HookConfig{Command: []string{"sh", "-c", script}}
+}
+
+func TestRunPassesPayloadThroughEnvAndStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+
+	hook := shell(`{
+		echo "$GO_ROTATE_EVENT"
+		echo "$GO_ROTATE_KEY_NAME"
+		echo "$GO_ROTATE_VERSION"
+		echo "$GO_ROTATE_FINGERPRINT"
+		echo "$GO_ROTATE_PREVIOUS_FINGERPRINT"
+		cat
+	} > ` + out)
+
+	err := hooks.Run(context.Background(), hooks.EventPostRotate, []config.HookConfig{hook}, hooks.Payload{
+		KeyName:             "payments",
+		Version:             7,
+		Fingerprint:         "SHA256:new",
+		PreviousFingerprint: "SHA256:old",
+		PublicKeyPEM:        []byte("-----BEGIN RSA PUBLIC KEY-----\n"),
+	})
+	require.NoError(t, err)
+
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"post_rotate", "payments", "7", "SHA256:new", "SHA256:old",
+		"-----BEGIN RSA PUBLIC KEY-----", "",
+	}, "\n"), string(written))
+}
+
+func TestRunStopsAtFirstFailure(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+
+	failing := shell("exit 3")
+	err := hooks.Run(context.Background(), hooks.EventPreRotate, []config.HookConfig{
+		failing,
+		shell("touch " + out),
+	}, hooks.Payload{})
+
+	var hookErr *hooks.Error
+
+	require.True(t, errors.As(err, &hookErr))
+	assert.Equal(t, hooks.EventPreRotate, hookErr.Event)
+	assert.Equal(t, failing.Command, hookErr.Hook.Command)
+	assert.NoFileExists(t, out)
+}
+
+func TestRunEnforcesTimeout(t *testing.T) {
+	hook := shell("exec sleep 5")
+	hook.Timeout = config.Duration(50 * time.Millisecond)
+
+	start := time.Now()
+	err := hooks.Run(context.Background(), hooks.EventPostRotate, []config.HookConfig{hook}, hooks.Payload{})
+
+	assert.ErrorContains(t, err, "timed out")
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestRunRejectsEmptyCommand(t *testing.T) {
+	err := hooks.Run(context.Background(), hooks.EventPreRotate, []config.HookConfig{{}}, hooks.Payload{})
+	assert.Error(t, err)
+}
+
+func TestRollbackRequested(t *testing.T) {
+	rollback := shell("exit 1")
+	rollback.RollbackOnFailure = true
+
+	err := hooks.Run(context.Background(), hooks.EventPostRotate, []config.HookConfig{rollback}, hooks.Payload{})
+	assert.True(t, hooks.RollbackRequested(err))
+
+	// Only post_rotate hooks can request a rollback
+	err = hooks.Run(context.Background(), hooks.EventPreRotate, []config.HookConfig{rollback}, hooks.Payload{})
+	assert.False(t, hooks.RollbackRequested(err))
+
+	err = hooks.Run(context.Background(), hooks.EventPostRotate, []config.HookConfig{shell("exit 1")}, hooks.Payload{})
+	assert.False(t, hooks.RollbackRequested(err))
+
+	assert.False(t, hooks.RollbackRequested(errors.New("not a hook")))
+}
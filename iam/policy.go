@@ -0,0 +1,257 @@
+// Package iam builds the least-privilege IAM policy go-rotate's commands
+// need to work with a key pair.
+package iam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+)
+
+// PolicyVersion is the IAM policy language version.
+const PolicyVersion = "2012-10-17"
+
+// Wildcard stands in for an unknown region or account in an ARN.
+const Wildcard = "*"
+
+// Policy is an IAM policy document.
+type Policy struct {
+	Version   string      `json:"Version" yaml:"Version"`
+	Statement []Statement `json:"Statement" yaml:"Statement"`
+}
+
+// Statement is a single IAM policy statement.
+type Statement struct {
+	Sid       string                       `json:"Sid" yaml:"Sid"`
+	Effect    string                       `json:"Effect" yaml:"Effect"`
+	Action    []string                     `json:"Action" yaml:"Action"`
+	Resource  []string                     `json:"Resource" yaml:"Resource"`
+	Condition map[string]map[string]string `json:"Condition,omitempty" yaml:"Condition,omitempty"`
+}
+
+// Access is what a command does with a key pair's parameters.
+type Access struct {
+	// Read reads the parameters, and Decrypt their values.
+	Read    bool
+	Decrypt bool
+
+	// Write stores new values, Delete removes both halves when storing a
+	// new pair is rolled back, and Tag applies --tag.
+	Write  bool
+	Delete bool
+	Tag    bool
+
+	// Describe lists parameter policies.
+	Describe bool
+
+	// Lock holds the --lock ssm lock parameter.
+	Lock bool
+
+	// Probe writes, reads and deletes the scratch parameter that checks
+	// --kms-key-id.
+	Probe bool
+}
+
+// Commands maps every command that calls AWS to the access it needs.
+var Commands = map[string]Access{
+	"daemon": {Read: true, Decrypt: true, Write: true, Delete: true, Tag: true, Lock: true},
+	"doctor": {Read: true, Decrypt: true, Write: true, Delete: true, Lock: true, Probe: true},
+	"exec":   {Read: true, Decrypt: true},
+	"fetch":  {Read: true, Decrypt: true},
+	"render": {Read: true, Decrypt: true},
+	"status": {Read: true, Describe: true},
+	"store":  {Read: true, Decrypt: true, Write: true, Delete: true, Tag: true, Lock: true},
+}
+
+// CommandNames returns the names of the commands in Commands, sorted.
+func CommandNames() []string {
+	names := make([]string, 0, len(Commands))
+	for name := range Commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Options describe the key pair and the commands a policy is for.
+type Options struct {
+	// Name is the key name, without the _priv.pem and _pub.pem suffixes.
+	Name string
+
+	// Commands are names from Commands. Empty means all of them.
+	Commands []string
+
+	// Region and Account scope the ARNs. Empty means any.
+	Region  string
+	Account string
+
+	// KeyID is the KMS key ID, ARN or alias the parameters are encrypted
+	// with. Empty means the AWS managed aws/ssm key, whose key policy
+	// already allows the account to use it through Parameter Store.
+	KeyID string
+
+	// Lock is set when store, daemon or doctor uses --lock ssm.
+	Lock bool
+}
+
+// NewPolicy returns the policy that allows exactly what the commands in
+// opts do with the key pair.
+func NewPolicy(opts Options) (Policy, error) {
+	access, err := combine(opts.Commands)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	region := orWildcard(opts.Region)
+	account := orWildcard(opts.Account)
+
+	keyPair := []string{
+		ParameterARN(region, account, aws.MakePrivateKeyName(opts.Name)),
+		ParameterARN(region, account, aws.MakePublicKeyName(opts.Name)),
+	}
+
+	policy := Policy{Version: PolicyVersion}
+
+	if access.Read {
+		policy.Statement = append(policy.Statement, allow("ReadKeyPair", []string{"ssm:GetParameter"}, keyPair))
+	}
+
+	if access.Write {
+		actions := []string{"ssm:PutParameter"}
+
+		if access.Delete {
+			actions = append(actions, "ssm:DeleteParameter")
+		}
+
+		if access.Tag {
+			actions = append(actions, "ssm:AddTagsToResource")
+		}
+
+		policy.Statement = append(policy.Statement, allow("WriteKeyPair", actions, keyPair))
+	}
+
+	if access.Describe {
+		// DescribeParameters cannot be limited to particular parameters
+		policy.Statement = append(policy.Statement,
+			allow("DescribeParameters", []string{"ssm:DescribeParameters"}, []string{Wildcard}))
+	}
+
+	if access.Lock && opts.Lock {
+		lockName := opts.Name + lock.ParameterNameSuffix
+
+		policy.Statement = append(policy.Statement, allow("HoldRotationLock",
+			[]string{"ssm:GetParameter", "ssm:PutParameter", "ssm:DeleteParameter"},
+			[]string{ParameterARN(region, account, lockName)}))
+	}
+
+	if access.Probe && opts.KeyID != "" {
+		policy.Statement = append(policy.Statement, allow("ProbeKMSKey",
+			[]string{"ssm:GetParameter", "ssm:PutParameter", "ssm:DeleteParameter"},
+			[]string{ParameterARN(region, account, aws.MakeProbeName(opts.Name))}))
+	}
+
+	if statement, ok := kmsStatement(opts.KeyID, region, account, access); ok {
+		policy.Statement = append(policy.Statement, statement)
+	}
+
+	return policy, nil
+}
+
+// ParameterARN returns the ARN of the named parameter. Names without a
+// leading slash are separated from "parameter" by one.
+func ParameterARN(region, account, name string) string {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+
+	return fmt.Sprintf("arn:aws:ssm:%s:%s:parameter%s", region, account, name)
+}
+
+// combine returns the access needed by all of the named commands.
+func combine(commands []string) (Access, error) {
+	if len(commands) == 0 {
+		commands = CommandNames()
+	}
+
+	var combined Access
+
+	for _, name := range commands {
+		access, ok := Commands[name]
+		if !ok {
+			return Access{}, fmt.Errorf("unknown command '%s'. Known commands are %s",
+				name, strings.Join(CommandNames(), ", "))
+		}
+
+		combined.Read = combined.Read || access.Read
+		combined.Decrypt = combined.Decrypt || access.Decrypt
+		combined.Write = combined.Write || access.Write
+		combined.Delete = combined.Delete || access.Delete
+		combined.Tag = combined.Tag || access.Tag
+		combined.Describe = combined.Describe || access.Describe
+		combined.Lock = combined.Lock || access.Lock
+		combined.Probe = combined.Probe || access.Probe
+	}
+
+	return combined, nil
+}
+
+// kmsStatement allows using a customer managed KMS key, and only through
+// Parameter Store. Keys named by alias are matched by their alias, since
+// Parameter Store calls KMS with the key's ARN.
+func kmsStatement(keyID, region, account string, access Access) (Statement, bool) {
+	var actions []string
+
+	if access.Decrypt {
+		actions = append(actions, "kms:Decrypt")
+	}
+
+	if access.Write {
+		actions = append(actions, "kms:Encrypt", "kms:GenerateDataKey")
+	}
+
+	if keyID == "" || len(actions) == 0 {
+		return Statement{}, false
+	}
+
+	statement := allow("UseKMSKey", actions, nil)
+	statement.Condition = map[string]map[string]string{}
+
+	if region == Wildcard {
+		statement.Condition["StringLike"] = map[string]string{"kms:ViaService": "ssm.*.amazonaws.com"}
+	} else {
+		statement.Condition["StringEquals"] = map[string]string{"kms:ViaService": "ssm." + region + ".amazonaws.com"}
+	}
+
+	switch {
+	case strings.HasPrefix(keyID, "arn:") && strings.Contains(keyID, ":alias/"):
+		keyID = keyID[strings.Index(keyID, ":alias/")+1:]
+
+		fallthrough
+	case strings.HasPrefix(keyID, "alias/"):
+		statement.Resource = []string{Wildcard}
+		statement.Condition["ForAnyValue:StringEquals"] = map[string]string{"kms:ResourceAliases": keyID}
+	case strings.HasPrefix(keyID, "arn:"):
+		statement.Resource = []string{keyID}
+	default:
+		statement.Resource = []string{fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", region, account, keyID)}
+	}
+
+	return statement, true
+}
+
+func allow(sid string, actions, resources []string) Statement {
+	return Statement{Sid: sid, Effect: "Allow", Action: actions, Resource: resources}
+}
+
+func orWildcard(value string) string {
+	if value == "" {
+		return Wildcard
+	}
+
+	return value
+}
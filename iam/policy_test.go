@@ -0,0 +1,146 @@
+package iam_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/iam"
+)
+
+const (
+	privateKeyARN = "arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_priv.pem"
+	publicKeyARN  = "arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_pub.pem"
+)
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		options iam.Options
+
+		err      bool
+		expected []iam.Statement
+	}{
+		{
+			name:    "reads for fetch",
+			options: iam.Options{Commands: []string{"fetch"}},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+			},
+		},
+		{
+			name:    "writes, tags and locks for store",
+			options: iam.Options{Commands: []string{"store"}, Lock: true},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "WriteKeyPair", Effect: "Allow",
+					Action:   []string{"ssm:PutParameter", "ssm:DeleteParameter", "ssm:AddTagsToResource"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "HoldRotationLock", Effect: "Allow",
+					Action:   []string{"ssm:GetParameter", "ssm:PutParameter", "ssm:DeleteParameter"},
+					Resource: []string{"arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_lock"}},
+			},
+		},
+		{
+			name:    "writes and tags for daemon",
+			options: iam.Options{Commands: []string{"daemon"}},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "WriteKeyPair", Effect: "Allow",
+					Action:   []string{"ssm:PutParameter", "ssm:DeleteParameter", "ssm:AddTagsToResource"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+			},
+		},
+		{
+			name:    "describes for status",
+			options: iam.Options{Commands: []string{"status"}},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "DescribeParameters", Effect: "Allow", Action: []string{"ssm:DescribeParameters"},
+					Resource: []string{"*"}},
+			},
+		},
+		{
+			name:    "decrypts with a KMS key ID",
+			options: iam.Options{Commands: []string{"fetch"}, KeyID: "1234abcd"},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "UseKMSKey", Effect: "Allow", Action: []string{"kms:Decrypt"},
+					Resource: []string{"arn:aws:kms:us-east-1:123456789012:key/1234abcd"},
+					Condition: map[string]map[string]string{
+						"StringEquals": {"kms:ViaService": "ssm.us-east-1.amazonaws.com"},
+					}},
+			},
+		},
+		{
+			name:    "matches a KMS alias",
+			options: iam.Options{Commands: []string{"daemon"}, KeyID: "alias/keys"},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "WriteKeyPair", Effect: "Allow",
+					Action:   []string{"ssm:PutParameter", "ssm:DeleteParameter", "ssm:AddTagsToResource"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "UseKMSKey", Effect: "Allow", Action: []string{"kms:Decrypt", "kms:Encrypt", "kms:GenerateDataKey"},
+					Resource: []string{"*"},
+					Condition: map[string]map[string]string{
+						"StringEquals":             {"kms:ViaService": "ssm.us-east-1.amazonaws.com"},
+						"ForAnyValue:StringEquals": {"kms:ResourceAliases": "alias/keys"},
+					}},
+			},
+		},
+		{
+			name:    "probes the KMS key for doctor",
+			options: iam.Options{Commands: []string{"doctor"}, KeyID: "1234abcd"},
+			expected: []iam.Statement{
+				{Sid: "ReadKeyPair", Effect: "Allow", Action: []string{"ssm:GetParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "WriteKeyPair", Effect: "Allow", Action: []string{"ssm:PutParameter", "ssm:DeleteParameter"},
+					Resource: []string{privateKeyARN, publicKeyARN}},
+				{Sid: "ProbeKMSKey", Effect: "Allow",
+					Action:   []string{"ssm:GetParameter", "ssm:PutParameter", "ssm:DeleteParameter"},
+					Resource: []string{"arn:aws:ssm:us-east-1:123456789012:parameter/prod/signing_probe"}},
+				{Sid: "UseKMSKey", Effect: "Allow", Action: []string{"kms:Decrypt", "kms:Encrypt", "kms:GenerateDataKey"},
+					Resource: []string{"arn:aws:kms:us-east-1:123456789012:key/1234abcd"},
+					Condition: map[string]map[string]string{
+						"StringEquals": {"kms:ViaService": "ssm.us-east-1.amazonaws.com"},
+					}},
+			},
+		},
+		{
+			name:    "rejects unknown commands",
+			options: iam.Options{Commands: []string{"generate"}},
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.options.Name = "/prod/signing"
+			test.options.Region = "us-east-1"
+			test.options.Account = "123456789012"
+
+			policy, err := iam.NewPolicy(test.options)
+			if test.err {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, iam.PolicyVersion, policy.Version)
+			assert.Equal(t, test.expected, policy.Statement)
+		})
+	}
+}
+
+func TestParameterARN(t *testing.T) {
+	assert.Equal(t, "arn:aws:ssm:*:*:parameter/signing_priv.pem", iam.ParameterARN("*", "*", "signing_priv.pem"))
+	assert.Equal(t, privateKeyARN, iam.ParameterARN("us-east-1", "123456789012", "/prod/signing_priv.pem"))
+}
@@ -0,0 +1,92 @@
+// Package identity names the actor performing an operation, for event
+// notifications and records.
+package identity
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Local returns the local user and host as "user@host".
+func Local() string {
+	name := "unknown"
+
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+
+	return name + "@" + host
+}
+
+// stsTimeout bounds the caller identity lookup so auditing never hangs a
+// command.
+const stsTimeout = 5 * time.Second
+
+// Caller is the AWS identity a set of credentials belongs to.
+type Caller struct {
+	Account string `json:"account" yaml:"account"`
+	ARN     string `json:"arn" yaml:"arn"`
+	UserID  string `json:"user_id" yaml:"user_id"`
+}
+
+// Resolver looks up the AWS caller identity.
+type Resolver interface {
+	Caller(ctx context.Context) (Caller, error)
+}
+
+// STS resolves the caller identity of Config with sts:GetCallerIdentity,
+// which needs no IAM permissions.
+type STS struct {
+	Config aws.Config
+}
+
+func (s STS) Caller(ctx context.Context) (Caller, error) {
+	ctx, cancel := context.WithTimeout(ctx, stsTimeout)
+	defer cancel()
+
+	output, err := sts.NewFromConfig(s.Config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return Caller{}, err
+	}
+
+	return Caller{
+		Account: aws.ToString(output.Account),
+		ARN:     aws.ToString(output.Arn),
+		UserID:  aws.ToString(output.UserId),
+	}, nil
+}
+
+// Once resolves the caller the first time it is asked and returns the same
+// caller, or error, every time after. Commands look up their caller through
+// one, so a run makes at most one STS call however many records it writes.
+type Once struct {
+	resolver Resolver
+
+	once   sync.Once
+	caller Caller
+	err    error
+}
+
+// NewOnce returns a Once that resolves the caller with resolver.
+func NewOnce(resolver Resolver) *Once {
+	return &Once{resolver: resolver}
+}
+
+func (o *Once) Caller(ctx context.Context) (Caller, error) {
+	o.once.Do(func() {
+		o.caller, o.err = o.resolver.Caller(ctx)
+	})
+
+	return o.caller, o.err
+}
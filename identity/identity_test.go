@@ -0,0 +1,54 @@
+package identity_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kmesiab/go-key-rotator-cli/identity"
+)
+
+// countingResolver reports caller, or err, and counts its lookups.
+type countingResolver struct {
+	caller identity.Caller
+	err    error
+	calls  int
+}
+
+func (r *countingResolver) Caller(context.Context) (identity.Caller, error) {
+	r.calls++
+
+	return r.caller, r.err
+}
+
+func TestOnceResolvesTheCallerOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver *countingResolver
+	}{
+		{
+			name:     "reuses the caller",
+			resolver: &countingResolver{caller: identity.Caller{Account: "123456789012", ARN: "arn:aws:iam::123456789012:user/alice"}},
+		},
+		{
+			name:     "reuses the error",
+			resolver: &countingResolver{err: errors.New("no credentials")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			once := identity.NewOnce(test.resolver)
+
+			for i := 0; i < 3; i++ {
+				caller, err := once.Caller(context.Background())
+				assert.Equal(t, test.resolver.caller, caller)
+				assert.Equal(t, test.resolver.err, err)
+			}
+
+			assert.Equal(t, 1, test.resolver.calls)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package lock
+
+// SetTestHookExpiredRead sets the function FileLocker calls once it has read
+// an expired lease, until the test ends.
+func SetTestHookExpiredRead(cleanup func(func()), hook func()) {
+	testHookExpiredRead = hook
+
+	cleanup(func() { testHookExpiredRead = nil })
+}
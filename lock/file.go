@@ -0,0 +1,203 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileLocker keeps leases as files in Dir. It only excludes rotations
+// running on the same machine, or sharing Dir over a network file system.
+type FileLocker struct {
+	Dir   string
+	TTL   time.Duration
+	Owner string
+}
+
+func NewFileLocker(dir string, ttl time.Duration) *FileLocker {
+	return &FileLocker{Dir: dir, TTL: ttl, Owner: DefaultOwner()}
+}
+
+func (l *FileLocker) Acquire(name string) (Lock, error) {
+	path := filepath.Join(l.Dir, strings.ReplaceAll(strings.Trim(name, "/"), "/", "_")+".lock")
+
+	lease, err := newLease(l.Owner, l.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := lease.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = createExclusive(path, value)
+
+	if errors.Is(err, os.ErrExist) {
+		err = l.takeOverExpired(path, value, lease.Token)
+	}
+
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: '%s' was taken by another rotation", ErrLocked, name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileLock{path: path, ttl: l.TTL, lease: lease}, nil
+}
+
+// takeOverExpired replaces the lock file at path with value if its lease
+// has expired. The expired file is first renamed to a name only this
+// rotation uses, so of two rotations taking it over, only one moves the
+// lease they both read. A rotation that moved a newer lease instead puts
+// it back and fails with ErrLocked. Creating the new file fails if another
+// rotation got there first.
+//
+// While a newer lease is moved aside, its holder cannot renew or release
+// it, and a third rotation may create its own in its place, in which case
+// the newer lease is lost; its holder finds out when it next renews or
+// releases the lock.
+func (l *FileLocker) takeOverExpired(path, value, token string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Released since we tried to create it
+		return createExclusive(path, value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	holder, err := decodeLease(string(data))
+	if err == nil && !holder.expired() {
+		return lockedError(path, holder)
+	}
+
+	if testHookExpiredRead != nil {
+		testHookExpiredRead()
+	}
+
+	expired := path + ".expired." + token
+
+	err = os.Rename(path, expired)
+	if errors.Is(err, os.ErrNotExist) {
+		return createExclusive(path, value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	moved, err := os.ReadFile(expired)
+	if err != nil {
+		return err
+	}
+
+	if string(moved) != string(data) {
+		// Another rotation took the lease over since we read it
+		restoreErr := os.Link(expired, path)
+		_ = os.Remove(expired)
+
+		if restoreErr != nil {
+			return fmt.Errorf("error restoring lock %s: %w", path, restoreErr)
+		}
+
+		return fmt.Errorf("%w: '%s' was taken by another rotation", ErrLocked, path)
+	}
+
+	if err := os.Remove(expired); err != nil {
+		return err
+	}
+
+	return createExclusive(path, value)
+}
+
+// testHookExpiredRead is called once takeOverExpired has read an expired
+// lease, so tests can race another rotation against it.
+var testHookExpiredRead func()
+
+func createExclusive(path, value string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(value); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+type fileLock struct {
+	path  string
+	ttl   time.Duration
+	lease Lease
+}
+
+// Renew rewrites the lock file with the lease extended by the locker's
+// TTL, unless the lease expired and was taken over. The file is replaced
+// by a rename so other rotations never read a partly written lease.
+func (l *fileLock) Renew() error {
+	if err := l.verify(); err != nil {
+		return err
+	}
+
+	lease := l.lease.renewed(l.ttl)
+
+	value, err := lease.encode()
+	if err != nil {
+		return err
+	}
+
+	temporary := l.path + "." + lease.Token
+	if err := createExclusive(temporary, value); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temporary, l.path); err != nil {
+		_ = os.Remove(temporary)
+
+		return err
+	}
+
+	l.lease = lease
+
+	return nil
+}
+
+// Release removes the lock file, unless the lease expired and was taken
+// over by another holder in the meantime.
+func (l *fileLock) Release() error {
+	if err := l.verify(); err != nil {
+		return err
+	}
+
+	return os.Remove(l.path)
+}
+
+// verify checks that the lock file still holds this lease.
+func (l *fileLock) verify() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+
+	holder, err := decodeLease(string(data))
+	if err != nil {
+		return err
+	}
+
+	if holder.Token != l.lease.Token {
+		return lockedError(l.path, holder)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+// Package lock prevents two rotations of the same key from running at once.
+//
+// Rotating a key writes the private and public halves in two separate
+// calls, so concurrent rotations can interleave and leave a mismatched
+// pair behind. A Locker hands out a lease on a key name for a limited TTL;
+// a lease whose holder crashed simply expires. Two Lockers are provided:
+// ParameterStoreLocker keeps the lease in Parameter Store next to the key,
+// which works across machines, and FileLocker keeps it on the local disk.
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by Acquire when another holder owns the lease.
+var ErrLocked = errors.New("lock is held by another rotation")
+
+// DefaultPollInterval is how often AcquireWait retries a held lock.
+const DefaultPollInterval = time.Second
+
+type Locker interface {
+	// Acquire takes the lease on name, failing fast with ErrLocked if it is
+	// held by someone else.
+	Acquire(name string) (Lock, error)
+}
+
+type Lock interface {
+	// Renew extends the lease by the locker's TTL from now, failing with
+	// ErrLocked if it expired and was taken over in the meantime.
+	Renew() error
+	Release() error
+}
+
+// Lease is the record stored by a Locker while a lock is held.
+type Lease struct {
+	Owner     string    `json:"owner"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcquireWait calls Acquire until it succeeds, fails with an error other
+// than ErrLocked, or wait has elapsed. A zero wait fails fast.
+func AcquireWait(locker Locker, name string, wait time.Duration) (Lock, error) {
+	deadline := time.Now().Add(wait)
+
+	for {
+		held, err := locker.Acquire(name)
+		if !errors.Is(err, ErrLocked) {
+			return held, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, err
+		}
+
+		time.Sleep(min(DefaultPollInterval, remaining))
+	}
+}
+
+// KeepAlive renews held a third of ttl apart until the returned function
+// is called, so a lease outlives work that takes longer than its TTL. The
+// returned function stops renewing and reports the first failed renewal.
+func KeepAlive(held Lock, ttl time.Duration) func() error {
+	interval := ttl / 3
+	if interval <= 0 {
+		return func() error { return nil }
+	}
+
+	var (
+		done    = make(chan struct{})
+		stopped = make(chan error, 1)
+	)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				stopped <- nil
+
+				return
+			case <-ticker.C:
+				if err := held.Renew(); err != nil {
+					<-done
+					stopped <- err
+
+					return
+				}
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+
+		return <-stopped
+	}
+}
+
+// DefaultOwner identifies this process as hostname:pid.
+func DefaultOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+func newLease(owner string, ttl time.Duration) (Lease, error) {
+	token := make([]byte, 16)
+
+	if _, err := rand.Read(token); err != nil {
+		return Lease{}, err
+	}
+
+	return Lease{
+		Owner:     owner,
+		Token:     hex.EncodeToString(token),
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+	}, nil
+}
+
+// renewed returns the lease extended to ttl from now.
+func (l Lease) renewed(ttl time.Duration) Lease {
+	l.ExpiresAt = time.Now().Add(ttl).UTC()
+
+	return l
+}
+
+func (l Lease) expired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
+
+func (l Lease) encode() (string, error) {
+	data, err := json.Marshal(l)
+
+	return string(data), err
+}
+
+func decodeLease(data string) (Lease, error) {
+	var lease Lease
+
+	err := json.Unmarshal([]byte(data), &lease)
+
+	return lease, err
+}
+
+func lockedError(name string, holder Lease) error {
+	return fmt.Errorf("%w: '%s' is held by %s until %s",
+		ErrLocked, name, holder.Owner, holder.ExpiresAt.Format(time.RFC3339))
+}
+
+// NoopLocker hands out locks without excluding anyone.
+type NoopLocker struct{}
+
+func (NoopLocker) Acquire(string) (Lock, error) {
+	return noopLock{}, nil
+}
+
+type noopLock struct{}
+
+func (noopLock) Renew() error {
+	return nil
+}
+
+func (noopLock) Release() error {
+	return nil
+}
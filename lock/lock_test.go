@@ -0,0 +1,319 @@
+package lock_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/lock"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// memoryStore is a minimal in-memory types.ParameterStoreInterface.
+type memoryStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[string]string)}
+}
+
+func (s *memoryStore) GetParameter(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[name]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	}
+
+	return value, nil
+}
+
+func (s *memoryStore) PutParameter(name, value, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[name] = value
+
+	return nil
+}
+
+func (s *memoryStore) CreateParameter(name, value, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[name]; ok {
+		return fmt.Errorf("%s: %w", name, types.ErrParameterAlreadyExists)
+	}
+
+	s.values[name] = value
+
+	return nil
+}
+
+func (s *memoryStore) DeleteParameter(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, name)
+
+	return nil
+}
+
+func (s *memoryStore) DescribeParameter(name string) (*types.ParameterMetadata, error) {
+	return nil, fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+}
+
+func lockers(t *testing.T, ttl time.Duration) map[string]func(owner string) lock.Locker {
+	dir := t.TempDir()
+	store := newMemoryStore()
+
+	return map[string]func(owner string) lock.Locker{
+		"file": func(owner string) lock.Locker {
+			return &lock.FileLocker{Dir: dir, TTL: ttl, Owner: owner}
+		},
+		"ssm": func(owner string) lock.Locker {
+			return &lock.ParameterStoreLocker{Store: store, TTL: ttl, Owner: owner}
+		},
+	}
+}
+
+func TestLockerExcludesConcurrentHolders(t *testing.T) {
+	for name, newLocker := range lockers(t, time.Minute) {
+		t.Run(name, func(t *testing.T) {
+			first, err := newLocker("ci-job-1").Acquire("team/key")
+			require.NoError(t, err)
+
+			_, err = newLocker("ci-job-2").Acquire("team/key")
+			assert.ErrorIs(t, err, lock.ErrLocked)
+
+			// Other keys are unaffected
+			other, err := newLocker("ci-job-2").Acquire("team/other")
+			require.NoError(t, err)
+			require.NoError(t, other.Release())
+
+			require.NoError(t, first.Release())
+
+			second, err := newLocker("ci-job-2").Acquire("team/key")
+			require.NoError(t, err)
+			require.NoError(t, second.Release())
+		})
+	}
+}
+
+func TestLockerTakesOverExpiredLeases(t *testing.T) {
+	for name, newLocker := range lockers(t, -time.Second) {
+		t.Run(name, func(t *testing.T) {
+			crashed, err := newLocker("crashed").Acquire("key")
+			require.NoError(t, err)
+
+			taken, err := newLocker("next").Acquire("key")
+			require.NoError(t, err)
+
+			// The crashed holder must not release a lease it no longer owns
+			assert.ErrorIs(t, crashed.Release(), lock.ErrLocked)
+			require.NoError(t, taken.Release())
+		})
+	}
+}
+
+// racingStore lets another rotation take the lock between deleting an
+// expired lease and creating a new one.
+type racingStore struct {
+	*memoryStore
+	afterDelete func()
+}
+
+func (s *racingStore) DeleteParameter(name string) error {
+	err := s.memoryStore.DeleteParameter(name)
+
+	if s.afterDelete != nil {
+		s.afterDelete()
+		s.afterDelete = nil
+	}
+
+	return err
+}
+
+func TestParameterStoreLockerLosesTakeoverRaces(t *testing.T) {
+	store := &racingStore{memoryStore: newMemoryStore()}
+
+	_, err := (&lock.ParameterStoreLocker{Store: store, TTL: -time.Second, Owner: "crashed"}).Acquire("key")
+	require.NoError(t, err)
+
+	winner := &lock.ParameterStoreLocker{Store: store, TTL: time.Minute, Owner: "winner"}
+
+	var won lock.Lock
+
+	store.afterDelete = func() {
+		won, err = winner.Acquire("key")
+		require.NoError(t, err)
+	}
+
+	_, err = (&lock.ParameterStoreLocker{Store: store, TTL: time.Minute, Owner: "loser"}).Acquire("key")
+	assert.ErrorIs(t, err, lock.ErrLocked)
+
+	require.NotNil(t, won)
+	require.NoError(t, won.Release())
+}
+
+// pausingStore runs afterRead once this rotation has read the lease it is
+// about to take over.
+type pausingStore struct {
+	*memoryStore
+	afterRead func()
+}
+
+func (s *pausingStore) GetParameter(name string) (string, error) {
+	value, err := s.memoryStore.GetParameter(name)
+
+	if s.afterRead != nil {
+		s.afterRead()
+		s.afterRead = nil
+	}
+
+	return value, err
+}
+
+func TestParameterStoreLockerTakesOverAnExpiredLeaseOnce(t *testing.T) {
+	store := newMemoryStore()
+
+	_, err := (&lock.ParameterStoreLocker{Store: store, TTL: -time.Second, Owner: "crashed"}).Acquire("key")
+	require.NoError(t, err)
+
+	// Both read the expired lease, then the first takes it over
+	var first lock.Lock
+
+	second := &pausingStore{memoryStore: store, afterRead: func() {
+		first, err = (&lock.ParameterStoreLocker{Store: store, TTL: time.Minute, Owner: "first"}).Acquire("key")
+		require.NoError(t, err)
+	}}
+
+	_, err = (&lock.ParameterStoreLocker{Store: second, TTL: time.Minute, Owner: "second"}).Acquire("key")
+	assert.ErrorIs(t, err, lock.ErrLocked)
+
+	// The first lease was not deleted from under its holder
+	require.NotNil(t, first)
+	require.NoError(t, first.Renew())
+	require.NoError(t, first.Release())
+}
+
+func TestFileLockerTakesOverAnExpiredLeaseOnce(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := (&lock.FileLocker{Dir: dir, TTL: -time.Second, Owner: "crashed"}).Acquire("key")
+	require.NoError(t, err)
+
+	// Both read the expired lease, then the first takes it over
+	var first lock.Lock
+
+	lock.SetTestHookExpiredRead(t.Cleanup, func() {
+		lock.SetTestHookExpiredRead(t.Cleanup, nil)
+
+		first, err = (&lock.FileLocker{Dir: dir, TTL: time.Minute, Owner: "first"}).Acquire("key")
+		require.NoError(t, err)
+	})
+
+	_, err = (&lock.FileLocker{Dir: dir, TTL: time.Minute, Owner: "second"}).Acquire("key")
+	assert.ErrorIs(t, err, lock.ErrLocked)
+
+	// The first lease was put back for its holder
+	require.NotNil(t, first)
+	require.NoError(t, first.Renew())
+	require.NoError(t, first.Release())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no lease is left behind")
+}
+
+func TestLockerRenewsLeases(t *testing.T) {
+	const ttl = 150 * time.Millisecond
+
+	for name, newLocker := range lockers(t, ttl) {
+		t.Run(name, func(t *testing.T) {
+			held, err := newLocker("slow-hook").Acquire("key")
+			require.NoError(t, err)
+
+			stop := lock.KeepAlive(held, ttl)
+
+			// Still held well past the original TTL
+			time.Sleep(3 * ttl)
+
+			_, err = newLocker("next").Acquire("key")
+			assert.ErrorIs(t, err, lock.ErrLocked)
+
+			require.NoError(t, stop())
+			require.NoError(t, held.Release())
+		})
+	}
+}
+
+func TestRenewFailsOnceTakenOver(t *testing.T) {
+	for name, newLocker := range lockers(t, -time.Second) {
+		t.Run(name, func(t *testing.T) {
+			crashed, err := newLocker("crashed").Acquire("key")
+			require.NoError(t, err)
+
+			taken, err := newLocker("next").Acquire("key")
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, crashed.Renew(), lock.ErrLocked)
+			require.NoError(t, taken.Release())
+		})
+	}
+}
+
+func TestAcquireWait(t *testing.T) {
+	locker := &lock.FileLocker{Dir: t.TempDir(), TTL: time.Minute, Owner: "test"}
+
+	held, err := locker.Acquire("key")
+	require.NoError(t, err)
+
+	// Fails fast without a wait
+	_, err = lock.AcquireWait(locker, "key", 0)
+	assert.ErrorIs(t, err, lock.ErrLocked)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = held.Release()
+	}()
+
+	waited, err := lock.AcquireWait(locker, "key", 5*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, waited.Release())
+}
+
+func TestFileLockerWritesPrivateLockFiles(t *testing.T) {
+	dir := t.TempDir()
+	locker := lock.NewFileLocker(dir, time.Minute)
+
+	held, err := locker.Acquire("/team/key")
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, "team_key.lock"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.NoError(t, held.Release())
+}
+
+func TestNoopLocker(t *testing.T) {
+	first, err := lock.NoopLocker{}.Acquire("key")
+	require.NoError(t, err)
+
+	second, err := lock.NoopLocker{}.Acquire("key")
+	require.NoError(t, err)
+
+	assert.NoError(t, first.Renew())
+	assert.NoError(t, first.Release())
+	assert.NoError(t, second.Release())
+}
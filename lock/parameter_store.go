@@ -0,0 +1,173 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// ParameterNameSuffix is appended to a key name to form its lock parameter.
+const ParameterNameSuffix = "_lock"
+
+const parameterTypeString = "String"
+
+// ParameterStoreLocker keeps leases in Parameter Store, so rotations running
+// on different machines exclude each other.
+type ParameterStoreLocker struct {
+	Store types.ParameterStoreInterface
+	TTL   time.Duration
+	Owner string
+}
+
+func NewParameterStoreLocker(store types.ParameterStoreInterface, ttl time.Duration) *ParameterStoreLocker {
+	return &ParameterStoreLocker{Store: store, TTL: ttl, Owner: DefaultOwner()}
+}
+
+func (l *ParameterStoreLocker) Acquire(name string) (Lock, error) {
+	parameterName := name + ParameterNameSuffix
+
+	lease, err := newLease(l.Owner, l.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := lease.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.Store.CreateParameter(parameterName, value, parameterTypeString)
+
+	if errors.Is(err, types.ErrParameterAlreadyExists) {
+		err = l.takeOverExpired(parameterName, value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Confirm no one removed our lease while taking over an expired one
+	if err := l.verify(parameterName, lease); err != nil {
+		return nil, err
+	}
+
+	return &parameterStoreLock{locker: l, parameterName: parameterName, lease: lease}, nil
+}
+
+// takeOverExpired deletes the lease at parameterName if it has expired and
+// creates value in its place. Creating fails if another rotation got there
+// first, in which case the lock is held.
+//
+// Parameter Store cannot delete a parameter only if it holds a given
+// value, so the expired lease is read again just before it is deleted, and
+// Acquire reads the new lease back once it is created. A rotation that
+// took the lease over in the moment between that second read and the
+// delete still loses its lease; it finds out when it next renews or
+// releases the lock.
+func (l *ParameterStoreLocker) takeOverExpired(parameterName, value string) error {
+	current, err := l.Store.GetParameter(parameterName)
+
+	switch {
+	case errors.Is(err, types.ErrParameterNotFound):
+		// Released since we tried to create it
+	case err != nil:
+		return fmt.Errorf("error reading lock %s: %w", parameterName, err)
+	default:
+		holder, err := decodeLease(current)
+		if err == nil && !holder.expired() {
+			return lockedError(parameterName, holder)
+		}
+
+		if err := l.deleteLease(parameterName, current); err != nil {
+			return err
+		}
+	}
+
+	err = l.Store.CreateParameter(parameterName, value, parameterTypeString)
+	if errors.Is(err, types.ErrParameterAlreadyExists) {
+		return fmt.Errorf("%w: '%s' was taken by another rotation", ErrLocked, parameterName)
+	}
+
+	return err
+}
+
+// deleteLease deletes the lease at parameterName if it still holds the
+// expired lease read as expired.
+func (l *ParameterStoreLocker) deleteLease(parameterName, expired string) error {
+	current, err := l.Store.GetParameter(parameterName)
+
+	switch {
+	case errors.Is(err, types.ErrParameterNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("error reading lock %s: %w", parameterName, err)
+	case current != expired:
+		return fmt.Errorf("%w: '%s' was taken by another rotation", ErrLocked, parameterName)
+	}
+
+	err = l.Store.DeleteParameter(parameterName)
+	if err != nil && !errors.Is(err, types.ErrParameterNotFound) {
+		return fmt.Errorf("error removing expired lock %s: %w", parameterName, err)
+	}
+
+	return nil
+}
+
+func (l *ParameterStoreLocker) verify(parameterName string, lease Lease) error {
+	current, err := l.Store.GetParameter(parameterName)
+	if err != nil {
+		return fmt.Errorf("error reading lock %s: %w", parameterName, err)
+	}
+
+	holder, err := decodeLease(current)
+	if err != nil {
+		return fmt.Errorf("error decoding lock %s: %w", parameterName, err)
+	}
+
+	if holder.Token != lease.Token {
+		return lockedError(parameterName, holder)
+	}
+
+	return nil
+}
+
+type parameterStoreLock struct {
+	locker        *ParameterStoreLocker
+	parameterName string
+	lease         Lease
+}
+
+// Renew overwrites the lock parameter with the lease extended by the
+// locker's TTL, unless the lease expired and was taken over.
+func (l *parameterStoreLock) Renew() error {
+	if err := l.locker.verify(l.parameterName, l.lease); err != nil {
+		return err
+	}
+
+	lease := l.lease.renewed(l.locker.TTL)
+
+	value, err := lease.encode()
+	if err != nil {
+		return err
+	}
+
+	if err := l.locker.Store.PutParameter(l.parameterName, value, parameterTypeString); err != nil {
+		return fmt.Errorf("error renewing lock %s: %w", l.parameterName, err)
+	}
+
+	l.lease = lease
+
+	return nil
+}
+
+// Release deletes the lock parameter, unless the lease expired and was
+// taken over by another holder in the meantime.
+func (l *parameterStoreLock) Release() error {
+	if err := l.locker.verify(l.parameterName, l.lease); err != nil {
+		return err
+	}
+
+	return l.locker.Store.DeleteParameter(l.parameterName)
+}
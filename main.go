@@ -1,31 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws/session"
-	rotator "github.com/kmesiab/go-key-rotator"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	log "github.com/kmesiab/go-klogger"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/aws/aws-sdk-go/aws"
-
+	"github.com/kmesiab/go-key-rotator-cli/app"
 	"github.com/kmesiab/go-key-rotator-cli/args"
+	cliaws "github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_audit"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_config"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_daemon"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_doctor"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_exec"
 	"github.com/kmesiab/go-key-rotator-cli/cmd_fetch"
 	"github.com/kmesiab/go-key-rotator-cli/cmd_generate"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_iam_policy"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_render"
 	"github.com/kmesiab/go-key-rotator-cli/cmd_rotate"
+	"github.com/kmesiab/go-key-rotator-cli/cmd_status"
+	"github.com/kmesiab/go-key-rotator-cli/output"
+	"github.com/kmesiab/go-key-rotator-cli/types"
 )
 
-var rootCmd = &cobra.Command{
-	Use:   "go-rotate",
-	Short: "go-rotate is a CLI tool for managing RSA key rotation",
-	Long: `
-go-rotate is a tool for generating, storing, and retrieving
-public/private RSA key pairs using AWS Parameter store.
-	`,
-}
+// awsConfig and timeout are set once the flags, environment and config
+// profile that configure them have been resolved, before any sub command
+// runs.
+var (
+	awsConfig aws.Config
+	timeout   time.Duration
+)
 
 func main() {
 	log.InitializeGlobalLogger(logrus.InfoLevel, &logrus.TextFormatter{
@@ -35,71 +46,261 @@ func main() {
 		QuoteEmptyFields: true,
 	})
 
-	printGreeting()
+	rootCmd, err := newRootCommand()
+	if err != nil {
+		os.Exit(1)
+	}
 
-	// Set the default command to show help
-	rootCmd.Run = runShowHelp
+	// Execute the command. Errors are logged here, once, and mapped to the
+	// documented exit codes.
+	if err := run(context.Background(), rootCmd); err != nil {
+		var exitErr *types.ExitError
+
+		code := app.ExitCode(err)
 
-	var (
-		err  error
-		sess *session.Session
-	)
+		if errors.As(err, &exitErr) {
+			os.Exit(code)
+		}
 
-	config := aws.NewConfig() //.WithRegion("us-west-2")
-	if sess, err = session.NewSession(config); err != nil {
-		fmt.Printf("Error creating AWS config: %s\n", err)
+		log.Logf("Error executing command: %s", err).
+			Add("kind", types.KindOf(err)).
+			Add("exit_code", code).
+			Error()
+
+		os.Exit(code)
+	}
+}
+
+// errorResult is written to stdout in place of a command's result when the
+// command fails in json or yaml mode.
+type errorResult struct {
+	Error    string          `json:"error" yaml:"error"`
+	Kind     types.ErrorKind `json:"kind" yaml:"kind"`
+	ExitCode int             `json:"exit_code" yaml:"exit_code"`
+}
+
+// run executes rootCmd. When a command fails in json or yaml mode, the
+// error is also written to stdout in that format, so scripts reading the
+// result see why there is none. A child process's exit status is passed
+// through as it is.
+func run(ctx context.Context, rootCmd *cobra.Command) error {
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+
+	var exitErr *types.ExitError
+
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+
+	format, formatErr := output.ParseFormat(args.GetOutput(cmd))
+	if formatErr != nil || format == output.FormatText {
+		return err
+	}
 
-		return
+	result := errorResult{
+		Error:    err.Error(),
+		Kind:     types.KindOf(err),
+		ExitCode: app.ExitCode(err),
 	}
 
+	if writeErr := output.Write(os.Stdout, format, result); writeErr != nil {
+		log.Logf("Error writing the error result: %s", writeErr).Warn()
+	}
+
+	return err
+}
+
+// newRootCommand builds the go-rotate command with its flags and every sub
+// command.
+func newRootCommand() (*cobra.Command, error) {
+	rootCmd := &cobra.Command{
+		Use:   "go-rotate",
+		Short: "go-rotate is a CLI tool for managing RSA key rotation",
+		Long: `
+go-rotate is a tool for generating, storing, and retrieving
+public/private RSA key pairs using AWS Parameter store.
+	`,
+	}
+
+	// Set the default command to show help
+	rootCmd.Run = runShowHelp
+	rootCmd.PersistentPreRunE = configure
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return types.NewError(types.ErrorKindValidation, err)
+	})
+
+	args.AttachConfigFlag(rootCmd)
+	args.AttachProfileFlags(rootCmd)
+	args.AttachNamingFlags(rootCmd)
+	args.AttachAWSFlags(rootCmd)
+	args.AttachOutputFlag(rootCmd)
+	args.AttachAuditLogFlag(rootCmd)
+
 	// Add sub commands and initialize their flags
 	if err := args.Init(rootCmd,
-		NewGenerateCommand(sess).Run,
-		NewRotateCommand(sess).Run,
-		NewFetchCommand(sess).Run,
+		withAWSConfig(NewGenerateCommand, cmd_generate.GenerateCommand.Run),
+		withAWSConfig(NewRotateCommand, cmd_rotate.RotateCommand.Run),
+		withAWSConfig(NewFetchCommand, cmd_fetch.FetchCommand.Run),
 	); err != nil {
-		os.Exit(1)
+		return nil, err
+	}
+
+	if err := args.Mount(rootCmd, args.MountStatusCommand,
+		withAWSConfig(NewStatusCommand, cmd_status.StatusCommand.Run)); err != nil {
+		return nil, err
 	}
 
-	// Execute the command
-	if err := rootCmd.Execute(); err != nil {
-		log.Logf("Error executing command: %s\n", err).Error()
+	if err := args.Mount(rootCmd, args.MountDaemonCommand,
+		withAWSConfig(NewDaemonCommand, cmd_daemon.DaemonCommand.Run)); err != nil {
+		return nil, err
+	}
 
-		os.Exit(1)
+	if err := args.Mount(rootCmd, args.MountAuditCommand,
+		withAWSConfig(NewAuditCommand, cmd_audit.AuditCommand.Verify)); err != nil {
+		return nil, err
+	}
+
+	if err := args.Mount(rootCmd, args.MountExecCommand,
+		withAWSConfig(NewExecCommand, cmd_exec.ExecCommand.Run)); err != nil {
+		return nil, err
 	}
+
+	if err := args.Mount(rootCmd, args.MountConfigCommand,
+		withAWSConfig(NewConfigCommand, cmd_config.ConfigCommand.Show)); err != nil {
+		return nil, err
+	}
+
+	if err := args.Mount(rootCmd, args.MountRenderCommand,
+		withAWSConfig(NewRenderCommand, cmd_render.RenderCommand.Run)); err != nil {
+		return nil, err
+	}
+
+	if err := args.Mount(rootCmd, args.MountDoctorCommand,
+		withAWSConfig(NewDoctorCommand, cmd_doctor.DoctorCommand.Run)); err != nil {
+		return nil, err
+	}
+
+	if err := args.Mount(rootCmd, args.MountIAMPolicyCommand,
+		withAWSConfig(NewIAMPolicyCommand, cmd_iam_policy.IAMPolicyCommand.Run)); err != nil {
+		return nil, err
+	}
+
+	return rootCmd, nil
 }
 
-func NewRotateCommand(sess *session.Session) cmd_rotate.RotateCommand {
-	cmd := cmd_rotate.RotateCommand{
-		Session: sess,
+// withAWSConfig defers building a command until it runs, so it is given the
+// command's context and the AWS config created from the resolved settings.
+func withAWSConfig[T any](build func(context.Context, aws.Config) T, run func(T, *cobra.Command, []string) error) args.CommandRunFunc {
+	return func(cmd *cobra.Command, a []string) error {
+		return run(build(cmd.Context(), awsConfig), cmd, a)
 	}
+}
+
+// newCommand returns the dependencies every command is built from, backed
+// by the parameter store in cfg's account.
+func newCommand(ctx context.Context, cfg aws.Config) app.Command {
+	return app.NewCommand(cliaws.NewParameterStore(ctx, cfg, timeout), cfg)
+}
+
+func NewRotateCommand(ctx context.Context, cfg aws.Config) cmd_rotate.RotateCommand {
+	return cmd_rotate.RotateCommand{Command: newCommand(ctx, cfg)}
+}
+
+func NewStatusCommand(ctx context.Context, cfg aws.Config) cmd_status.StatusCommand {
+	return cmd_status.StatusCommand{Command: newCommand(ctx, cfg)}
+}
+
+func NewAuditCommand(ctx context.Context, cfg aws.Config) cmd_audit.AuditCommand {
+	return cmd_audit.AuditCommand{Command: newCommand(ctx, cfg)}
+}
+
+func NewConfigCommand(ctx context.Context, cfg aws.Config) cmd_config.ConfigCommand {
+	return cmd_config.ConfigCommand{Command: newCommand(ctx, cfg)}
+}
+
+func NewExecCommand(ctx context.Context, cfg aws.Config) cmd_exec.ExecCommand {
+	return cmd_exec.ExecCommand{Command: newCommand(ctx, cfg)}
+}
+
+func NewRenderCommand(ctx context.Context, cfg aws.Config) cmd_render.RenderCommand {
+	return cmd_render.RenderCommand{Command: newCommand(ctx, cfg)}
+}
 
-	cmd.KeyRotator = rotator.NewKeyRotator(rotator.NewAWSParameterStore(sess))
-	cmd.AWSSession = sess
+func NewDaemonCommand(ctx context.Context, cfg aws.Config) cmd_daemon.DaemonCommand {
+	return cmd_daemon.DaemonCommand{Command: newCommand(ctx, cfg)}
+}
 
-	return cmd
+func NewDoctorCommand(ctx context.Context, cfg aws.Config) cmd_doctor.DoctorCommand {
+	return cmd_doctor.DoctorCommand{Command: newCommand(ctx, cfg)}
 }
 
-func NewGenerateCommand(sess *session.Session) cmd_generate.GenerateCommand {
-	cmd := cmd_generate.GenerateCommand{}
+func NewIAMPolicyCommand(ctx context.Context, cfg aws.Config) cmd_iam_policy.IAMPolicyCommand {
+	return cmd_iam_policy.IAMPolicyCommand{Command: newCommand(ctx, cfg)}
+}
 
-	cmd.KeyRotator = rotator.NewKeyRotator(rotator.NewAWSParameterStore(sess))
-	cmd.AWSSession = sess
+func NewGenerateCommand(ctx context.Context, cfg aws.Config) cmd_generate.GenerateCommand {
+	return cmd_generate.GenerateCommand{Command: newCommand(ctx, cfg)}
+}
 
-	return cmd
+func NewFetchCommand(ctx context.Context, cfg aws.Config) cmd_fetch.FetchCommand {
+	return cmd_fetch.FetchCommand{Command: newCommand(ctx, cfg)}
 }
 
-func NewFetchCommand(sess *session.Session) cmd_fetch.FetchCommand {
-	cmd := cmd_fetch.FetchCommand{}
+// configure runs before every command. It applies the environment and the
+// config profile to flags that were not given, sets up output and loads
+// the AWS config.
+func configure(cmd *cobra.Command, _ []string) error {
+	settings, err := app.ApplySettings(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := configureOutput(cmd); err != nil {
+		return err
+	}
+
+	if timeout = args.GetDuration(cmd, args.FlagStringTimeout); timeout < 0 {
+		return types.Errorf(types.ErrorKindValidation, "invalid --%s %s", args.FlagStringTimeout, timeout)
+	}
+
+	options := settings.ConfigOptions()
+	options.MFAToken = args.GetString(cmd, args.FlagStringMFAToken)
+
+	if awsConfig, err = cliaws.NewConfig(cmd.Context(), options); err != nil {
+		return types.Errorf(types.ErrorKindValidation, "error loading AWS config: %w", err)
+	}
+
+	return nil
+}
+
+// configureOutput keeps stdout for command results. In text mode the
+// greeting is printed to stderr; in json and yaml mode it is suppressed and
+// only warnings and errors are logged, as JSON.
+func configureOutput(cmd *cobra.Command) error {
+	format, err := output.ParseFormat(args.GetOutput(cmd))
+	if err != nil {
+		return types.NewError(types.ErrorKindValidation, err)
+	}
+
+	if format == output.FormatText {
+		printGreeting()
+
+		return nil
+	}
 
-	cmd.KeyRotator = rotator.NewKeyRotator(rotator.NewAWSParameterStore(sess))
-	cmd.AWSSession = sess
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	log.SetLogger(logger)
 
-	return cmd
+	return nil
 }
 
 func printGreeting() {
-	fmt.Println(`
+	fmt.Fprintln(os.Stderr, `
 ┏┓┏┓  ┏┓┏┓╋┏┓╋┏┓
 ┗┫┗┛  ┛ ┗┛┗┗┻┗┗ 
  ┛
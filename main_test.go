@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/app"
+	"github.com/kmesiab/go-key-rotator-cli/ssmfake"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// keyFiles is the part of a command's JSON result naming the key files.
+type keyFiles struct {
+	PublicKeyFile  string `json:"public_key_file"`
+	PrivateKeyFile string `json:"private_key_file"`
+}
+
+// newEndpoint serves a fake Parameter Store and isolates the test from the
+// user's AWS and go-rotate configuration.
+func newEndpoint(t *testing.T) (*ssmfake.SSM, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "aws_config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "aws_credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	fake := ssmfake.New()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, server.URL
+}
+
+// execute runs go-rotate against endpoint and returns what it wrote to
+// stdout.
+func execute(t *testing.T, endpoint string, arguments ...string) ([]byte, error) {
+	t.Helper()
+
+	rootCmd, err := newRootCommand()
+	require.NoError(t, err)
+
+	rootCmd.SetArgs(append([]string{
+		"--endpoint-url", endpoint,
+		"--region", "us-east-1",
+		"--max-attempts", "1",
+		"--output", "json",
+	}, arguments...))
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	var captured bytes.Buffer
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&captured, reader)
+		close(done)
+	}()
+
+	err = run(context.Background(), rootCmd)
+
+	os.Stdout = stdout
+	_ = writer.Close()
+	<-done
+
+	return captured.Bytes(), err
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestGenerateDoesNotTouchParameterStore(t *testing.T) {
+	fake, endpoint := newEndpoint(t)
+	dir := t.TempDir()
+
+	out, err := execute(t, endpoint, "generate", "--name", "signing", "--out-dir", dir)
+	require.NoError(t, err)
+
+	var result keyFiles
+	require.NoError(t, json.Unmarshal(out, &result))
+	assert.FileExists(t, result.PrivateKeyFile)
+	assert.FileExists(t, result.PublicKeyFile)
+
+	for _, operation := range []string{
+		ssmfake.OperationGetParameter,
+		ssmfake.OperationPutParameter,
+		ssmfake.OperationDescribeParameters,
+	} {
+		assert.Zero(t, fake.Calls(operation), operation)
+	}
+}
+
+func TestStoreThenFetch(t *testing.T) {
+	fake, endpoint := newEndpoint(t)
+
+	out, err := execute(t, endpoint, "store", "--name", "/prod/signing",
+		"--out-dir", t.TempDir())
+	require.NoError(t, err)
+
+	var stored keyFiles
+	require.NoError(t, json.Unmarshal(out, &stored))
+
+	private, ok := fake.Parameter("/prod/signing_priv.pem")
+	require.True(t, ok)
+	assert.Equal(t, ssmtypes.ParameterTypeSecureString, private.Type)
+	assert.Equal(t, readFile(t, stored.PrivateKeyFile), private.Latest().Value)
+
+	_, err = execute(t, endpoint, "store", "--name", "/prod/signing", "--no-write")
+	require.NoError(t, err)
+
+	private, _ = fake.Parameter("/prod/signing_priv.pem")
+	public, _ := fake.Parameter("/prod/signing_pub.pem")
+	require.Len(t, private.Versions, 2)
+	assert.NotEqual(t, private.Versions[0].Value, private.Versions[1].Value)
+
+	out, err = execute(t, endpoint, "fetch", "--name", "/prod/signing", "--out-dir", t.TempDir())
+	require.NoError(t, err)
+
+	var fetched keyFiles
+	require.NoError(t, json.Unmarshal(out, &fetched))
+	assert.Equal(t, private.Latest().Value, readFile(t, fetched.PrivateKeyFile))
+	assert.Equal(t, public.Latest().Value, readFile(t, fetched.PublicKeyFile))
+}
+
+func TestFetchMissingKey(t *testing.T) {
+	_, endpoint := newEndpoint(t)
+
+	_, err := execute(t, endpoint, "fetch", "--name", "/prod/missing", "--out-dir", t.TempDir())
+	assert.Error(t, err)
+	assert.Equal(t, app.ExitNotFound, app.ExitCode(err))
+}
+
+func TestStoreWhenParameterStoreFails(t *testing.T) {
+	fake, endpoint := newEndpoint(t)
+
+	fake.Inject(ssmfake.Fault{
+		Operation: ssmfake.OperationPutParameter,
+		Name:      "/prod/signing_pub.pem",
+		Err:       &ssmtypes.InternalServerError{},
+	})
+
+	out, err := execute(t, endpoint, "store", "--name", "/prod/signing", "--no-write")
+	assert.Error(t, err)
+	assert.Equal(t, app.ExitBackendUnavailable, app.ExitCode(err))
+
+	// The failure is reported on stdout in the requested format
+	var result errorResult
+	require.NoError(t, json.Unmarshal(out, &result))
+	assert.Equal(t, errorResult{
+		Error:    err.Error(),
+		Kind:     types.ErrorKindBackendUnavailable,
+		ExitCode: app.ExitBackendUnavailable,
+	}, result)
+
+	// The half-stored pair is rolled back
+	_, ok := fake.Parameter("/prod/signing_priv.pem")
+	assert.False(t, ok)
+}
+
+func TestConfigKeysUseTheProfilePrefix(t *testing.T) {
+	fake, endpoint := newEndpoint(t)
+
+	path := filepath.Join(t.TempDir(), "rotator.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profile: staging
+profiles:
+  staging:
+    path_prefix: /staging/
+keys:
+  - name: payments
+    max_age: 30d
+`), 0o600))
+
+	_, err := execute(t, endpoint, "--config", path, "store", "--name", "payments", "--no-write")
+	require.NoError(t, err)
+
+	// The key's max age applied to the prefixed name
+	private, ok := fake.Parameter("/staging/payments_priv.pem")
+	require.True(t, ok)
+	require.Len(t, private.Policies, 1)
+	assert.Contains(t, private.Policies[0], "NoChangeNotification")
+
+	out, err := execute(t, endpoint, "--config", path, "status")
+	require.NoError(t, err)
+
+	var status struct {
+		Keys []struct {
+			Name   string `json:"name"`
+			MaxAge string `json:"max_age"`
+		} `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(out, &status))
+	require.Len(t, status.Keys, 1)
+	assert.Equal(t, "/staging/payments", status.Keys[0].Name)
+	assert.Equal(t, "30d", status.Keys[0].MaxAge)
+}
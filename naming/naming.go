@@ -0,0 +1,58 @@
+// Package naming builds Parameter Store paths for keys from a template, so
+// every team lays its keys out the same way, e.g. /prod/payments/signing.
+package naming
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Placeholders a name template can use.
+const (
+	PlaceholderEnv     = "{env}"
+	PlaceholderService = "{service}"
+	PlaceholderPurpose = "{purpose}"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Values fill a name template's placeholders. Purpose is the key's --name.
+type Values struct {
+	Env     string
+	Service string
+	Purpose string
+}
+
+// Expand returns the path template describes for values. Every placeholder
+// the template uses must have a value, and the template must use
+// {purpose} so that keys do not share a name.
+func Expand(template string, values Values) (string, error) {
+	known := map[string]string{
+		PlaceholderEnv:     values.Env,
+		PlaceholderService: values.Service,
+		PlaceholderPurpose: values.Purpose,
+	}
+
+	if !strings.Contains(template, PlaceholderPurpose) {
+		return "", fmt.Errorf("name template '%s' must contain %s", template, PlaceholderPurpose)
+	}
+
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		value, ok := known[placeholder]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder %s in name template '%s'. Placeholders: %s, %s, %s",
+				placeholder, template, PlaceholderEnv, PlaceholderService, PlaceholderPurpose)
+		}
+
+		if value == "" {
+			return "", fmt.Errorf("name template '%s' needs a value for %s", template, placeholder)
+		}
+	}
+
+	return strings.NewReplacer(
+		PlaceholderEnv, values.Env,
+		PlaceholderService, values.Service,
+		PlaceholderPurpose, values.Purpose,
+	).Replace(template), nil
+}
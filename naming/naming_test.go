@@ -0,0 +1,39 @@
+package naming_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/naming"
+)
+
+func TestExpand(t *testing.T) {
+	values := naming.Values{Env: "prod", Service: "payments", Purpose: "signing"}
+
+	path, err := naming.Expand("/{env}/{service}/{purpose}", values)
+	require.NoError(t, err)
+	assert.Equal(t, "/prod/payments/signing", path)
+
+	path, err = naming.Expand("{service}-{purpose}", values)
+	require.NoError(t, err)
+	assert.Equal(t, "payments-signing", path)
+
+	tests := []struct {
+		template string
+		values   naming.Values
+		expected string
+	}{
+		{template: "/{env}/{service}", values: values, expected: "must contain {purpose}"},
+		{template: "/{region}/{purpose}", values: values, expected: "unknown placeholder {region}"},
+		{template: "/{env}/{purpose}", values: naming.Values{Purpose: "signing"}, expected: "needs a value for {env}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			_, err := naming.Expand(test.template, test.values)
+			assert.ErrorContains(t, err, test.expected)
+		})
+	}
+}
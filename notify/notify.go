@@ -0,0 +1,200 @@
+// Package notify tells webhook consumers when a key changes.
+//
+// Each event is POSTed as JSON to every configured webhook that subscribes
+// to its type. Requests carry an X-Go-Rotate-Timestamp header and an
+// X-Go-Rotate-Signature header holding "sha256=" followed by the hex
+// HMAC-SHA256 of the timestamp, a ".", and the request body, keyed with the
+// webhook's secret. Failed deliveries are retried with exponential backoff.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kmesiab/go-key-rotator-cli/config"
+)
+
+type EventType string
+
+const (
+	// EventStored is sent when a key pair is stored for the first time.
+	EventStored EventType = "key.stored"
+
+	// EventRotated is sent when a stored key pair is replaced.
+	EventRotated EventType = "key.rotated"
+
+	// EventRolledBack is sent when a rotation failed and the previous key
+	// pair was restored.
+	EventRolledBack EventType = "key.rolled_back"
+)
+
+const (
+	HeaderSignature = "X-Go-Rotate-Signature"
+	HeaderTimestamp = "X-Go-Rotate-Timestamp"
+	HeaderEvent     = "X-Go-Rotate-Event"
+
+	signaturePrefix = "sha256="
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 3
+	DefaultBackoff = time.Second
+)
+
+type Event struct {
+	ID             string    `json:"id"`
+	Type           EventType `json:"type"`
+	KeyName        string    `json:"key_name"`
+	OldFingerprint string    `json:"old_fingerprint,omitempty"`
+	NewFingerprint string    `json:"new_fingerprint,omitempty"`
+	Version        int64     `json:"version,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	Actor          string    `json:"actor"`
+}
+
+// NewEvent returns an event of the given type with a fresh ID and the
+// current time.
+func NewEvent(eventType EventType, keyName, actor string) Event {
+	id := make([]byte, 16)
+
+	// crypto/rand.Read does not fail on supported platforms
+	_, _ = rand.Read(id)
+
+	return Event{
+		ID:        hex.EncodeToString(id),
+		Type:      eventType,
+		KeyName:   keyName,
+		Timestamp: time.Now().UTC(),
+		Actor:     actor,
+	}
+}
+
+type Notifier struct {
+	Webhooks []config.WebhookConfig
+	Client   *http.Client
+
+	// Backoff is the delay before the first retry. It doubles on each
+	// subsequent retry.
+	Backoff time.Duration
+}
+
+func NewNotifier(webhooks []config.WebhookConfig) *Notifier {
+	return &Notifier{
+		Webhooks: webhooks,
+		Client:   &http.Client{},
+		Backoff:  DefaultBackoff,
+	}
+}
+
+// Notify delivers the event to every subscribed webhook and returns the
+// errors of any deliveries that failed after all retries.
+func (n *Notifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, webhook := range n.Webhooks {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, string(event.Type)) {
+			continue
+		}
+
+		if err := n.deliver(ctx, webhook, event.Type, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.URL, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) deliver(ctx context.Context, webhook config.WebhookConfig, eventType EventType, body []byte) error {
+	retries := DefaultRetries
+	if webhook.Retries != nil {
+		retries = *webhook.Retries
+	}
+
+	backoff := n.Backoff
+
+	for attempt := 0; ; attempt++ {
+		retryable, err := n.send(ctx, webhook, eventType, body)
+		if err == nil || !retryable || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// send makes a single delivery attempt and reports whether a failure is
+// worth retrying.
+func (n *Notifier) send(ctx context.Context, webhook config.WebhookConfig, eventType EventType, body []byte) (bool, error) {
+	timeout := time.Duration(webhook.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEvent, string(eventType))
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderSignature, Sign(webhook.SigningSecret(), timestamp, body))
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return true, err
+	}
+
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+
+	retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+
+	return retryable, fmt.Errorf("unexpected response status %s", response.Status)
+}
+
+// Sign returns the signature header value for a request body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the timestamp and body.
+// Consumers should also reject timestamps that are too old.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,169 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/config"
+	"github.com/kmesiab/go-key-rotator-cli/notify"
+)
+
+const secret = "shh"
+
+// receiver records deliveries and answers with the queued status codes,
+// then 200.
+type receiver struct {
+	mu        sync.Mutex
+	statuses  []int
+	events    []notify.Event
+	signed    []bool
+	attempts  int
+	eventType []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+
+	var event notify.Event
+	_ = json.Unmarshal(body, &event)
+
+	r.events = append(r.events, event)
+	r.eventType = append(r.eventType, req.Header.Get(notify.HeaderEvent))
+	r.signed = append(r.signed, notify.Verify(secret,
+		req.Header.Get(notify.HeaderTimestamp), body, req.Header.Get(notify.HeaderSignature)))
+}
+
+func newNotifier(webhooks ...config.WebhookConfig) *notify.Notifier {
+	notifier := notify.NewNotifier(webhooks)
+	notifier.Backoff = time.Millisecond
+
+	return notifier
+}
+
+func retries(n int) *int {
+	return &n
+}
+
+func TestNotifyDeliversSignedEvents(t *testing.T) {
+	recv := &receiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	event := notify.NewEvent(notify.EventRotated, "payments", "alice@host")
+	event.OldFingerprint = "SHA256:old"
+	event.NewFingerprint = "SHA256:new"
+	event.Version = 4
+
+	err := newNotifier(config.WebhookConfig{URL: server.URL, Secret: secret}).Notify(context.Background(), event)
+	require.NoError(t, err)
+
+	require.Len(t, recv.events, 1)
+	assert.True(t, recv.signed[0], "signature should verify")
+	assert.Equal(t, string(notify.EventRotated), recv.eventType[0])
+	assert.Equal(t, event.ID, recv.events[0].ID)
+	assert.Equal(t, "payments", recv.events[0].KeyName)
+	assert.Equal(t, "SHA256:old", recv.events[0].OldFingerprint)
+	assert.Equal(t, "SHA256:new", recv.events[0].NewFingerprint)
+	assert.Equal(t, int64(4), recv.events[0].Version)
+	assert.Equal(t, "alice@host", recv.events[0].Actor)
+}
+
+func TestNotifyReadsSecretFromEnvironment(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", secret)
+
+	recv := &receiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	webhook := config.WebhookConfig{URL: server.URL, Secret: "ignored", SecretEnv: "TEST_WEBHOOK_SECRET"}
+	err := newNotifier(webhook).Notify(context.Background(), notify.NewEvent(notify.EventStored, "k", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, recv.signed)
+}
+
+func TestNotifyRetriesServerErrors(t *testing.T) {
+	recv := &receiver{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	err := newNotifier(config.WebhookConfig{URL: server.URL, Secret: secret}).
+		Notify(context.Background(), notify.NewEvent(notify.EventRotated, "k", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, recv.attempts)
+	assert.Len(t, recv.events, 1)
+}
+
+func TestNotifyGivesUpAfterRetries(t *testing.T) {
+	recv := &receiver{statuses: []int{500, 500, 500}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	webhook := config.WebhookConfig{URL: server.URL, Secret: secret, Retries: retries(1)}
+	err := newNotifier(webhook).Notify(context.Background(), notify.NewEvent(notify.EventRotated, "k", "a"))
+	assert.ErrorContains(t, err, server.URL)
+	assert.Equal(t, 2, recv.attempts)
+}
+
+func TestNotifyDoesNotRetryClientErrors(t *testing.T) {
+	recv := &receiver{statuses: []int{http.StatusUnauthorized}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	err := newNotifier(config.WebhookConfig{URL: server.URL, Secret: secret}).
+		Notify(context.Background(), notify.NewEvent(notify.EventRotated, "k", "a"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, recv.attempts)
+}
+
+func TestNotifyFiltersEvents(t *testing.T) {
+	rotations := &receiver{}
+	rotationServer := httptest.NewServer(rotations)
+	defer rotationServer.Close()
+
+	everything := &receiver{}
+	everythingServer := httptest.NewServer(everything)
+	defer everythingServer.Close()
+
+	notifier := newNotifier(
+		config.WebhookConfig{URL: rotationServer.URL, Secret: secret, Events: []string{string(notify.EventRotated)}},
+		config.WebhookConfig{URL: everythingServer.URL, Secret: secret},
+	)
+
+	require.NoError(t, notifier.Notify(context.Background(), notify.NewEvent(notify.EventRolledBack, "k", "a")))
+	require.NoError(t, notifier.Notify(context.Background(), notify.NewEvent(notify.EventRotated, "k", "a")))
+
+	assert.Len(t, rotations.events, 1)
+	assert.Len(t, everything.events, 2)
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	body := []byte(`{"key_name":"payments"}`)
+	signature := notify.Sign(secret, "1700000000", body)
+
+	assert.True(t, notify.Verify(secret, "1700000000", body, signature))
+	assert.False(t, notify.Verify(secret, "1700000001", body, signature))
+	assert.False(t, notify.Verify("other", "1700000000", body, signature))
+	assert.False(t, notify.Verify(secret, "1700000000", []byte(`{"key_name":"auth"}`), signature))
+}
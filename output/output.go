@@ -0,0 +1,75 @@
+// Package output renders command results for people or for scripts.
+//
+// In text mode a result is printed in its human readable form. In json and
+// yaml mode the result itself is encoded, so every field a script might need
+// (names, paths, fingerprints, versions, errors) is available on stdout.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Formats lists every supported output format.
+var Formats = []Format{FormatText, FormatJSON, FormatYAML}
+
+// ParseFormat returns the format named by value.
+func ParseFormat(value string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(value, string(format)) {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown output format '%s'; expected one of %v", value, Formats)
+}
+
+// Texter is implemented by results with a human readable form. Results that
+// do not implement it are printed with fmt in text mode.
+type Texter interface {
+	Text() string
+}
+
+// Write renders result to w in the given format.
+func Write(w io.Writer, format Format, result any) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(result)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+
+		return encoder.Close()
+	case FormatText, "":
+		if texter, ok := result.(Texter); ok {
+			_, err := io.WriteString(w, texter.Text())
+
+			return err
+		}
+
+		_, err := fmt.Fprintln(w, result)
+
+		return err
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
@@ -0,0 +1,91 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kmesiab/go-key-rotator-cli/output"
+)
+
+type result struct {
+	Name        string `json:"name" yaml:"name"`
+	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+}
+
+func (r result) Text() string {
+	return "🔐 " + r.Name + "\n"
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected output.Format
+		wantErr  bool
+	}{
+		{value: "text", expected: output.FormatText},
+		{value: "JSON", expected: output.FormatJSON},
+		{value: "yaml", expected: output.FormatYAML},
+		{value: "xml", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			format, err := output.ParseFormat(test.value)
+			if test.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, format)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	value := result{Name: "my_key", Fingerprint: "SHA256:abc"}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		require.NoError(t, output.Write(&buf, output.FormatText, value))
+		assert.Equal(t, "🔐 my_key\n", buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var (
+			buf     bytes.Buffer
+			decoded result
+		)
+
+		require.NoError(t, output.Write(&buf, output.FormatJSON, value))
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, value, decoded)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var (
+			buf     bytes.Buffer
+			decoded result
+		)
+
+		require.NoError(t, output.Write(&buf, output.FormatYAML, value))
+		assert.Contains(t, buf.String(), "fingerprint: SHA256:abc")
+		require.NoError(t, yaml.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, value, decoded)
+	})
+
+	t.Run("text without Texter", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		require.NoError(t, output.Write(&buf, output.FormatText, 42))
+		assert.Equal(t, "42\n", buf.String())
+	})
+}
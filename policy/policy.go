@@ -0,0 +1,142 @@
+// Package policy decides when a stored key pair is due for rotation.
+//
+// A Policy carries a maximum key age and a due window. A key younger than
+// MaxAge-DueWindow is OK, a key inside the due window is DUE, and a key older
+// than MaxAge is OVERDUE. Keys that have never been stored are MISSING, and
+// keys without a MaxAge are UNMANAGED.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+type Status string
+
+const (
+	StatusOK        Status = "OK"
+	StatusDue       Status = "DUE"
+	StatusOverdue   Status = "OVERDUE"
+	StatusMissing   Status = "MISSING"
+	StatusUnmanaged Status = "UNMANAGED"
+)
+
+// DefaultDueWindowRatio is the fraction of MaxAge used as the due window
+// when a policy does not set one explicitly.
+const DefaultDueWindowRatio = 0.1
+
+const day = 24 * time.Hour
+
+type Policy struct {
+	MaxAge    time.Duration
+	DueWindow time.Duration
+}
+
+// Managed reports whether the policy has a maximum age.
+func (p Policy) Managed() bool {
+	return p.MaxAge > 0
+}
+
+// EffectiveDueWindow returns the configured due window, or the default
+// fraction of MaxAge when none is configured.
+func (p Policy) EffectiveDueWindow() time.Duration {
+	if p.DueWindow > 0 {
+		return p.DueWindow
+	}
+
+	return time.Duration(float64(p.MaxAge) * DefaultDueWindowRatio)
+}
+
+// Evaluate returns the rotation status of a key last modified at lastModified.
+// A zero lastModified means the key has never been stored.
+func (p Policy) Evaluate(lastModified, now time.Time) Status {
+	if lastModified.IsZero() {
+		return StatusMissing
+	}
+
+	if !p.Managed() {
+		return StatusUnmanaged
+	}
+
+	age := now.Sub(lastModified)
+
+	switch {
+	case age >= p.MaxAge:
+		return StatusOverdue
+	case age >= p.MaxAge-p.EffectiveDueWindow():
+		return StatusDue
+	default:
+		return StatusOK
+	}
+}
+
+// NeedsRotation reports whether a key with the given status should be rotated.
+func (s Status) NeedsRotation() bool {
+	return s == StatusDue || s == StatusOverdue || s == StatusMissing
+}
+
+// ParseDuration parses a Go duration string, additionally accepting whole
+// days ("90d") and weeks ("2w").
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": 7 * day} {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
+
+// FormatDuration renders a duration in whole days when it is at least a day
+// long, and as a Go duration rounded to the minute otherwise.
+func FormatDuration(d time.Duration) string {
+	if d >= day {
+		return fmt.Sprintf("%dd", d/day)
+	}
+
+	return d.Round(time.Minute).String()
+}
+
+// LastRotated returns the time the key pair stored under privateKeyName and
+// publicKeyName was last written, taken as the older of the two halves. A
+// zero time is returned when either half is missing.
+func LastRotated(store types.ParameterStoreInterface, privateKeyName, publicKeyName string) (time.Time, error) {
+	var lastRotated time.Time
+
+	for _, name := range []string{privateKeyName, publicKeyName} {
+		metadata, err := store.DescribeParameter(name)
+
+		if errors.Is(err, types.ErrParameterNotFound) {
+			return time.Time{}, nil
+		}
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if lastRotated.IsZero() || metadata.LastModifiedDate.Before(lastRotated) {
+			lastRotated = metadata.LastModifiedDate
+		}
+	}
+
+	return lastRotated, nil
+}
@@ -0,0 +1,121 @@
+package policy_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/policy"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const day = 24 * time.Hour
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	ninetyDays := policy.Policy{MaxAge: 90 * day, DueWindow: 7 * day}
+
+	tests := []struct {
+		name     string
+		policy   policy.Policy
+		age      time.Duration
+		missing  bool
+		expected policy.Status
+	}{
+		{"fresh key", ninetyDays, 10 * day, false, policy.StatusOK},
+		{"just before due window", ninetyDays, 83*day - time.Minute, false, policy.StatusOK},
+		{"inside due window", ninetyDays, 83 * day, false, policy.StatusDue},
+		{"at max age", ninetyDays, 90 * day, false, policy.StatusOverdue},
+		{"past max age", ninetyDays, 200 * day, false, policy.StatusOverdue},
+		{"default due window", policy.Policy{MaxAge: 100 * day}, 91 * day, false, policy.StatusDue},
+		{"no policy", policy.Policy{}, 500 * day, false, policy.StatusUnmanaged},
+		{"never stored", ninetyDays, 0, true, policy.StatusMissing},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lastModified := now.Add(-test.age)
+			if test.missing {
+				lastModified = time.Time{}
+			}
+
+			assert.Equal(t, test.expected, test.policy.Evaluate(lastModified, now))
+		})
+	}
+}
+
+func TestNeedsRotation(t *testing.T) {
+	assert.False(t, policy.StatusOK.NeedsRotation())
+	assert.False(t, policy.StatusUnmanaged.NeedsRotation())
+	assert.True(t, policy.StatusDue.NeedsRotation())
+	assert.True(t, policy.StatusOverdue.NeedsRotation())
+	assert.True(t, policy.StatusMissing.NeedsRotation())
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"90d", 90 * day, true},
+		{"2w", 14 * day, true},
+		{"36h", 36 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"", 0, false},
+		{"d", 0, false},
+		{"-3d", 0, false},
+		{"ninety days", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := policy.ParseDuration(test.input)
+			if !test.valid {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "90d", policy.FormatDuration(90*day+5*time.Hour))
+	assert.Equal(t, "5h0m0s", policy.FormatDuration(5*time.Hour))
+}
+
+type metadataStore struct {
+	types.ParameterStoreInterface
+	modified map[string]time.Time
+}
+
+func (s metadataStore) DescribeParameter(name string) (*types.ParameterMetadata, error) {
+	modified, ok := s.modified[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	}
+
+	return &types.ParameterMetadata{Name: name, LastModifiedDate: modified}, nil
+}
+
+func TestLastRotated(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Minute)
+
+	store := metadataStore{modified: map[string]time.Time{"priv": newer, "pub": older}}
+
+	lastRotated, err := policy.LastRotated(store, "priv", "pub")
+	require.NoError(t, err)
+	assert.Equal(t, older, lastRotated)
+
+	lastRotated, err = policy.LastRotated(store, "priv", "missing")
+	require.NoError(t, err)
+	assert.True(t, lastRotated.IsZero())
+}
@@ -0,0 +1,97 @@
+// Package process runs a child command in the foreground, the way
+// exec-style wrappers such as aws-vault exec do. Signals sent to go-rotate
+// are forwarded to the child, and the child's exit code is passed back.
+package process
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// signalExitBase is added to a signal's number to form the exit code of a
+// child killed by it, as shells do.
+const signalExitBase = 128
+
+// Stdio is what a child reads its stdin from and writes its stdout and
+// stderr to. Nil streams are connected to the null device.
+type Stdio struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner runs a child command in the foreground. Commands run children
+// through it so tests can keep them from starting real processes.
+type Runner interface {
+	// Run runs command and returns its exit code. See Run.
+	Run(command []string, env []string, stdio Stdio) (int, error)
+}
+
+// SystemRunner is the Runner that starts real processes.
+type SystemRunner struct{}
+
+func (SystemRunner) Run(command []string, env []string, stdio Stdio) (int, error) {
+	return Run(command, env, stdio)
+}
+
+// Run starts command with env as its whole environment, connected to
+// stdio, and waits for it to exit. It returns the child's exit code. An
+// error is only returned if the child could not be started.
+func Run(command []string, env []string, stdio Stdio) (int, error) {
+	if len(command) == 0 {
+		return 0, errors.New("no command given")
+	}
+
+	// #nosec G204 -- running the operator's command is the point of exec
+	child := exec.Command(command[0], command[1:]...)
+	child.Env = env
+	child.Stdin = stdio.Stdin
+	child.Stdout = stdio.Stdout
+	child.Stderr = stdio.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := child.Wait()
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return 0, err
+	}
+
+	return ExitCode(child.ProcessState), nil
+}
+
+// ExitCode returns the exit code of a finished process. A process killed by
+// a signal exits with 128 plus the signal number.
+func ExitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return signalExitBase + int(status.Signal())
+	}
+
+	return state.ExitCode()
+}
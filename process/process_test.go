@@ -0,0 +1,93 @@
+//go:build !windows
+
+package process_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/process"
+)
+
+func TestRunReturnsExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected int
+	}{
+		{name: "success", script: "exit 0", expected: 0},
+		{name: "failure", script: "exit 3", expected: 3},
+		{name: "killed by signal", script: "kill -TERM $$", expected: 128 + int(syscall.SIGTERM)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := process.Run([]string{"sh", "-c", test.script}, os.Environ(), process.Stdio{})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, code)
+		})
+	}
+}
+
+func TestRunPassesEnvironment(t *testing.T) {
+	code, err := process.Run([]string{"sh", "-c", `test "$PRIVATE_KEY" = secret`}, []string{"PRIVATE_KEY=secret"}, process.Stdio{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+}
+
+func TestRunConnectsStdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code, err := process.Run([]string{"sh", "-c", "cat; echo failed >&2"}, os.Environ(), process.Stdio{
+		Stdin:  strings.NewReader("secret"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "secret", stdout.String())
+	assert.Equal(t, "failed\n", stderr.String())
+}
+
+func TestRunStartFailure(t *testing.T) {
+	_, err := process.Run([]string{"/no/such/command"}, nil, process.Stdio{})
+	assert.Error(t, err)
+}
+
+func TestRunForwardsSignals(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	script := `trap 'exit 7' USR1; touch "$1"; while :; do sleep 0.1; done`
+
+	go func() {
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(ready)
+
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}()
+
+	code, err := process.Run([]string{"sh", "-c", script, "sh", ready}, os.Environ(), process.Stdio{})
+	require.NoError(t, err)
+	assert.Equal(t, 7, code)
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"HUP", "sighup", "SIGHUP"} {
+		sig, err := process.ParseSignal(name)
+		require.NoError(t, err)
+		assert.Equal(t, syscall.SIGHUP, sig)
+	}
+
+	_, err := process.ParseSignal("NOPE")
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ParseSignal returns the signal named by name, with or without its SIG
+// prefix, such as "HUP" or "SIGUSR1".
+func ParseSignal(name string) (os.Signal, error) {
+	sig, ok := signalsByName[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, fmt.Errorf("unknown signal '%s'", name)
+	}
+
+	return sig, nil
+}
+
+// Signal sends sig to the process with the given PID.
+func Signal(pid int, sig os.Signal) error {
+	target, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return target.Signal(sig)
+}
+
+// Shell runs command with the platform shell, sending its output to
+// stderr.
+func Shell(ctx context.Context, command string) error {
+	// #nosec G204 -- reload commands come from the operator
+	child := exec.CommandContext(ctx, shell[0], append(shell[1:], command)...)
+	child.Stdout = os.Stderr
+	child.Stderr = os.Stderr
+
+	return child.Run()
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package process
+
+import (
+	"os"
+)
+
+// forwardedSignals are relayed to the child instead of stopping go-rotate.
+var forwardedSignals = []os.Signal{
+	os.Interrupt,
+}
+
+var signalsByName = map[string]os.Signal{
+	"INT":  os.Interrupt,
+	"KILL": os.Kill,
+}
+
+// shell is the command line used to run reload commands.
+var shell = []string{"cmd", "/C"}
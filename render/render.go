@@ -0,0 +1,238 @@
+// Package render fills Go templates with keys from the parameter store.
+//
+// Templates can call these functions, each taking a key name as passed to
+// --name and expanded by the renderer's ExpandName:
+//
+//	privateKeyPEM "payments"   the PEM encoded private key
+//	publicKeyPEM "payments"    the PEM encoded public key
+//	publicKeyJWK "payments"    the public key as a JSON Web Key
+//	fingerprint "payments"     the public key's SHA256 fingerprint
+//
+// plus indent, which indents every line of a string by n spaces so a PEM
+// block can be embedded in YAML.
+package render
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+	"text/template"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// JWK is an RSA public key in JSON Web Key format. The key ID is the
+// RFC 7638 thumbprint of the key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// NewJWK returns the JWK of an RSA public key for RS256 signatures.
+func NewJWK(publicKey *rsa.PublicKey) JWK {
+	modulus := base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+	exponent := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+
+	// RFC 7638: the required members, in lexicographic order, without spaces
+	thumbprint := sha256.Sum256([]byte(`{"e":"` + exponent + `","kty":"RSA","n":"` + modulus + `"}`))
+
+	return JWK{
+		KeyType:   "RSA",
+		KeyID:     base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+		Use:       "sig",
+		Algorithm: "RS256",
+		Modulus:   modulus,
+		Exponent:  exponent,
+	}
+}
+
+// Renderer renders templates, fetching each key pair they use at most once.
+type Renderer struct {
+	// ExpandName turns a name given to a template function into the full
+	// key name, e.g. by adding the path prefix. Names are used as they are
+	// when it is nil.
+	ExpandName func(name string) (string, error)
+
+	// Region is where the keys are stored. Names are checked against its
+	// length limit, or the longest region's when it is empty.
+	Region string
+
+	keyRotator *rotator.KeyRotator
+
+	privateKeys map[string]*rsa.PrivateKey
+	publicKeys  map[string]*rsa.PublicKey
+}
+
+func NewRenderer(store types.ParameterStoreInterface) *Renderer {
+	return &Renderer{
+		keyRotator:  rotator.NewKeyRotator(store),
+		privateKeys: make(map[string]*rsa.PrivateKey),
+		publicKeys:  make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Render executes the template text and returns its output.
+func (r *Renderer) Render(name, text string) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(r.Funcs()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// Funcs returns the template functions.
+func (r *Renderer) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"privateKeyPEM": r.privateKeyPEM,
+		"publicKeyPEM":  r.publicKeyPEM,
+		"publicKeyJWK":  r.publicKeyJWK,
+		"fingerprint":   r.fingerprint,
+		"indent":        indent,
+	}
+}
+
+// Keys returns the names of the keys the rendered templates used.
+func (r *Renderer) Keys() []string {
+	names := make([]string, 0, len(r.publicKeys))
+
+	for name := range r.publicKeys {
+		names = append(names, name)
+	}
+
+	for name := range r.privateKeys {
+		if _, ok := r.publicKeys[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// UsedPrivateKey reports whether any rendered template included a private
+// key, in which case the output must be kept private too.
+func (r *Renderer) UsedPrivateKey() bool {
+	return len(r.privateKeys) > 0
+}
+
+// keyName returns the full, valid key name for a name given to a template
+// function.
+func (r *Renderer) keyName(name string) (string, error) {
+	if r.ExpandName != nil {
+		var err error
+
+		if name, err = r.ExpandName(name); err != nil {
+			return "", err
+		}
+	}
+
+	if err := aws.ValidateKeyName(name, r.Region); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+func (r *Renderer) privateKeyPEM(name string) (string, error) {
+	name, err := r.keyName(name)
+	if err != nil {
+		return "", err
+	}
+
+	privateKey, ok := r.privateKeys[name]
+	if !ok {
+		if privateKey, err = r.keyRotator.GetCurrentRSAPrivateKey(aws.MakePrivateKeyName(name)); err != nil {
+			return "", err
+		}
+
+		r.privateKeys[name] = privateKey
+	}
+
+	return string(rotator.EncodePrivateKeyToPEM(privateKey)), nil
+}
+
+func (r *Renderer) publicKey(name string) (*rsa.PublicKey, error) {
+	name, err := r.keyName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if publicKey, ok := r.publicKeys[name]; ok {
+		return publicKey, nil
+	}
+
+	publicKey, err := r.keyRotator.GetCurrentRSAPublicKey(aws.MakePublicKeyName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	r.publicKeys[name] = publicKey
+
+	return publicKey, nil
+}
+
+func (r *Renderer) publicKeyPEM(name string) (string, error) {
+	publicKey, err := r.publicKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := rotator.EncodePublicKeyToPEM(publicKey)
+
+	return string(encoded), err
+}
+
+func (r *Renderer) publicKeyJWK(name string) (string, error) {
+	publicKey, err := r.publicKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(NewJWK(publicKey))
+
+	return string(encoded), err
+}
+
+func (r *Renderer) fingerprint(name string) (string, error) {
+	publicKey, err := r.publicKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	return rotation.Fingerprint(publicKey)
+}
+
+// indent prefixes every non-empty line of text with spaces.
+func indent(spaces int, text string) string {
+	prefix := strings.Repeat(" ", spaces)
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,37 @@
+package rotation
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+)
+
+// Fingerprint returns the SHA-256 fingerprint of a public key's PKIX
+// encoding, formatted like OpenSSH fingerprints: "SHA256:<base64>".
+func Fingerprint(publicKey *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return fingerprintDER(der), nil
+}
+
+// FingerprintPEM returns the fingerprint of a PEM encoded public key.
+func FingerprintPEM(publicKeyPEM string) (string, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return "", errors.New("failed to decode PEM block containing the public key")
+	}
+
+	return fingerprintDER(block.Bytes), nil
+}
+
+func fingerprintDER(der []byte) string {
+	sum := sha256.Sum256(der)
+
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,37 @@
+package rotation_test
+
+import (
+	"testing"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+)
+
+func TestFingerprint(t *testing.T) {
+	publicKey, _, err := rotator.NewKeyRotator(nil).GenerateKeyPair(2048)
+	require.NoError(t, err)
+
+	fingerprint, err := rotation.Fingerprint(publicKey)
+	require.NoError(t, err)
+	assert.Regexp(t, `^SHA256:[A-Za-z0-9+/]{43}$`, fingerprint)
+
+	publicKeyPEM, err := rotator.EncodePublicKeyToPEM(publicKey)
+	require.NoError(t, err)
+
+	fromPEM, err := rotation.FingerprintPEM(string(publicKeyPEM))
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, fromPEM)
+
+	otherKey, _, err := rotator.NewKeyRotator(nil).GenerateKeyPair(2048)
+	require.NoError(t, err)
+
+	other, err := rotation.Fingerprint(otherKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, other)
+
+	_, err = rotation.FingerprintPEM("not a key")
+	assert.Error(t, err)
+}
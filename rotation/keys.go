@@ -0,0 +1,30 @@
+package rotation
+
+import (
+	"crypto/rsa"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// RSAKeyGenerator generates RSA key pairs from crypto/rand. It implements
+// types.KeyGeneratorInterface.
+type RSAKeyGenerator struct{}
+
+func (RSAKeyGenerator) GenerateKeyPair(keySize int) (*rsa.PublicKey, *rsa.PrivateKey, error) {
+	// The go-key-rotator generator checks the size and never uses the store
+	return rotator.NewKeyRotator(nil).GenerateKeyPair(keySize)
+}
+
+// ReadPrivateKey reads the named parameter and decodes the PEM encoded
+// private key it holds.
+func ReadPrivateKey(store types.ParameterStoreInterface, name string) (*rsa.PrivateKey, error) {
+	return rotator.NewKeyRotator(store).GetCurrentRSAPrivateKey(name)
+}
+
+// ReadPublicKey reads the named parameter and decodes the PEM encoded
+// public key it holds.
+func ReadPublicKey(store types.ParameterStoreInterface, name string) (*rsa.PublicKey, error) {
+	return rotator.NewKeyRotator(store).GetCurrentRSAPublicKey(name)
+}
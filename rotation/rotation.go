@@ -0,0 +1,220 @@
+// Package rotation rotates key pairs so the store is never left holding a
+// mismatched pair.
+//
+// The private and public halves of a key pair are separate parameters and
+// cannot be written atomically. TransactionalRotator snapshots the current
+// pair, writes both new halves, reads them back to verify them, and restores
+// the snapshot if any step fails.
+package rotation
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// ParameterTypeSecureString is the parameter type both halves are stored as.
+const ParameterTypeSecureString = "SecureString"
+
+var (
+	// ErrRolledBack is wrapped by errors from a rotation that failed and
+	// restored the previous key pair.
+	ErrRolledBack = errors.New("rotation rolled back")
+
+	// ErrRollbackFailed is wrapped by errors from a rotation that failed
+	// and could not restore the previous key pair. The store may hold a
+	// mismatched pair.
+	ErrRollbackFailed = errors.New("rotation rollback failed")
+
+	// ErrOptionsUnsupported is returned when PutOptions are set but the
+	// store cannot apply them.
+	ErrOptionsUnsupported = errors.New("parameter store does not support parameter options")
+)
+
+// Snapshot holds the stored values of a key pair so they can be restored.
+type Snapshot struct {
+	PrivateKeyName string
+	PublicKeyName  string
+
+	// Values of each half keyed by parameter name. A half that did not
+	// exist is absent.
+	Values map[string]string
+
+	// Options are applied to each restored half, so a restore stores it
+	// with the same KMS key, tier, tags and policies as the rotation it
+	// undoes. The store must implement types.ParameterOptionsStore to use
+	// them.
+	Options types.PutOptions
+}
+
+// TakeSnapshot reads the current values of both halves of a key pair.
+func TakeSnapshot(store types.ParameterStoreInterface, privateKeyName, publicKeyName string) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		PrivateKeyName: privateKeyName,
+		PublicKeyName:  publicKeyName,
+		Values:         make(map[string]string, 2),
+	}
+
+	for _, name := range []string{privateKeyName, publicKeyName} {
+		value, err := store.GetParameter(name)
+
+		if errors.Is(err, types.ErrParameterNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+
+		snapshot.Values[name] = value
+	}
+
+	return snapshot, nil
+}
+
+// Exists reports whether the snapshotted key pair had been stored.
+func (s *Snapshot) Exists() bool {
+	return len(s.Values) > 0
+}
+
+// Restore writes the snapshotted values back to the store. Halves that did
+// not exist when the snapshot was taken are deleted.
+func (s *Snapshot) Restore(store types.ParameterStoreInterface) error {
+	var errs []error
+
+	for _, name := range []string{s.PrivateKeyName, s.PublicKeyName} {
+		value, existed := s.Values[name]
+
+		var err error
+
+		if existed {
+			err = put(store, name, value, s.Options)
+		} else if err = store.DeleteParameter(name); errors.Is(err, types.ErrParameterNotFound) {
+			err = nil
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error restoring %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// TransactionalRotator implements types.KeyRotatorInterface with rollback.
+type TransactionalRotator struct {
+	Store types.ParameterStoreInterface
+
+	// Generator generates each new key pair. Nil means RSAKeyGenerator.
+	Generator types.KeyGeneratorInterface
+
+	// Options are applied to both halves of each new key pair. The store
+	// must implement types.ParameterOptionsStore to use them.
+	Options types.PutOptions
+}
+
+func NewTransactionalRotator(store types.ParameterStoreInterface) *TransactionalRotator {
+	return &TransactionalRotator{Store: store}
+}
+
+// Rotate generates a new key pair and stores it. If either write, or the
+// read back that follows, fails, the previous pair is restored and the
+// returned error wraps ErrRolledBack, or ErrRollbackFailed if the restore
+// failed too.
+func (r *TransactionalRotator) Rotate(
+	parameterStoreKeyNamePrivateKey,
+	parameterStoreKeyNamePublicKey string,
+	keySize int,
+) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	if parameterStoreKeyNamePrivateKey == "" || parameterStoreKeyNamePublicKey == "" {
+		return nil, nil, errors.New("invalid parameter names: names cannot be empty")
+	}
+
+	var generator types.KeyGeneratorInterface = RSAKeyGenerator{}
+	if r.Generator != nil {
+		generator = r.Generator
+	}
+
+	publicKey, privateKey, err := generator.GenerateKeyPair(keySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKeyPEM, err := rotator.EncodePublicKeyToPEM(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Refuse before writing, since a rollback could not apply them either
+	if _, ok := r.Store.(types.ParameterOptionsStore); !ok && !r.Options.IsZero() {
+		return nil, nil, ErrOptionsUnsupported
+	}
+
+	snapshot, err := TakeSnapshot(r.Store, parameterStoreKeyNamePrivateKey, parameterStoreKeyNamePublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshot.Options = r.Options
+
+	values := map[string]string{
+		parameterStoreKeyNamePrivateKey: string(rotator.EncodePrivateKeyToPEM(privateKey)),
+		parameterStoreKeyNamePublicKey:  string(publicKeyPEM),
+	}
+
+	if err := r.write(snapshot, values); err != nil {
+		if restoreErr := snapshot.Restore(r.Store); restoreErr != nil {
+			return nil, nil, fmt.Errorf("%w: %w: %w", ErrRollbackFailed, err, restoreErr)
+		}
+
+		return nil, nil, fmt.Errorf("%w: %w", ErrRolledBack, err)
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// write stores and then verifies each value, private key first.
+func (r *TransactionalRotator) write(snapshot *Snapshot, values map[string]string) error {
+	names := []string{snapshot.PrivateKeyName, snapshot.PublicKeyName}
+
+	for _, name := range names {
+		if err := put(r.Store, name, values[name], r.Options); err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+	}
+
+	for _, name := range names {
+		stored, err := r.Store.GetParameter(name)
+		if err != nil {
+			return fmt.Errorf("error verifying %s: %w", name, err)
+		}
+
+		if stored != values[name] {
+			return fmt.Errorf("error verifying %s: stored value does not match", name)
+		}
+	}
+
+	return nil
+}
+
+// put writes one half of a key pair with options, replacing the stored
+// half.
+func put(store types.ParameterStoreInterface, name, value string, options types.PutOptions) error {
+	if options.IsZero() {
+		return store.PutParameter(name, value, ParameterTypeSecureString)
+	}
+
+	optionsStore, ok := store.(types.ParameterOptionsStore)
+	if !ok {
+		return ErrOptionsUnsupported
+	}
+
+	options.Type = ParameterTypeSecureString
+	options.Overwrite = true
+
+	return optionsStore.PutParameterWithOptions(name, value, options)
+}
@@ -0,0 +1,305 @@
+package rotation_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	rotator "github.com/kmesiab/go-key-rotator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/rotation"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+const (
+	privateKeyName = "team/key_priv.pem"
+	publicKeyName  = "team/key_pub.pem"
+)
+
+var errInjected = errors.New("injected fault")
+
+// faultyStore is an in-memory store that fails the nth call to an
+// operation on a given parameter, counting from 1.
+type faultyStore struct {
+	values map[string]string
+	calls  map[string]int
+	faults map[string]int
+	// corrupt makes the nth GetParameter of a name return a different value
+	corrupt map[string]int
+}
+
+func newFaultyStore() *faultyStore {
+	return &faultyStore{
+		values:  make(map[string]string),
+		calls:   make(map[string]int),
+		faults:  make(map[string]int),
+		corrupt: make(map[string]int),
+	}
+}
+
+func (s *faultyStore) failOn(op, name string, call int) {
+	s.faults[op+" "+name] = call
+}
+
+func (s *faultyStore) fault(op, name string) error {
+	key := op + " " + name
+	s.calls[key]++
+
+	if s.faults[key] == s.calls[key] {
+		return fmt.Errorf("%s %s: %w", op, name, errInjected)
+	}
+
+	return nil
+}
+
+func (s *faultyStore) GetParameter(name string) (string, error) {
+	if err := s.fault("get", name); err != nil {
+		return "", err
+	}
+
+	value, ok := s.values[name]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	}
+
+	if s.corrupt[name] == s.calls["get "+name] {
+		return value + "corrupted", nil
+	}
+
+	return value, nil
+}
+
+func (s *faultyStore) PutParameter(name, value, _ string) error {
+	if err := s.fault("put", name); err != nil {
+		return err
+	}
+
+	s.values[name] = value
+
+	return nil
+}
+
+func (s *faultyStore) CreateParameter(name, value, parameterType string) error {
+	if _, ok := s.values[name]; ok {
+		return fmt.Errorf("%s: %w", name, types.ErrParameterAlreadyExists)
+	}
+
+	return s.PutParameter(name, value, parameterType)
+}
+
+func (s *faultyStore) DeleteParameter(name string) error {
+	if err := s.fault("delete", name); err != nil {
+		return err
+	}
+
+	if _, ok := s.values[name]; !ok {
+		return fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	}
+
+	delete(s.values, name)
+
+	return nil
+}
+
+func (s *faultyStore) DescribeParameter(name string) (*types.ParameterMetadata, error) {
+	return &types.ParameterMetadata{Name: name}, nil
+}
+
+func seed(store *faultyStore) map[string]string {
+	store.values[privateKeyName] = "old private"
+	store.values[publicKeyName] = "old public"
+
+	return map[string]string{privateKeyName: "old private", publicKeyName: "old public"}
+}
+
+func TestRotateStoresMatchingPair(t *testing.T) {
+	store := newFaultyStore()
+	seed(store)
+
+	privateKey, publicKey, err := rotation.NewTransactionalRotator(store).Rotate(privateKeyName, publicKeyName, 2048)
+	require.NoError(t, err)
+	assert.Equal(t, &privateKey.PublicKey, publicKey)
+
+	publicKeyPEM, err := rotator.EncodePublicKeyToPEM(publicKey)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(rotator.EncodePrivateKeyToPEM(privateKey)), store.values[privateKeyName])
+	assert.Equal(t, string(publicKeyPEM), store.values[publicKeyName])
+}
+
+func TestRotateRollsBackOnFaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject func(store *faultyStore)
+	}{
+		{"private key write fails", func(s *faultyStore) { s.failOn("put", privateKeyName, 1) }},
+		{"public key write fails", func(s *faultyStore) { s.failOn("put", publicKeyName, 1) }},
+		// The first get of each name is the snapshot, the second the read back
+		{"private key read back fails", func(s *faultyStore) { s.failOn("get", privateKeyName, 2) }},
+		{"public key read back fails", func(s *faultyStore) { s.failOn("get", publicKeyName, 2) }},
+		{"read back mismatch", func(s *faultyStore) { s.corrupt[publicKeyName] = 2 }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newFaultyStore()
+			previous := seed(store)
+			test.inject(store)
+
+			_, _, err := rotation.NewTransactionalRotator(store).Rotate(privateKeyName, publicKeyName, 2048)
+			assert.ErrorIs(t, err, rotation.ErrRolledBack)
+			assert.Equal(t, previous, store.values)
+		})
+	}
+}
+
+func TestRotateRollsBackNewKeysByDeleting(t *testing.T) {
+	store := newFaultyStore()
+	store.failOn("put", publicKeyName, 1)
+
+	_, _, err := rotation.NewTransactionalRotator(store).Rotate(privateKeyName, publicKeyName, 2048)
+	assert.ErrorIs(t, err, rotation.ErrRolledBack)
+	assert.Empty(t, store.values)
+}
+
+func TestRotateReportsFailedRollback(t *testing.T) {
+	store := newFaultyStore()
+	seed(store)
+
+	store.failOn("put", publicKeyName, 1)
+	// The second put of the private key is the restore
+	store.failOn("put", privateKeyName, 2)
+
+	_, _, err := rotation.NewTransactionalRotator(store).Rotate(privateKeyName, publicKeyName, 2048)
+	assert.ErrorIs(t, err, rotation.ErrRollbackFailed)
+	assert.ErrorIs(t, err, errInjected)
+	assert.NotErrorIs(t, err, rotation.ErrRolledBack)
+}
+
+func TestRotateFailsBeforeWritingWhenSnapshotFails(t *testing.T) {
+	store := newFaultyStore()
+	previous := seed(store)
+	store.failOn("get", privateKeyName, 1)
+
+	_, _, err := rotation.NewTransactionalRotator(store).Rotate(privateKeyName, publicKeyName, 2048)
+	assert.ErrorIs(t, err, errInjected)
+	assert.NotErrorIs(t, err, rotation.ErrRolledBack)
+	assert.Equal(t, previous, store.values)
+	assert.Zero(t, store.calls["put "+privateKeyName])
+}
+
+func TestRotateValidatesInput(t *testing.T) {
+	store := newFaultyStore()
+
+	_, _, err := rotation.NewTransactionalRotator(store).Rotate("", publicKeyName, 2048)
+	assert.Error(t, err)
+
+	_, _, err = rotation.NewTransactionalRotator(store).Rotate(privateKeyName, publicKeyName, 1024)
+	assert.Error(t, err)
+	assert.Empty(t, store.values)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	store := newFaultyStore()
+	store.values[privateKeyName] = "old private"
+
+	snapshot, err := rotation.TakeSnapshot(store, privateKeyName, publicKeyName)
+	require.NoError(t, err)
+	assert.True(t, snapshot.Exists())
+
+	store.values[privateKeyName] = "new private"
+	store.values[publicKeyName] = "new public"
+
+	require.NoError(t, snapshot.Restore(store))
+	assert.Equal(t, map[string]string{privateKeyName: "old private"}, store.values)
+}
+
+// optionsStore records the options each parameter was written with.
+type optionsStore struct {
+	*faultyStore
+
+	options map[string]types.PutOptions
+}
+
+func (s *optionsStore) PutParameterWithOptions(name, value string, options types.PutOptions) error {
+	s.options[name] = options
+
+	return s.PutParameter(name, value, options.Type)
+}
+
+func TestRotateAppliesOptionsToBothHalves(t *testing.T) {
+	store := &optionsStore{faultyStore: newFaultyStore(), options: make(map[string]types.PutOptions)}
+	options := types.PutOptions{
+		KeyID: "alias/keys",
+		Tier:  "Advanced",
+		Tags:  map[string]string{"owner": "payments"},
+	}
+
+	r := rotation.NewTransactionalRotator(store)
+	r.Options = options
+
+	_, _, err := r.Rotate(privateKeyName, publicKeyName, 2048)
+	require.NoError(t, err)
+
+	options.Type = rotation.ParameterTypeSecureString
+	options.Overwrite = true
+
+	assert.Equal(t, options, store.options[privateKeyName])
+	assert.Equal(t, options, store.options[publicKeyName])
+}
+
+func TestRotateWithOptionsNeedsSupportingStore(t *testing.T) {
+	store := newFaultyStore()
+	expected := seed(store)
+
+	r := rotation.NewTransactionalRotator(store)
+	r.Options = types.PutOptions{Tier: "Advanced"}
+
+	_, _, err := r.Rotate(privateKeyName, publicKeyName, 2048)
+	assert.ErrorIs(t, err, rotation.ErrOptionsUnsupported)
+	assert.Equal(t, expected, store.values)
+
+	// Refused before writing anything, rather than rolled back
+	assert.Zero(t, store.calls["put "+privateKeyName])
+}
+
+func TestRotateRestoresWithOptions(t *testing.T) {
+	store := &optionsStore{faultyStore: newFaultyStore(), options: make(map[string]types.PutOptions)}
+	expected := seed(store.faultyStore)
+	store.failOn("put", publicKeyName, 1)
+
+	options := types.PutOptions{KeyID: "alias/keys", Tags: map[string]string{"owner": "payments"}}
+
+	r := rotation.NewTransactionalRotator(store)
+	r.Options = options
+
+	_, _, err := r.Rotate(privateKeyName, publicKeyName, 2048)
+	assert.ErrorIs(t, err, rotation.ErrRolledBack)
+	assert.Equal(t, expected, store.values)
+
+	// The restored private key keeps the KMS key it was rotated with
+	options.Type = rotation.ParameterTypeSecureString
+	options.Overwrite = true
+	assert.Equal(t, options, store.options[privateKeyName])
+	assert.Equal(t, 2, store.calls["put "+privateKeyName])
+}
+
+func TestSnapshotRestoreWithOptions(t *testing.T) {
+	store := &optionsStore{faultyStore: newFaultyStore(), options: make(map[string]types.PutOptions)}
+	seed(store.faultyStore)
+
+	snapshot, err := rotation.TakeSnapshot(store, privateKeyName, publicKeyName)
+	require.NoError(t, err)
+
+	snapshot.Options = types.PutOptions{KeyID: "alias/keys"}
+
+	require.NoError(t, snapshot.Restore(store))
+	assert.Equal(t, "alias/keys", store.options[privateKeyName].KeyID)
+	assert.Equal(t, "alias/keys", store.options[publicKeyName].KeyID)
+
+	// Without options, a store that cannot apply them still restores
+	snapshot.Options = types.PutOptions{}
+	require.NoError(t, snapshot.Restore(store.faultyStore))
+}
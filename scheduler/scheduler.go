@@ -0,0 +1,259 @@
+// Package scheduler runs key rotations on a per-key schedule.
+//
+// Each Job pairs a key name with a Schedule, either a fixed interval or a
+// standard five field cron expression. The Scheduler sleeps until the next
+// job is due, rotates it through a types.KeyRotatorInterface, or a Rotate
+// function wrapping one, and reports the outcome of every job over HTTP.
+// Time is read through a Clock so the loop can be driven deterministically
+// in tests.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	klog "github.com/kmesiab/go-klogger"
+	"github.com/robfig/cron/v3"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// Clock abstracts time so the scheduler can be tested without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock backed by the time package.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Schedule returns the next activation time after t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// IntervalSchedule activates at a fixed interval.
+type IntervalSchedule time.Duration
+
+func (i IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// ParseCron parses a standard five field cron expression, or a descriptor
+// such as "@daily".
+func ParseCron(expression string) (Schedule, error) {
+	return cron.ParseStandard(expression)
+}
+
+type Job struct {
+	Name     string
+	KeySize  int
+	Schedule Schedule
+}
+
+// JobStatus is the health report for a single job.
+type JobStatus struct {
+	Name      string    `json:"name"`
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Rotations int       `json:"rotations"`
+}
+
+type Scheduler struct {
+	Jobs       []Job
+	KeyRotator types.KeyRotatorInterface
+	Clock      Clock
+
+	// Rotate, when set, rotates each due job in place of KeyRotator, so
+	// callers can run hooks and record the rotation around it.
+	Rotate func(job Job) error
+
+	// AfterRotate, when set, is called with the outcome of every rotation.
+	AfterRotate func(job Job, err error)
+
+	mu       sync.Mutex
+	statuses map[string]*JobStatus
+}
+
+func NewScheduler(keyRotator types.KeyRotatorInterface, clock Clock, jobs []Job) *Scheduler {
+	return &Scheduler{
+		Jobs:       jobs,
+		KeyRotator: keyRotator,
+		Clock:      clock,
+		statuses:   make(map[string]*JobStatus, len(jobs)),
+	}
+}
+
+// Run rotates keys as their schedules come due until ctx is cancelled. A
+// rotation that is already in progress when ctx is cancelled runs to
+// completion before Run returns, so a key pair is never left half written.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.Jobs) == 0 {
+		return errors.New("no jobs to schedule")
+	}
+
+	now := s.Clock.Now()
+
+	for _, job := range s.Jobs {
+		s.setStatus(job.Name, func(status *JobStatus) {
+			status.NextRun = job.Schedule.Next(now)
+		})
+	}
+
+	for {
+		wait := s.nextRun().Sub(s.Clock.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.Clock.After(wait):
+		}
+
+		for _, job := range s.dueJobs() {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			s.runJob(job)
+		}
+	}
+}
+
+// Statuses returns a snapshot of every job's status, in job order.
+func (s *Scheduler) Statuses() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.Jobs))
+
+	for _, job := range s.Jobs {
+		if status, ok := s.statuses[job.Name]; ok {
+			statuses = append(statuses, *status)
+		}
+	}
+
+	return statuses
+}
+
+// ServeHTTP reports job statuses as JSON. It responds 503 when the most
+// recent run of any job failed.
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	statuses := s.Statuses()
+	code := http.StatusOK
+
+	for _, status := range statuses {
+		if status.LastError != "" {
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"jobs": statuses}); err != nil {
+		klog.Logf("Error writing health response: %s", err).Error()
+	}
+}
+
+func (s *Scheduler) runJob(job Job) {
+	klog.Logf("Rotating scheduled key '%s'", job.Name).Info()
+
+	err := s.rotate(job)
+
+	now := s.Clock.Now()
+
+	s.setStatus(job.Name, func(status *JobStatus) {
+		status.LastRun = now
+		status.NextRun = job.Schedule.Next(now)
+		status.LastError = ""
+
+		if err != nil {
+			status.LastError = err.Error()
+
+			return
+		}
+
+		status.Rotations++
+	})
+
+	if err != nil {
+		klog.Logf("Error rotating scheduled key '%s': %s", job.Name, err).Error()
+	}
+
+	if s.AfterRotate != nil {
+		s.AfterRotate(job, err)
+	}
+}
+
+func (s *Scheduler) rotate(job Job) error {
+	if s.Rotate != nil {
+		return s.Rotate(job)
+	}
+
+	_, _, err := s.KeyRotator.Rotate(
+		aws.MakePrivateKeyName(job.Name),
+		aws.MakePublicKeyName(job.Name),
+		job.KeySize,
+	)
+
+	return err
+}
+
+func (s *Scheduler) nextRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+
+	for _, status := range s.statuses {
+		if next.IsZero() || status.NextRun.Before(next) {
+			next = status.NextRun
+		}
+	}
+
+	return next
+}
+
+func (s *Scheduler) dueJobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Job
+
+	now := s.Clock.Now()
+
+	for _, job := range s.Jobs {
+		if !s.statuses[job.Name].NextRun.After(now) {
+			due = append(due, job)
+		}
+	}
+
+	return due
+}
+
+func (s *Scheduler) setStatus(name string, update func(status *JobStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[name]
+	if !ok {
+		status = &JobStatus{Name: name}
+		s.statuses[name] = status
+	}
+
+	update(status)
+}
@@ -0,0 +1,277 @@
+package scheduler_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/scheduler"
+)
+
+// fakeClock only moves when Advance is called.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	waiting chan struct{}
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waiting: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{deadline: c.now.Add(d), ch: ch})
+	c.waiting <- struct{}{}
+
+	return ch
+}
+
+// Advance moves the clock forward and fires any expired timers.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.waiters[:0]
+
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+
+			continue
+		}
+
+		w.ch <- c.now
+	}
+
+	c.waiters = pending
+}
+
+// awaitTimer blocks until the scheduler has started waiting on the clock.
+func (c *fakeClock) awaitTimer(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-c.waiting:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler never waited on the clock")
+	}
+}
+
+type recordingRotator struct {
+	mu    sync.Mutex
+	calls []string
+	err   error
+}
+
+func (r *recordingRotator) Rotate(privateKeyName, _ string, _ int) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, privateKeyName)
+
+	return nil, nil, r.err
+}
+
+func (r *recordingRotator) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.calls...)
+}
+
+func startScheduler(t *testing.T, sched *scheduler.Scheduler) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- sched.Run(ctx) }()
+
+	return cancel, done
+}
+
+func TestSchedulerRunsJobsOnInterval(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	rotator := &recordingRotator{}
+
+	sched := scheduler.NewScheduler(rotator, clock, []scheduler.Job{
+		{Name: "hourly", KeySize: 2048, Schedule: scheduler.IntervalSchedule(time.Hour)},
+		{Name: "daily", KeySize: 2048, Schedule: scheduler.IntervalSchedule(24 * time.Hour)},
+	})
+
+	cancel, done := startScheduler(t, sched)
+
+	for i := 0; i < 24; i++ {
+		clock.awaitTimer(t)
+		clock.Advance(time.Hour)
+	}
+
+	clock.awaitTimer(t)
+	cancel()
+	require.NoError(t, <-done)
+
+	hourly, daily := 0, 0
+
+	for _, call := range rotator.Calls() {
+		switch call {
+		case aws.MakePrivateKeyName("hourly"):
+			hourly++
+		case aws.MakePrivateKeyName("daily"):
+			daily++
+		}
+	}
+
+	assert.Equal(t, 24, hourly)
+	assert.Equal(t, 1, daily)
+}
+
+func TestSchedulerRunsCronJobs(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	rotator := &recordingRotator{}
+
+	schedule, err := scheduler.ParseCron("30 3 * * *")
+	require.NoError(t, err)
+
+	sched := scheduler.NewScheduler(rotator, clock, []scheduler.Job{
+		{Name: "nightly", KeySize: 2048, Schedule: schedule},
+	})
+
+	cancel, done := startScheduler(t, sched)
+
+	clock.awaitTimer(t)
+
+	// Not yet due, so the timer does not fire
+	clock.Advance(3 * time.Hour)
+	assert.Empty(t, rotator.Calls())
+
+	clock.Advance(30 * time.Minute)
+	clock.awaitTimer(t)
+	assert.Len(t, rotator.Calls(), 1)
+
+	statuses := sched.Statuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 30, 0, 0, time.UTC), statuses[0].NextRun)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestSchedulerHealthReportsFailures(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	rotator := &recordingRotator{}
+
+	sched := scheduler.NewScheduler(rotator, clock, []scheduler.Job{
+		{Name: "flaky", KeySize: 2048, Schedule: scheduler.IntervalSchedule(time.Minute)},
+	})
+
+	cancel, done := startScheduler(t, sched)
+
+	clock.awaitTimer(t)
+	clock.Advance(time.Minute)
+	clock.awaitTimer(t)
+
+	recorder := httptest.NewRecorder()
+	sched.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	rotator.mu.Lock()
+	rotator.err = errors.New("parameter store unavailable")
+	rotator.mu.Unlock()
+
+	clock.Advance(time.Minute)
+	clock.awaitTimer(t)
+
+	recorder = httptest.NewRecorder()
+	sched.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var body struct {
+		Jobs []scheduler.JobStatus `json:"jobs"`
+	}
+
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Len(t, body.Jobs, 1)
+	assert.Equal(t, "parameter store unavailable", body.Jobs[0].LastError)
+	assert.Equal(t, 1, body.Jobs[0].Rotations)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestSchedulerRotatesThroughRotate(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	rotator := &recordingRotator{}
+
+	sched := scheduler.NewScheduler(rotator, clock, []scheduler.Job{
+		{Name: "hourly", KeySize: 2048, Schedule: scheduler.IntervalSchedule(time.Hour)},
+	})
+
+	var (
+		mu      sync.Mutex
+		rotated []string
+	)
+
+	sched.Rotate = func(job scheduler.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rotated = append(rotated, job.Name)
+
+		return nil
+	}
+
+	cancel, done := startScheduler(t, sched)
+
+	clock.awaitTimer(t)
+	clock.Advance(time.Hour)
+	clock.awaitTimer(t)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"hourly"}, rotated)
+	assert.Empty(t, rotator.Calls())
+	assert.Equal(t, 1, sched.Statuses()[0].Rotations)
+}
+
+func TestSchedulerRequiresJobs(t *testing.T) {
+	sched := scheduler.NewScheduler(&recordingRotator{}, scheduler.SystemClock{}, nil)
+	assert.Error(t, sched.Run(context.Background()))
+}
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	_, err := scheduler.ParseCron("every tuesday")
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package ssmfake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+)
+
+// targetPrefix starts the X-Amz-Target header of every SSM request.
+const targetPrefix = "AmazonSSM."
+
+// ServeHTTP answers SSM requests made with the AWS JSON 1.1 protocol, so s
+// can be the endpoint of a real SDK client. Requests are not authenticated.
+func (s *SSM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), targetPrefix)
+
+	handler, ok := map[string]func(context.Context, *json.Decoder) (any, error){
+		OperationGetParameter:       s.serveGetParameter,
+		OperationPutParameter:       s.servePutParameter,
+		OperationDeleteParameter:    s.serveDeleteParameter,
+		OperationDescribeParameters: s.serveDescribeParameters,
+		OperationAddTagsToResource:  s.serveAddTagsToResource,
+	}[operation]
+	if !ok {
+		writeError(w, &smithy.GenericAPIError{
+			Code:    "UnknownOperationException",
+			Message: fmt.Sprintf("operation '%s' is not supported", operation),
+			Fault:   smithy.FaultClient,
+		})
+
+		return
+	}
+
+	response, err := handler(r.Context(), json.NewDecoder(r.Body))
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *SSM) serveGetParameter(ctx context.Context, body *json.Decoder) (any, error) {
+	var input ssm.GetParameterInput
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+
+	output, err := s.GetParameter(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	parameter := map[string]any{
+		"Name":             awssdk.ToString(output.Parameter.Name),
+		"Type":             output.Parameter.Type,
+		"Value":            awssdk.ToString(output.Parameter.Value),
+		"Version":          output.Parameter.Version,
+		"LastModifiedDate": epochSeconds(awssdk.ToTime(output.Parameter.LastModifiedDate)),
+		"ARN":              awssdk.ToString(output.Parameter.ARN),
+		"DataType":         awssdk.ToString(output.Parameter.DataType),
+	}
+
+	if output.Parameter.Selector != nil {
+		parameter["Selector"] = awssdk.ToString(output.Parameter.Selector)
+	}
+
+	return map[string]any{"Parameter": parameter}, nil
+}
+
+func (s *SSM) servePutParameter(ctx context.Context, body *json.Decoder) (any, error) {
+	var input ssm.PutParameterInput
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+
+	output, err := s.PutParameter(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{"Version": output.Version, "Tier": output.Tier}, nil
+}
+
+func (s *SSM) serveDeleteParameter(ctx context.Context, body *json.Decoder) (any, error) {
+	var input ssm.DeleteParameterInput
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.DeleteParameter(ctx, &input); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{}, nil
+}
+
+func (s *SSM) serveDescribeParameters(ctx context.Context, body *json.Decoder) (any, error) {
+	var input ssm.DescribeParametersInput
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+
+	output, err := s.DescribeParameters(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make([]map[string]any, 0, len(output.Parameters))
+
+	for _, metadata := range output.Parameters {
+		policies := make([]map[string]any, 0, len(metadata.Policies))
+		for _, policy := range metadata.Policies {
+			policies = append(policies, map[string]any{
+				"PolicyText":   awssdk.ToString(policy.PolicyText),
+				"PolicyType":   awssdk.ToString(policy.PolicyType),
+				"PolicyStatus": awssdk.ToString(policy.PolicyStatus),
+			})
+		}
+
+		parameter := map[string]any{
+			"Name":             awssdk.ToString(metadata.Name),
+			"Type":             metadata.Type,
+			"Tier":             metadata.Tier,
+			"Version":          metadata.Version,
+			"LastModifiedDate": epochSeconds(awssdk.ToTime(metadata.LastModifiedDate)),
+			"DataType":         awssdk.ToString(metadata.DataType),
+			"Policies":         policies,
+		}
+
+		if metadata.KeyId != nil {
+			parameter["KeyId"] = awssdk.ToString(metadata.KeyId)
+		}
+
+		parameters = append(parameters, parameter)
+	}
+
+	return map[string]any{"Parameters": parameters}, nil
+}
+
+func (s *SSM) serveAddTagsToResource(ctx context.Context, body *json.Decoder) (any, error) {
+	var input ssm.AddTagsToResourceInput
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.AddTagsToResource(ctx, &input); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{}, nil
+}
+
+// decode reads a request body into one of the SDK's input structs, whose
+// field names match the protocol's.
+func decode(body *json.Decoder, input any) error {
+	if err := body.Decode(input); err != nil {
+		return &smithy.GenericAPIError{
+			Code:    "SerializationException",
+			Message: err.Error(),
+			Fault:   smithy.FaultClient,
+		}
+	}
+
+	return nil
+}
+
+// writeError sends err the way SSM does. Errors that are not API errors,
+// such as injected ones, become internal server errors.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = &ssmtypes.InternalServerError{Message: awssdk.String(err.Error())}
+	}
+
+	status := http.StatusBadRequest
+	if apiErr.ErrorFault() == smithy.FaultServer {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-Errortype", apiErr.ErrorCode())
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"__type":  apiErr.ErrorCode(),
+		"message": apiErr.ErrorMessage(),
+	})
+}
+
+// epochSeconds formats t the way the protocol sends timestamps.
+func epochSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,630 @@
+// Package ssmfake is an in-memory stand-in for AWS Systems Manager
+// Parameter Store, for tests. SSM implements aws.SSMAPI for use in process,
+// and is an http.Handler speaking the SSM JSON protocol, so the real SDK can
+// reach it through --endpoint-url.
+//
+// It keeps a version history per parameter, models SecureString encryption,
+// the Standard and Advanced tiers and parameter policies, and can inject
+// faults into any operation.
+package ssmfake
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+)
+
+// Operations the fake supports, as named by the SSM API.
+const (
+	OperationGetParameter       = "GetParameter"
+	OperationPutParameter       = "PutParameter"
+	OperationDeleteParameter    = "DeleteParameter"
+	OperationDescribeParameters = "DescribeParameters"
+	OperationAddTagsToResource  = "AddTagsToResource"
+)
+
+// Parameter Store limits the fake enforces.
+const (
+	MaxVersions           = 100
+	MaxTags               = 50
+	MaxStandardValueBytes = 4 * 1024
+	MaxAdvancedValueBytes = 8 * 1024
+)
+
+// DefaultKeyID encrypts SecureString parameters stored without a KMS key.
+const DefaultKeyID = "alias/aws/ssm"
+
+// Version is one stored value of a parameter.
+type Version struct {
+	Value        string
+	Version      int64
+	LastModified time.Time
+}
+
+// Parameter is a stored parameter and its history, oldest version first.
+type Parameter struct {
+	Name     string
+	Type     ssmtypes.ParameterType
+	KeyID    string
+	Tier     ssmtypes.ParameterTier
+	Policies []string
+	Tags     map[string]string
+	Versions []Version
+}
+
+// Latest returns the current version of the parameter.
+func (p Parameter) Latest() Version {
+	return p.Versions[len(p.Versions)-1]
+}
+
+// Fault makes matching calls fail instead of running.
+type Fault struct {
+	// Operation and Name restrict the fault to one operation and one
+	// parameter. Empty values match every call.
+	Operation string
+	Name      string
+
+	// Err is returned by each matching call. With Hang set, calls instead
+	// block until their context is done.
+	Err  error
+	Hang bool
+
+	// Times is how many calls fail before the fault clears. Zero means
+	// every matching call fails.
+	Times int
+}
+
+// SSM is an in-memory Parameter Store. The zero value is not usable; create
+// one with New.
+type SSM struct {
+	// Now returns the time parameters are modified at.
+	Now func() time.Time
+
+	// Region is the region in the parameters' ARNs, which count towards
+	// the length of their names.
+	Region string
+
+	mu         sync.Mutex
+	parameters map[string]*Parameter
+	faults     []*Fault
+	calls      map[string]int
+}
+
+var _ aws.SSMAPI = (*SSM)(nil)
+
+// DefaultRegion is the Region of a new SSM.
+const DefaultRegion = "us-east-1"
+
+// New returns an empty Parameter Store.
+func New() *SSM {
+	return &SSM{
+		Now:        time.Now,
+		Region:     DefaultRegion,
+		parameters: make(map[string]*Parameter),
+		calls:      make(map[string]int),
+	}
+}
+
+// Inject adds a fault. Faults are matched in the order they were added.
+func (s *SSM) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+// Calls returns how many times operation was called, including calls that
+// failed.
+func (s *SSM) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[operation]
+}
+
+// Parameter returns a copy of the named parameter.
+func (s *SSM) Parameter(name string) (Parameter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parameter, ok := s.parameters[name]
+	if !ok {
+		return Parameter{}, false
+	}
+
+	clone := *parameter
+	clone.Policies = append([]string(nil), parameter.Policies...)
+	clone.Versions = append([]Version(nil), parameter.Versions...)
+	clone.Tags = make(map[string]string, len(parameter.Tags))
+
+	for key, value := range parameter.Tags {
+		clone.Tags[key] = value
+	}
+
+	return clone, true
+}
+
+// begin records a call and returns the first fault matching it, if any. It
+// must be called with s.mu held.
+func (s *SSM) begin(operation, name string) *Fault {
+	s.calls[operation]++
+
+	for i, fault := range s.faults {
+		if fault.Operation != "" && fault.Operation != operation {
+			continue
+		}
+
+		if fault.Name != "" && fault.Name != name {
+			continue
+		}
+
+		if fault.Times > 0 {
+			if fault.Times--; fault.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+
+		return fault
+	}
+
+	return nil
+}
+
+// call runs operation under the lock, unless a fault fails it first. A
+// hanging fault waits for ctx without holding the lock.
+func (s *SSM) call(ctx context.Context, operation, name string, run func() error) error {
+	s.mu.Lock()
+	fault := s.begin(operation, name)
+
+	if fault == nil {
+		defer s.mu.Unlock()
+
+		return run()
+	}
+
+	s.mu.Unlock()
+
+	if fault.Hang {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	return fault.Err
+}
+
+// GetParameter returns the latest version of a parameter, or the version
+// selected with a "name:version" suffix.
+func (s *SSM) GetParameter(ctx context.Context, input *ssm.GetParameterInput,
+	_ ...func(*ssm.Options),
+) (*ssm.GetParameterOutput, error) {
+	name, selector := splitSelector(awssdk.ToString(input.Name))
+	output := &ssm.GetParameterOutput{}
+
+	err := s.call(ctx, OperationGetParameter, name, func() error {
+		parameter, ok := s.parameters[name]
+		if !ok {
+			return &ssmtypes.ParameterNotFound{Message: awssdk.String(name)}
+		}
+
+		version := parameter.Latest()
+
+		if selector != "" {
+			number, err := strconv.ParseInt(selector, 10, 64)
+			if err != nil {
+				return validationError("version selectors must be numbers, not '%s'", selector)
+			}
+
+			if version, ok = parameter.version(number); !ok {
+				return &ssmtypes.ParameterVersionNotFound{
+					Message: awssdk.String(fmt.Sprintf("%s version %d", name, number)),
+				}
+			}
+		}
+
+		value := version.Value
+		if parameter.Type == ssmtypes.ParameterTypeSecureString && !awssdk.ToBool(input.WithDecryption) {
+			value = encrypt(parameter.KeyID, value)
+		}
+
+		output.Parameter = &ssmtypes.Parameter{
+			Name:             awssdk.String(name),
+			Type:             parameter.Type,
+			Value:            awssdk.String(value),
+			Version:          version.Version,
+			LastModifiedDate: awssdk.Time(version.LastModified),
+			ARN:              awssdk.String("arn:aws:ssm:us-east-1:123456789012:parameter/" + strings.TrimPrefix(name, "/")),
+			DataType:         awssdk.String("text"),
+		}
+
+		if selector != "" {
+			output.Parameter.Selector = awssdk.String(":" + selector)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// PutParameter stores a new version of a parameter.
+func (s *SSM) PutParameter(ctx context.Context, input *ssm.PutParameterInput,
+	_ ...func(*ssm.Options),
+) (*ssm.PutParameterOutput, error) {
+	name := awssdk.ToString(input.Name)
+	output := &ssm.PutParameterOutput{}
+
+	err := s.call(ctx, OperationPutParameter, name, func() error {
+		if err := aws.ValidateParameterStoreName(name, s.Region); err != nil {
+			return validationError("%s", err)
+		}
+
+		value := awssdk.ToString(input.Value)
+		if value == "" {
+			return validationError("parameter value cannot be empty")
+		}
+
+		existing, exists := s.parameters[name]
+
+		if exists && !awssdk.ToBool(input.Overwrite) {
+			return &ssmtypes.ParameterAlreadyExists{Message: awssdk.String(name)}
+		}
+
+		if awssdk.ToBool(input.Overwrite) && len(input.Tags) > 0 {
+			return validationError("tags and overwrite can't be used together")
+		}
+
+		parameter := &Parameter{Name: name, Tags: map[string]string{}}
+		if exists {
+			clone := *existing
+			parameter = &clone
+		}
+
+		if err := parameter.apply(input); err != nil {
+			return err
+		}
+
+		for _, tag := range input.Tags {
+			parameter.Tags[awssdk.ToString(tag.Key)] = awssdk.ToString(tag.Value)
+		}
+
+		parameter.Versions = append(parameter.Versions, Version{
+			Value:        value,
+			Version:      parameter.nextVersion(),
+			LastModified: s.Now(),
+		})
+
+		if len(parameter.Versions) > MaxVersions {
+			parameter.Versions = parameter.Versions[len(parameter.Versions)-MaxVersions:]
+		}
+
+		s.parameters[name] = parameter
+
+		output.Version = parameter.Latest().Version
+		output.Tier = parameter.Tier
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// apply sets the type, KMS key, tier and policies of a put on p.
+func (p *Parameter) apply(input *ssm.PutParameterInput) error {
+	switch input.Type {
+	case "":
+		if p.Type == "" {
+			return validationError("a type is required for a new parameter")
+		}
+	case ssmtypes.ParameterTypeString, ssmtypes.ParameterTypeStringList, ssmtypes.ParameterTypeSecureString:
+		p.Type = input.Type
+	default:
+		return &ssmtypes.UnsupportedParameterType{Message: awssdk.String(string(input.Type))}
+	}
+
+	switch {
+	case input.KeyId != nil && p.Type != ssmtypes.ParameterTypeSecureString:
+		return validationError("a KMS key can only encrypt a SecureString parameter")
+	case input.KeyId != nil:
+		p.KeyID = awssdk.ToString(input.KeyId)
+	case p.Type == ssmtypes.ParameterTypeSecureString:
+		// Like Parameter Store, a put without a key uses the default key,
+		// even when the parameter was encrypted with another
+		p.KeyID = DefaultKeyID
+	case p.Type != ssmtypes.ParameterTypeSecureString:
+		p.KeyID = ""
+	}
+
+	policies, err := parsePolicies(awssdk.ToString(input.Policies))
+	if err != nil {
+		return err
+	}
+
+	if input.Policies != nil {
+		p.Policies = policies
+	}
+
+	return p.setTier(input.Tier, len(awssdk.ToString(input.Value)))
+}
+
+// setTier applies the requested tier. Intelligent-Tiering picks Advanced
+// only when the parameter needs it. Advanced parameters cannot go back to
+// Standard.
+func (p *Parameter) setTier(requested ssmtypes.ParameterTier, size int) error {
+	needsAdvanced := len(p.Policies) > 0 || size > MaxStandardValueBytes
+
+	switch requested {
+	case "":
+		if p.Tier == "" {
+			p.Tier = ssmtypes.ParameterTierStandard
+		}
+	case ssmtypes.ParameterTierIntelligentTiering:
+		if needsAdvanced {
+			p.Tier = ssmtypes.ParameterTierAdvanced
+		} else if p.Tier == "" {
+			p.Tier = ssmtypes.ParameterTierStandard
+		}
+	case ssmtypes.ParameterTierStandard:
+		if p.Tier == ssmtypes.ParameterTierAdvanced {
+			return validationError("an Advanced parameter cannot be changed to the Standard tier")
+		}
+
+		p.Tier = requested
+	case ssmtypes.ParameterTierAdvanced:
+		p.Tier = requested
+	default:
+		return validationError("unknown tier '%s'", requested)
+	}
+
+	if p.Tier == ssmtypes.ParameterTierStandard {
+		if len(p.Policies) > 0 {
+			return &ssmtypes.InvalidPolicyTypeException{
+				Message: awssdk.String("parameter policies need the Advanced tier"),
+			}
+		}
+
+		if size > MaxStandardValueBytes {
+			return validationError("Standard parameters hold at most %d bytes", MaxStandardValueBytes)
+		}
+	}
+
+	if size > MaxAdvancedValueBytes {
+		return validationError("Advanced parameters hold at most %d bytes", MaxAdvancedValueBytes)
+	}
+
+	return nil
+}
+
+// DeleteParameter removes a parameter and its history.
+func (s *SSM) DeleteParameter(ctx context.Context, input *ssm.DeleteParameterInput,
+	_ ...func(*ssm.Options),
+) (*ssm.DeleteParameterOutput, error) {
+	name := awssdk.ToString(input.Name)
+
+	err := s.call(ctx, OperationDeleteParameter, name, func() error {
+		if _, ok := s.parameters[name]; !ok {
+			return &ssmtypes.ParameterNotFound{Message: awssdk.String(name)}
+		}
+
+		delete(s.parameters, name)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+// DescribeParameters returns the metadata of the parameters matching the
+// Name filters, which may use the Equals or BeginsWith options.
+func (s *SSM) DescribeParameters(ctx context.Context, input *ssm.DescribeParametersInput,
+	_ ...func(*ssm.Options),
+) (*ssm.DescribeParametersOutput, error) {
+	output := &ssm.DescribeParametersOutput{}
+
+	err := s.call(ctx, OperationDescribeParameters, "", func() error {
+		names := make([]string, 0, len(s.parameters))
+
+		for name := range s.parameters {
+			matches, err := matchesFilters(name, input.ParameterFilters)
+			if err != nil {
+				return err
+			}
+
+			if matches {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			output.Parameters = append(output.Parameters, s.parameters[name].metadata())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// AddTagsToResource adds or replaces tags on a parameter.
+func (s *SSM) AddTagsToResource(ctx context.Context, input *ssm.AddTagsToResourceInput,
+	_ ...func(*ssm.Options),
+) (*ssm.AddTagsToResourceOutput, error) {
+	name := awssdk.ToString(input.ResourceId)
+
+	err := s.call(ctx, OperationAddTagsToResource, name, func() error {
+		if input.ResourceType != ssmtypes.ResourceTypeForTaggingParameter {
+			return validationError("only parameters can be tagged, not '%s'", input.ResourceType)
+		}
+
+		parameter, ok := s.parameters[name]
+		if !ok {
+			return &ssmtypes.InvalidResourceId{Message: awssdk.String(name)}
+		}
+
+		tags := make(map[string]string, len(parameter.Tags)+len(input.Tags))
+		for key, value := range parameter.Tags {
+			tags[key] = value
+		}
+
+		for _, tag := range input.Tags {
+			tags[awssdk.ToString(tag.Key)] = awssdk.ToString(tag.Value)
+		}
+
+		if len(tags) > MaxTags {
+			return &ssmtypes.TooManyTagsError{Message: awssdk.String(name)}
+		}
+
+		parameter.Tags = tags
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+func (p *Parameter) version(number int64) (Version, bool) {
+	for _, version := range p.Versions {
+		if version.Version == number {
+			return version, true
+		}
+	}
+
+	return Version{}, false
+}
+
+func (p *Parameter) nextVersion() int64 {
+	if len(p.Versions) == 0 {
+		return 1
+	}
+
+	return p.Latest().Version + 1
+}
+
+func (p *Parameter) metadata() ssmtypes.ParameterMetadata {
+	latest := p.Latest()
+	metadata := ssmtypes.ParameterMetadata{
+		Name:             awssdk.String(p.Name),
+		Type:             p.Type,
+		Tier:             p.Tier,
+		Version:          latest.Version,
+		LastModifiedDate: awssdk.Time(latest.LastModified),
+		DataType:         awssdk.String("text"),
+	}
+
+	if p.KeyID != "" {
+		metadata.KeyId = awssdk.String(p.KeyID)
+	}
+
+	for _, policy := range p.Policies {
+		var parsed struct{ Type string }
+
+		_ = json.Unmarshal([]byte(policy), &parsed)
+
+		metadata.Policies = append(metadata.Policies, ssmtypes.ParameterInlinePolicy{
+			PolicyText:   awssdk.String(policy),
+			PolicyType:   awssdk.String(parsed.Type),
+			PolicyStatus: awssdk.String("Pending"),
+		})
+	}
+
+	return metadata
+}
+
+func matchesFilters(name string, filters []ssmtypes.ParameterStringFilter) (bool, error) {
+	for _, filter := range filters {
+		if awssdk.ToString(filter.Key) != "Name" {
+			return false, validationError("only the Name filter is supported, not '%s'", awssdk.ToString(filter.Key))
+		}
+
+		matched := false
+
+		for _, value := range filter.Values {
+			switch option := awssdk.ToString(filter.Option); option {
+			case "", "Equals":
+				matched = matched || name == value
+			case "BeginsWith":
+				matched = matched || strings.HasPrefix(name, value)
+			default:
+				return false, validationError("unsupported filter option '%s'", option)
+			}
+		}
+
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// parsePolicies splits a JSON array of policies into the text of each.
+func parsePolicies(text string) ([]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, &ssmtypes.InvalidPolicyTypeException{Message: awssdk.String(err.Error())}
+	}
+
+	policies := make([]string, 0, len(raw))
+	for _, policy := range raw {
+		policies = append(policies, string(policy))
+	}
+
+	return policies, nil
+}
+
+// splitSelector splits "name:3" into the name and the version selector.
+func splitSelector(name string) (string, string) {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+
+	return name, ""
+}
+
+// encrypt stands in for the ciphertext SSM returns for a SecureString read
+// without decryption. It is not encryption.
+func encrypt(keyID, value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(keyID + ":" + value))
+}
+
+func validationError(format string, a ...any) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(format, a...),
+		Fault:   smithy.FaultClient,
+	}
+}
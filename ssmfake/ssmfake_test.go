@@ -0,0 +1,237 @@
+package ssmfake_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/aws"
+	"github.com/kmesiab/go-key-rotator-cli/ssmfake"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+func TestVersionsAndSelectors(t *testing.T) {
+	fake := ssmfake.New()
+	store := &aws.ParameterStore{SSM: fake}
+
+	require.NoError(t, store.CreateParameter("/prod/signing_priv.pem", "one", "SecureString"))
+	require.NoError(t, store.PutParameter("/prod/signing_priv.pem", "two", "SecureString"))
+
+	value, err := store.GetParameter("/prod/signing_priv.pem")
+	require.NoError(t, err)
+	assert.Equal(t, "two", value)
+
+	value, err = store.GetParameter("/prod/signing_priv.pem:1")
+	require.NoError(t, err)
+	assert.Equal(t, "one", value)
+
+	_, err = fake.GetParameter(context.Background(), &ssm.GetParameterInput{
+		Name: awssdk.String("/prod/signing_priv.pem:3"),
+	})
+	var notFound *ssmtypes.ParameterVersionNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	err = store.CreateParameter("/prod/signing_priv.pem", "three", "SecureString")
+	assert.ErrorIs(t, err, types.ErrParameterAlreadyExists)
+
+	parameter, ok := fake.Parameter("/prod/signing_priv.pem")
+	require.True(t, ok)
+	assert.Len(t, parameter.Versions, 2)
+	assert.Equal(t, int64(2), parameter.Latest().Version)
+}
+
+func TestSecureString(t *testing.T) {
+	fake := ssmfake.New()
+	store := &aws.ParameterStore{SSM: fake}
+
+	require.NoError(t, store.PutParameter("key_priv.pem", "secret", "SecureString"))
+
+	output, err := fake.GetParameter(context.Background(), &ssm.GetParameterInput{
+		Name: awssdk.String("key_priv.pem"),
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, "secret", awssdk.ToString(output.Parameter.Value))
+
+	parameter, _ := fake.Parameter("key_priv.pem")
+	assert.Equal(t, ssmfake.DefaultKeyID, parameter.KeyID)
+
+	// Overwriting without a key goes back to the default key
+	require.NoError(t, store.PutParameterWithOptions("key_priv.pem", "secret", types.PutOptions{
+		Type:      "SecureString",
+		KeyID:     "alias/rotator",
+		Overwrite: true,
+	}))
+	require.NoError(t, store.PutParameter("key_priv.pem", "secret", "SecureString"))
+
+	parameter, _ = fake.Parameter("key_priv.pem")
+	assert.Equal(t, ssmfake.DefaultKeyID, parameter.KeyID)
+
+	err = store.PutParameterWithOptions("key_pub.pem", "public", types.PutOptions{
+		Type:  "String",
+		KeyID: "alias/rotator",
+	})
+	assert.ErrorContains(t, err, "only encrypt a SecureString")
+}
+
+func TestTiersAndPolicies(t *testing.T) {
+	fake := ssmfake.New()
+	store := &aws.ParameterStore{SSM: fake}
+	policy := aws.ExpirationPolicy(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	err := store.PutParameterWithOptions("key_priv.pem", "secret", types.PutOptions{
+		Type:     "SecureString",
+		Tier:     "Standard",
+		Policies: []string{policy},
+	})
+	var invalidPolicy *ssmtypes.InvalidPolicyTypeException
+	assert.ErrorAs(t, err, &invalidPolicy)
+
+	require.NoError(t, store.PutParameterWithOptions("key_priv.pem", "secret", types.PutOptions{
+		Type:     "SecureString",
+		Tier:     "Intelligent-Tiering",
+		Policies: []string{policy},
+		Tags:     map[string]string{"team": "payments"},
+	}))
+
+	parameter, _ := fake.Parameter("key_priv.pem")
+	assert.Equal(t, ssmtypes.ParameterTierAdvanced, parameter.Tier)
+	assert.Equal(t, map[string]string{"team": "payments"}, parameter.Tags)
+
+	policies, err := store.ParameterPolicies("key_priv.pem")
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, aws.PolicyTypeExpiration, policies[0].Type)
+
+	err = store.PutParameterWithOptions("key_priv.pem", "secret", types.PutOptions{Tier: "Standard", Overwrite: true})
+	assert.ErrorContains(t, err, "cannot be changed to the Standard tier")
+
+	err = store.PutParameterWithOptions("key_priv.pem", "secret", types.PutOptions{Type: "SecureString"})
+	assert.ErrorIs(t, err, types.ErrParameterAlreadyExists, "creates unless asked to overwrite")
+
+	err = store.PutParameter("big_priv.pem", strings.Repeat("x", ssmfake.MaxStandardValueBytes+1), "SecureString")
+	assert.ErrorContains(t, err, "Standard parameters hold at most")
+}
+
+func TestDeleteAndDescribe(t *testing.T) {
+	fake := ssmfake.New()
+	store := &aws.ParameterStore{SSM: fake}
+
+	require.NoError(t, store.PutParameter("/prod/a_pub.pem", "a", "String"))
+	require.NoError(t, store.PutParameter("/prod/b_pub.pem", "b", "String"))
+	require.NoError(t, store.PutParameter("/staging/a_pub.pem", "a", "String"))
+
+	output, err := fake.DescribeParameters(context.Background(), &ssm.DescribeParametersInput{
+		ParameterFilters: []ssmtypes.ParameterStringFilter{{
+			Key:    awssdk.String("Name"),
+			Option: awssdk.String("BeginsWith"),
+			Values: []string{"/prod/"},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, output.Parameters, 2)
+	assert.Equal(t, "/prod/a_pub.pem", awssdk.ToString(output.Parameters[0].Name))
+
+	require.NoError(t, store.DeleteParameter("/prod/a_pub.pem"))
+	assert.ErrorIs(t, store.DeleteParameter("/prod/a_pub.pem"), types.ErrParameterNotFound)
+
+	_, err = store.GetParameter("/prod/a_pub.pem")
+	assert.ErrorIs(t, err, types.ErrParameterNotFound)
+}
+
+func TestRejectsInvalidNames(t *testing.T) {
+	store := &aws.ParameterStore{SSM: ssmfake.New()}
+
+	err := store.PutParameter("/aws/key_priv.pem", "secret", "SecureString")
+	assert.ErrorContains(t, err, "names cannot begin with 'aws'")
+}
+
+func TestFaults(t *testing.T) {
+	fake := ssmfake.New()
+	store := &aws.ParameterStore{SSM: fake, Timeout: 10 * time.Millisecond}
+
+	fake.Inject(ssmfake.Fault{
+		Operation: ssmfake.OperationPutParameter,
+		Err:       &ssmtypes.TooManyUpdates{},
+		Times:     1,
+	})
+	fake.Inject(ssmfake.Fault{Operation: ssmfake.OperationGetParameter, Name: "slow_priv.pem", Hang: true})
+
+	err := store.PutParameter("key_priv.pem", "secret", "SecureString")
+	assert.Equal(t, types.ErrorKindConflict, types.KindOf(err))
+	assert.NoError(t, store.PutParameter("key_priv.pem", "secret", "SecureString"))
+
+	_, err = store.GetParameter("slow_priv.pem")
+	assert.Equal(t, types.ErrorKindBackendUnavailable, types.KindOf(err))
+
+	_, err = store.GetParameter("key_priv.pem")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, fake.Calls(ssmfake.OperationPutParameter))
+	assert.Equal(t, 2, fake.Calls(ssmfake.OperationGetParameter))
+}
+
+func TestServeHTTP(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_PROFILE", "")
+
+	fake := ssmfake.New()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg, err := aws.NewConfig(context.Background(), aws.ConfigOptions{
+		Region:      "us-east-1",
+		EndpointURL: server.URL,
+		MaxAttempts: 1,
+	})
+	require.NoError(t, err)
+
+	store := aws.NewParameterStore(context.Background(), cfg, time.Second)
+
+	require.NoError(t, store.PutParameterWithOptions("/prod/key_priv.pem", "secret", types.PutOptions{
+		Type:     "SecureString",
+		Tier:     "Advanced",
+		Policies: []string{aws.ExpirationNotificationPolicy(24 * time.Hour)},
+		Tags:     map[string]string{"team": "payments"},
+	}))
+
+	value, err := store.GetParameter("/prod/key_priv.pem")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	metadata, err := store.DescribeParameter("/prod/key_priv.pem")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), metadata.Version)
+	assert.WithinDuration(t, time.Now(), metadata.LastModifiedDate, time.Minute)
+
+	policies, err := store.ParameterPolicies("/prod/key_priv.pem")
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, aws.PolicyTypeExpirationNotification, policies[0].Type)
+
+	err = store.CreateParameter("/prod/key_priv.pem", "other", "SecureString")
+	assert.ErrorIs(t, err, types.ErrParameterAlreadyExists)
+
+	fake.Inject(ssmfake.Fault{Operation: ssmfake.OperationDeleteParameter, Err: errors.New("disk on fire")})
+
+	err = store.DeleteParameter("/prod/key_priv.pem")
+	assert.Equal(t, types.ErrorKindBackendUnavailable, types.KindOf(err))
+
+	parameter, ok := fake.Parameter("/prod/key_priv.pem")
+	require.True(t, ok)
+	assert.Equal(t, "payments", parameter.Tags["team"])
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies a failure so callers, and ultimately the process exit
+// code, can tell a bad request from a missing key or an unreachable backend.
+type ErrorKind string
+
+const (
+	ErrorKindValidation         ErrorKind = "validation"
+	ErrorKindNotFound           ErrorKind = "not_found"
+	ErrorKindPermissionDenied   ErrorKind = "permission_denied"
+	ErrorKindConflict           ErrorKind = "conflict"
+	ErrorKindBackendUnavailable ErrorKind = "backend_unavailable"
+)
+
+// Error attaches an ErrorKind to an error.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError returns err classified as kind. A nil err stays nil.
+func NewError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// Errorf formats an error classified as kind. Use %w to keep the cause.
+func Errorf(kind ErrorKind, format string, a ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, a...)}
+}
+
+// ExitError asks for the process to exit with Code. Commands return it to
+// pass on an exit status they did not fail with themselves, such as that of
+// a child process.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// KindOf returns the kind of the first classified error in err's tree. The
+// parameter store sentinel errors are classified too. Unclassified errors
+// have an empty kind.
+func KindOf(err error) ErrorKind {
+	var typed *Error
+
+	switch {
+	case errors.As(err, &typed):
+		return typed.Kind
+	case errors.Is(err, ErrParameterNotFound):
+		return ErrorKindNotFound
+	case errors.Is(err, ErrParameterAlreadyExists):
+		return ErrorKindConflict
+	}
+
+	return ""
+}
@@ -9,3 +9,9 @@ type KeyRotatorInterface interface {
 		keySize int,
 	) (*rsa.PrivateKey, *rsa.PublicKey, error)
 }
+
+// KeyGeneratorInterface generates RSA key pairs. The go-key-rotator
+// KeyRotator satisfies it.
+type KeyGeneratorInterface interface {
+	GenerateKeyPair(keySize int) (*rsa.PublicKey, *rsa.PrivateKey, error)
+}
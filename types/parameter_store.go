@@ -0,0 +1,81 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrParameterNotFound is returned by a ParameterStoreInterface when the
+	// requested parameter does not exist.
+	ErrParameterNotFound = errors.New("parameter not found")
+
+	// ErrParameterAlreadyExists is returned by CreateParameter when the
+	// parameter already exists.
+	ErrParameterAlreadyExists = errors.New("parameter already exists")
+)
+
+// ParameterStoreInterface is the set of parameter store operations the CLI
+// relies on. It is a superset of the go-key-rotator ParameterStoreInterface,
+// so any implementation can also back a go-key-rotator KeyRotator.
+type ParameterStoreInterface interface {
+	GetParameter(name string) (string, error)
+	PutParameter(name, value, parameterType string) error
+	DescribeParameter(name string) (*ParameterMetadata, error)
+
+	// CreateParameter stores a new parameter, failing with
+	// ErrParameterAlreadyExists rather than overwriting an existing one.
+	CreateParameter(name, value, parameterType string) error
+	DeleteParameter(name string) error
+}
+
+// PutOptions control how a parameter is stored. Zero values leave the
+// backend's defaults in place.
+type PutOptions struct {
+	Type string
+
+	// KeyID is the KMS key that encrypts a SecureString parameter.
+	KeyID string
+	Tier  string
+	Tags  map[string]string
+
+	// Policies are JSON parameter policies, such as an expiration.
+	Policies []string
+
+	// Overwrite replaces a parameter that exists. Without it, writing one
+	// fails with ErrParameterAlreadyExists.
+	Overwrite bool
+}
+
+// IsZero reports whether no option other than Type and Overwrite is set.
+func (o PutOptions) IsZero() bool {
+	return o.KeyID == "" && o.Tier == "" && len(o.Tags) == 0 && len(o.Policies) == 0
+}
+
+// ParameterOptionsStore is implemented by parameter stores that can apply
+// PutOptions when writing a parameter.
+type ParameterOptionsStore interface {
+	// PutParameterWithOptions creates the named parameter, or overwrites it
+	// when options.Overwrite is set.
+	PutParameterWithOptions(name, value string, options PutOptions) error
+}
+
+// ParameterPolicy is a policy attached to a stored parameter.
+type ParameterPolicy struct {
+	Type   string `json:"type" yaml:"type"`
+	Status string `json:"status" yaml:"status"`
+	Text   string `json:"text" yaml:"text"`
+}
+
+// ParameterPolicyStore is implemented by parameter stores that can report
+// the policies attached to a parameter.
+type ParameterPolicyStore interface {
+	ParameterPolicies(name string) ([]ParameterPolicy, error)
+}
+
+// ParameterMetadata describes a stored parameter without exposing its value.
+type ParameterMetadata struct {
+	Name             string
+	Version          int64
+	LastModifiedDate time.Time
+}
@@ -0,0 +1,108 @@
+// Package watch keeps local copies of a key pair in step with the store.
+//
+// A Watcher polls the version of both halves of a key pair and calls its
+// OnChange function whenever either changes. A failed OnChange is retried
+// on the next poll.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	klog "github.com/kmesiab/go-klogger"
+
+	"github.com/kmesiab/go-key-rotator-cli/scheduler"
+	"github.com/kmesiab/go-key-rotator-cli/types"
+)
+
+// DefaultInterval is how often the store is polled by default.
+const DefaultInterval = time.Minute
+
+// ErrMismatchedPair is returned by OnChange implementations that read a
+// private and public key that do not belong together, as happens when the
+// store is read in the middle of a rotation.
+var ErrMismatchedPair = errors.New("private and public keys do not match")
+
+// Version identifies the stored state of a key pair.
+type Version struct {
+	PrivateKey int64
+	PublicKey  int64
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d/%d", v.PrivateKey, v.PublicKey)
+}
+
+// CurrentVersion reads the version of both halves of a key pair.
+func CurrentVersion(store types.ParameterStoreInterface, privateKeyName, publicKeyName string) (Version, error) {
+	private, err := store.DescribeParameter(privateKeyName)
+	if err != nil {
+		return Version{}, err
+	}
+
+	public, err := store.DescribeParameter(publicKeyName)
+	if err != nil {
+		return Version{}, err
+	}
+
+	return Version{PrivateKey: private.Version, PublicKey: public.Version}, nil
+}
+
+type Watcher struct {
+	Store          types.ParameterStoreInterface
+	PrivateKeyName string
+	PublicKeyName  string
+	Interval       time.Duration
+	Clock          scheduler.Clock
+
+	// OnChange is called with the new version once at start and again
+	// whenever the version changes.
+	OnChange func(version Version) error
+}
+
+// Run polls until ctx is done. It returns an error only if the first sync
+// fails, so a watcher never starts without the keys in place.
+func (w *Watcher) Run(ctx context.Context) error {
+	current, err := w.sync(Version{})
+	if err != nil {
+		return err
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Clock.After(interval):
+		}
+
+		if current, err = w.sync(current); err != nil {
+			klog.Logf("Error syncing key pair %s: %s", w.PrivateKeyName, err).Error()
+		}
+	}
+}
+
+// sync calls OnChange if the stored version differs from current, and
+// returns the version the local copy is now at.
+func (w *Watcher) sync(current Version) (Version, error) {
+	version, err := CurrentVersion(w.Store, w.PrivateKeyName, w.PublicKeyName)
+	if err != nil {
+		return current, err
+	}
+
+	if version == current {
+		return current, nil
+	}
+
+	if err := w.OnChange(version); err != nil {
+		return current, err
+	}
+
+	return version, nil
+}
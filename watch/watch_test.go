@@ -0,0 +1,167 @@
+package watch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kmesiab/go-key-rotator-cli/types"
+	"github.com/kmesiab/go-key-rotator-cli/watch"
+)
+
+// versionStore reports a version for each parameter and nothing else.
+type versionStore struct {
+	mu       sync.Mutex
+	versions map[string]int64
+}
+
+func (s *versionStore) set(name string, version int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.versions[name] = version
+}
+
+func (s *versionStore) GetParameter(string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (s *versionStore) PutParameter(string, string, string) error {
+	return errors.New("not implemented")
+}
+
+func (s *versionStore) CreateParameter(string, string, string) error {
+	return errors.New("not implemented")
+}
+
+func (s *versionStore) DeleteParameter(string) error {
+	return errors.New("not implemented")
+}
+
+func (s *versionStore) DescribeParameter(name string) (*types.ParameterMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, ok := s.versions[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, types.ErrParameterNotFound)
+	}
+
+	return &types.ParameterMetadata{Name: name, Version: version}, nil
+}
+
+// tickClock fires its timer whenever the test sends a tick.
+type tickClock struct {
+	ticks   chan time.Time
+	waiting chan struct{}
+}
+
+func newTickClock() *tickClock {
+	return &tickClock{ticks: make(chan time.Time), waiting: make(chan struct{}, 1)}
+}
+
+func (c *tickClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *tickClock) After(time.Duration) <-chan time.Time {
+	c.waiting <- struct{}{}
+
+	return c.ticks
+}
+
+// tick waits for the watcher to wait on the clock and then fires it.
+func (c *tickClock) tick(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-c.waiting:
+	case <-time.After(time.Second):
+		t.Fatal("watcher never waited on the clock")
+	}
+
+	c.ticks <- time.Now()
+}
+
+func TestWatcherSyncsOnVersionChange(t *testing.T) {
+	store := &versionStore{versions: map[string]int64{"k_priv.pem": 1, "k_pub.pem": 1}}
+	clock := newTickClock()
+
+	var (
+		mu      sync.Mutex
+		synced  []watch.Version
+		failing = true
+	)
+
+	watcher := &watch.Watcher{
+		Store:          store,
+		PrivateKeyName: "k_priv.pem",
+		PublicKeyName:  "k_pub.pem",
+		Clock:          clock,
+		OnChange: func(version watch.Version) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if version.PrivateKey == 3 && failing {
+				failing = false
+
+				return errors.New("write failed")
+			}
+
+			synced = append(synced, version)
+
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() { done <- watcher.Run(ctx) }()
+
+	// Unchanged: no sync
+	clock.tick(t)
+
+	// Changed: sync
+	store.set("k_priv.pem", 2)
+	store.set("k_pub.pem", 2)
+	clock.tick(t)
+
+	// Failed sync is retried on the next poll
+	store.set("k_priv.pem", 3)
+	clock.tick(t)
+	clock.tick(t)
+
+	// Stop once the last sync has finished and the watcher waits again
+	<-clock.waiting
+	cancel()
+
+	require.NoError(t, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []watch.Version{
+		{PrivateKey: 1, PublicKey: 1},
+		{PrivateKey: 2, PublicKey: 2},
+		{PrivateKey: 3, PublicKey: 2},
+	}, synced)
+}
+
+func TestWatcherFailsWhenFirstSyncFails(t *testing.T) {
+	watcher := &watch.Watcher{
+		Store:          &versionStore{versions: map[string]int64{}},
+		PrivateKeyName: "k_priv.pem",
+		PublicKeyName:  "k_pub.pem",
+		Clock:          newTickClock(),
+		OnChange:       func(watch.Version) error { return nil },
+	}
+
+	assert.ErrorIs(t, watcher.Run(context.Background()), types.ErrParameterNotFound)
+}